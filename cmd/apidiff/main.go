@@ -0,0 +1,78 @@
+// Command apidiff compares interfaces of modules between two git revisions and suggests a semantic versioning bump.
+//
+// Removed variables, new required variables, changed defaults, narrowed types, removed outputs and changes
+// of resource addresses break users upgrading the modules, they require a major release:
+//
+//	go run ./cmd/apidiff -from v1.0.0
+//	go run ./cmd/apidiff -from v1.0.0 -to develop -fail-on major
+//
+// Without -to, the working tree is compared.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduleapi"
+)
+
+func main() {
+	from := flag.String("from", "", "git revision of the released modules, i.e. a tag")
+	to := flag.String("to", "", "git revision of the new modules, defaults to the working tree")
+	repo := flag.String("repo", ".", "path to the git repository")
+	dir := flag.String("dir", "modules", "directory of the modules, relative to the repository")
+	failOn := flag.String("fail-on", "", "exit with an error when the suggested bump is at least: patch, minor or major")
+	flag.Parse()
+
+	if *from == "" || flag.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: apidiff -from revision [-to revision] [-repo path] [-dir modules] [-fail-on bump]")
+		os.Exit(2)
+	}
+	threshold := moduleapi.None
+	if *failOn != "" {
+		var err error
+		if threshold, err = moduleapi.ParseBump(*failOn); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	old, err := moduleapi.ReadRevision(*repo, *from, *dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var new map[string]*moduleapi.Module
+	if *to == "" {
+		new, err = moduleapi.ReadDir(*repo, *dir)
+	} else {
+		new, err = moduleapi.ReadRevision(*repo, *to, *dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	changes := moduleapi.DiffAll(old, new)
+	var module string
+	for _, c := range changes {
+		if c.Module != module {
+			module = c.Module
+			var bump moduleapi.Bump
+			for _, other := range changes {
+				if other.Module == module && other.Bump > bump {
+					bump = other.Bump
+				}
+			}
+			fmt.Printf("%s: %s\n", path.Join(*dir, module), bump)
+		}
+		fmt.Printf("  %s\n", c)
+	}
+	suggested := moduleapi.Suggest(changes)
+	fmt.Printf("suggested bump: %s\n", suggested)
+	if threshold != moduleapi.None && suggested >= threshold {
+		os.Exit(1)
+	}
+}
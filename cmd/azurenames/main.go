@@ -0,0 +1,47 @@
+// Command azurenames verifies planned names against the naming rules of Azure.
+//
+// Plans are read in the JSON format of `terraform show -json`, every name known before apply is checked:
+//
+//	terraform plan -out tfplan && terraform show -json tfplan > plan.json
+//	go run ./cmd/azurenames plan.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/azurenames"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: azurenames plan.json...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		plan, err := planassert.Parse(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, v := range azurenames.CheckPlan(plan) {
+			fmt.Printf("%s: %s\n", path, v)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
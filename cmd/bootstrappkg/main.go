@@ -0,0 +1,126 @@
+// Command bootstrappkg assembles a bootstrap package of VM-Series firewalls and prints the inputs of the bootstrap
+// module uploading it.
+//
+// Files are placed in the package by their kind, content updates, software images and plugins keep their names,
+// which the firewall recognizes them by. File names and the contents of `init-cfg.txt`, `bootstrap.xml` and
+// `authcodes` are verified, nothing is printed when they have problems. Without -out, the module's `files` and
+// `files_md5` are printed for the files where they are:
+//
+//	go run ./cmd/bootstrappkg -init-cfg files/init-cfg.txt -authcodes files/authcodes \
+//	  -content files/panupv2-all-contents-8729-8157
+//
+// With -out, the files are copied to a directory with the layout of the package, all directories included, and its
+// `bootstrap_files_dir` and `files_md5` are printed. Files of an existing layout given as an argument are included:
+//
+//	go run ./cmd/bootstrappkg -out bootstrap -bootstrap-xml files/bootstrap.xml old_bootstrap
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/bootstrappkg"
+)
+
+// files collects repeated flags of files placed in a directory of the package.
+type files struct {
+	dir string
+	pkg bootstrappkg.Package
+}
+
+func (f files) String() string {
+	return f.dir
+}
+
+func (f files) Set(s string) error {
+	f.pkg[f.dir+"/"+filepath.Base(s)] = s
+	return nil
+}
+
+// file is a flag of a file placed at a fixed path of the package.
+type file struct {
+	remote string
+	pkg    bootstrappkg.Package
+}
+
+func (f file) String() string {
+	return f.remote
+}
+
+func (f file) Set(s string) error {
+	f.pkg[f.remote] = s
+	return nil
+}
+
+func main() {
+	pkg := bootstrappkg.Package{}
+	flag.Var(file{"config/init-cfg.txt", pkg}, "init-cfg", "the init-cfg.txt file")
+	flag.Var(file{"config/bootstrap.xml", pkg}, "bootstrap-xml", "the bootstrap.xml file")
+	flag.Var(file{"license/authcodes", pkg}, "authcodes", "the authcodes file")
+	flag.Var(files{"content", pkg}, "content", "a content update, repeatable")
+	flag.Var(files{"software", pkg}, "software", "a PAN-OS image, repeatable")
+	flag.Var(files{"plugins", pkg}, "plugin", "a plugin, repeatable")
+	flag.Var(files{"license", pkg}, "license", "a license key file, repeatable")
+	out := flag.String("out", "", "directory the package is built in")
+	flag.Parse()
+	if flag.NArg() > 1 || (len(pkg) == 0 && flag.NArg() == 0) {
+		fmt.Fprintln(os.Stderr, "usage: bootstrappkg [-out dir] [-init-cfg file] [-bootstrap-xml file] [-authcodes file]\n"+
+			"                   [-content file]... [-software file]... [-plugin file]... [-license file]... [dir]")
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 1 {
+		existing, err := bootstrappkg.Read(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for remote, local := range existing {
+			if _, ok := pkg[remote]; !ok {
+				pkg[remote] = local
+			}
+		}
+	}
+
+	if problems := pkg.Check(); len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("%s (%s)\n", p, pkg[p.Remote])
+		}
+		os.Exit(1)
+	}
+
+	if *out != "" {
+		if flag.NArg() == 1 && within(*out, flag.Arg(0)) {
+			fmt.Fprintf(os.Stderr, "%s: cannot build the package in %s\n", *out, flag.Arg(0))
+			os.Exit(1)
+		}
+		var err error
+		if pkg, err = pkg.Build(*out); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	tfvars, err := pkg.Tfvars(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(tfvars)
+}
+
+// within tells whether path is dir or is inside it, the files of dir would be overwritten by their copies.
+func within(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,104 @@
+// Command healthprobe verifies that firewalls of an example answer health probes of their load balancers.
+//
+// Probed interfaces are read from the variable files of the example. The bootstrap configuration of a firewall is
+// read from `files/<key>-bootstrap.xml` or `files/<name>-bootstrap.xml`, written by the example on apply, or rendered
+// from the firewall's template. The template's variables, like router IPs, are derived from the VNETs in the variable
+// files the way the example computes them, see healthprobe.TemplateVars:
+//
+//	go run ./cmd/healthprobe -var-file example.tfvars examples/dedicated_vmseries
+//
+// Variables which cannot be derived, i.e. of subnets sourced from an existing VNET, are given as expressions of the
+// Terraform language, -var overrides a derived value as well:
+//
+//	go run ./cmd/healthprobe -var-file example.tfvars -var data_gateway_ip='"10.0.1.1"' examples/gwlb_with_vmseries
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/healthprobe"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/panosconfig"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/tftemplate"
+)
+
+// varFiles collects repeated -var-file flags.
+type varFiles []string
+
+func (f *varFiles) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *varFiles) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func main() {
+	var files varFiles
+	flag.Var(&files, "var-file", "variable file of the example, relative to it, repeatable")
+	vars := tftemplate.Vars{}
+	flag.Var(vars, "var", "a variable given to the templates, as name=expression, overriding the derived ones, repeatable")
+	flag.Parse()
+	if flag.NArg() != 1 || len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: healthprobe -var-file file.tfvars... [-var name=expression]... example-dir")
+		os.Exit(2)
+	}
+	dir := flag.Arg(0)
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, filepath.Join(dir, f))
+	}
+	values, err := healthprobe.ReadVarFiles(paths...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	targets, err := healthprobe.Targets(values)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, target := range targets {
+		given := healthprobe.TemplateVars(values, target.Firewall)
+		for name, val := range vars {
+			given[name] = val
+		}
+		config, err := bootstrap(dir, target, given)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "vmseries[%q]: %v\n", target.Firewall, err)
+			failed = true
+			continue
+		}
+		for _, p := range healthprobe.Check(config, target.Interface, target.Probe) {
+			fmt.Printf("vmseries[%q] %s: %s\n", target.Firewall, target.Interface, p)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// bootstrap reads the rendered configuration of the firewall, or renders its template.
+func bootstrap(dir string, target healthprobe.Target, vars map[string]cty.Value) (*panosconfig.Config, error) {
+	for _, name := range []string{target.Firewall, target.Name} {
+		data, err := os.ReadFile(filepath.Join(dir, "files", name+"-bootstrap.xml"))
+		if err == nil {
+			return panosconfig.Parse(data)
+		}
+	}
+	out, err := tftemplate.Render(dir, target.Template, vars)
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap.xml is not rendered, cannot render %s: %w", target.Template, err)
+	}
+	return panosconfig.Parse([]byte(out))
+}
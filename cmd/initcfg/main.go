@@ -0,0 +1,110 @@
+// Command initcfg verifies `init-cfg.txt` files of VM-Series bootstrap packages, or generates one per firewall.
+//
+// Unknown keys, malformed values and contradicting settings are reported:
+//
+//	go run ./cmd/initcfg examples/*/files/init-cfg.sample.txt
+//
+// With -vmseries, the given file is a base for every firewall of the `vmseries` variable set in a variable file.
+// Each firewall gets its `hostname`, the files are written to the `files` directory next to the variable file,
+// and the `static_files` of each firewall, the bootstrap module's `files`, are printed:
+//
+//	go run ./cmd/initcfg -vmseries examples/dedicated_vmseries/example.tfvars examples/dedicated_vmseries/files/init-cfg.sample.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/initcfg"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func main() {
+	varFile := flag.String("vmseries", "", "variable file setting the vmseries variable, generates a file per firewall")
+	flag.Parse()
+	if flag.NArg() == 0 || (*varFile != "" && flag.NArg() != 1) {
+		fmt.Fprintln(os.Stderr, "usage: initcfg init-cfg.txt...\n       initcfg -vmseries file.tfvars init-cfg.txt")
+		os.Exit(2)
+	}
+
+	failed := false
+	configs := map[string]initcfg.Config{}
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			configs[path], err = initcfg.Parse(data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, p := range initcfg.Validate(configs[path]) {
+			fmt.Printf("%s: %s\n", path, p)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	if *varFile == "" {
+		return
+	}
+
+	if err := generate(*varFile, configs[flag.Arg(0)]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// generate writes a file per firewall and prints their static_files.
+func generate(varFile string, base initcfg.Config) error {
+	file, diags := hclparse.NewParser().ParseHCLFile(varFile)
+	if diags.HasErrors() {
+		return diags
+	}
+	attr, ok := file.Body.(*hclsyntax.Body).Attributes["vmseries"]
+	if !ok {
+		return fmt.Errorf("%s: vmseries is not set", varFile)
+	}
+	vmseries, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return diags
+	}
+	firewalls, err := initcfg.Generate(base, vmseries)
+	if err != nil {
+		return fmt.Errorf("%s: %w", varFile, err)
+	}
+
+	dir := filepath.Dir(varFile)
+	fmt.Println("vmseries = {")
+	for _, fw := range firewalls {
+		if problems := initcfg.Validate(fw.Config); len(problems) > 0 {
+			return fmt.Errorf("%s: %s", fw.Key, problems[0])
+		}
+		path := filepath.Join(dir, initcfg.FileName(fw.Key))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, fw.Config.Bytes(), 0644); err != nil {
+			return err
+		}
+
+		locals := make([]string, 0, len(fw.StaticFiles))
+		for local := range fw.StaticFiles {
+			locals = append(locals, local)
+		}
+		sort.Strings(locals)
+		fmt.Printf("  %s = {\n    bootstrap_storage = {\n      static_files = {\n", fw.Key)
+		for _, local := range locals {
+			fmt.Printf("        %q = %q\n", local, fw.StaticFiles[local])
+		}
+		fmt.Println("      }\n    }\n  }")
+	}
+	fmt.Println("}")
+	return nil
+}
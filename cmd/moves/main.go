@@ -0,0 +1,86 @@
+// Command moves proposes `moved` blocks for resources of modules whose addresses changed between two git revisions.
+//
+// Renamed resources and resources switched between a single instance, `count` and `for_each` are destroyed
+// and re-created by Terraform unless they are moved. The proposed blocks are printed per module, review them
+// before adding them to the module:
+//
+//	go run ./cmd/moves -from v1.0.0
+//	go run ./cmd/moves -from v1.0.0 -module bootstrap -format state-mv > move.sh
+//
+// With `-format state-mv` a script for `terraform state mv` of a single module is printed instead, for users
+// of the previous release.
+// Without -to, the working tree is compared.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduleapi"
+)
+
+func main() {
+	from := flag.String("from", "", "git revision of the released modules, i.e. a tag")
+	to := flag.String("to", "", "git revision of the new modules, defaults to the working tree")
+	repo := flag.String("repo", ".", "path to the git repository")
+	dir := flag.String("dir", "modules", "directory of the modules, relative to the repository")
+	module := flag.String("module", "", "name of a single module to compare")
+	format := flag.String("format", "moved", "output format: moved or state-mv")
+	flag.Parse()
+
+	if *from == "" || flag.NArg() > 0 || (*format != "moved" && *format != "state-mv") {
+		fmt.Fprintln(os.Stderr, "usage: moves -from revision [-to revision] [-repo path] [-dir modules] [-module name] [-format moved|state-mv]")
+		os.Exit(2)
+	}
+	if *format == "state-mv" && *module == "" {
+		fmt.Fprintln(os.Stderr, "a script for terraform state mv is generated for a single -module")
+		os.Exit(2)
+	}
+
+	old, err := moduleapi.ReadRevision(*repo, *from, *dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var new map[string]*moduleapi.Module
+	if *to == "" {
+		new, err = moduleapi.ReadDir(*repo, *dir)
+	} else {
+		new, err = moduleapi.ReadRevision(*repo, *to, *dir)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *module != "" && (old[*module] == nil || new[*module] == nil) {
+		fmt.Fprintf(os.Stderr, "module %s is not present in both revisions\n", *module)
+		os.Exit(1)
+	}
+
+	for _, name := range sortedNames(old) {
+		if new[name] == nil || (*module != "" && name != *module) {
+			continue
+		}
+		moves := moduleapi.ProposeMoves(old[name], new[name])
+		if len(moves) == 0 {
+			continue
+		}
+		if *format == "state-mv" {
+			fmt.Printf("# %s\n%s\n", path.Join(*dir, name), moduleapi.FormatStateMv(moves))
+			continue
+		}
+		fmt.Printf("# %s\n\n%s\n", path.Join(*dir, name), moduleapi.FormatMovedBlocks(moves))
+	}
+}
+
+func sortedNames(modules map[string]*moduleapi.Module) []string {
+	var names []string
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
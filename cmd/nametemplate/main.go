@@ -0,0 +1,101 @@
+// Command nametemplate lints name templates of the `name_templater` module defined in variable files.
+//
+// Every variable holding a template (an object with `delimiter` and `parts`), or a map of templates, is checked
+// for a missing or repeated `%s`, an abbreviation of an unknown resource type and doubled delimiters.
+// The name prefix and the resource type are given with flags, the template with each one is printed:
+//
+//	go run ./cmd/nametemplate -prefix example- -resource-type vnet custom.tfvars
+//
+// When Azure restricts names of the resource type, the template is also verified against the naming rules
+// and the number of characters left for names is printed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/azurenames"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/nametemplate"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func main() {
+	prefix := flag.String("prefix", "", "value of the name_prefix variable")
+	resourceType := flag.String("resource-type", "", "value of the resource_type variable")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: nametemplate [-prefix prefix] [-resource-type type] file.tfvars...")
+		os.Exit(2)
+	}
+
+	in := nametemplate.Inputs{NamePrefix: *prefix, ResourceType: *resourceType, RandomPet: "<random>"}
+	parser := hclparse.NewParser()
+	failed := false
+	for _, path := range flag.Args() {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			fmt.Fprintln(os.Stderr, diags)
+			failed = true
+			continue
+		}
+		attrs := file.Body.(*hclsyntax.Body).Attributes
+		names := make([]string, 0, len(attrs))
+		for name := range attrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			attr := attrs[name]
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				continue
+			}
+			found := templates(name, val)
+			labels := make([]string, 0, len(found))
+			for label := range found {
+				labels = append(labels, label)
+			}
+			sort.Strings(labels)
+			for _, label := range labels {
+				t := found[label]
+				rendered := nametemplate.Render(t, in)
+				problems := nametemplate.Lint(t, in)
+				if azureType, ok := azurenames.TemplaterTypes[*resourceType]; ok {
+					budget, p := azurenames.CheckTemplate(azureType, rendered)
+					fmt.Printf("%s:%d: %s: %q, %d characters left for names\n", path, attr.SrcRange.Start.Line, label, rendered, budget)
+					problems = append(problems, p...)
+				} else {
+					fmt.Printf("%s:%d: %s: %q\n", path, attr.SrcRange.Start.Line, label, rendered)
+				}
+				for _, p := range problems {
+					fmt.Printf("%s:%d: %s: %s\n", path, attr.SrcRange.Start.Line, label, p)
+					failed = true
+				}
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// templates returns the template held by a variable, or templates of a map, labelled by their keys.
+func templates(name string, val cty.Value) map[string]nametemplate.Template {
+	if t, err := nametemplate.FromValue(val); err == nil {
+		return map[string]nametemplate.Template{name: t}
+	}
+	found := map[string]nametemplate.Template{}
+	if !val.IsWhollyKnown() || val.IsNull() || !(val.Type().IsObjectType() || val.Type().IsMapType()) {
+		return found
+	}
+	for k, v := range val.AsValueMap() {
+		if t, err := nametemplate.FromValue(v); err == nil {
+			found[fmt.Sprintf("%s[%q]", name, k)] = t
+		}
+	}
+	return found
+}
@@ -0,0 +1,47 @@
+// Command panosconfig verifies references in PAN-OS configurations, like a rendered `bootstrap.xml`.
+//
+// Interfaces, zones, interface management profiles and virtual routers referenced but not defined are reported:
+//
+//	go run ./cmd/panosconfig examples/dedicated_vmseries/files/*-bootstrap.xml
+//
+// Templates are rendered first, see cmd/tftemplate.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/panosconfig"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: panosconfig bootstrap.xml...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		config, err := panosconfig.Parse(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, p := range panosconfig.Check(config) {
+			fmt.Printf("%s: %s\n", path, p)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,70 @@
+// Command readme updates the reference of modules and examples in their README.md files, like the
+// terraform_docs pre-commit hook does, for when terraform-docs is not installed.
+//
+// The requirements, providers, modules, resources, inputs and outputs are rendered between the markers
+// of the pre-commit hook. Without arguments, all modules and examples are updated:
+//
+//	go run ./cmd/readme
+//	go run ./cmd/readme modules/vnet
+//
+// With -check, READMEs are not changed, the command fails when any of them is not up to date:
+//
+//	go run ./cmd/readme -check
+//
+// With -sensitive, a `Sensitive` column is added to the inputs and outputs of modules which have sensitive ones.
+// terraform-docs does not render it, use it only for READMEs not maintained by the terraform_docs hook:
+//
+//	go run ./cmd/readme -sensitive ../my-module
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/readme"
+)
+
+func main() {
+	check := flag.Bool("check", false, "fail when a README is not up to date instead of updating it")
+	sensitive := flag.Bool("sensitive", false, "add a Sensitive column to inputs and outputs when any of them is sensitive")
+	flag.Parse()
+	opts := readme.Options{Sensitive: *sensitive}
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		modules, _ := filepath.Glob("modules/*/README.md")
+		examples, _ := filepath.Glob("examples/*/README.md")
+		for _, path := range append(modules, examples...) {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	if len(dirs) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: readme [-check] [-sensitive] [dir...]")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, dir := range dirs {
+		if *check {
+			if err := readme.Check(dir, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+			continue
+		}
+		updated, err := readme.Update(dir, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+			failed = true
+			continue
+		}
+		if updated {
+			fmt.Println("updated", filepath.Join(dir, "README.md"))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,145 @@
+// Command sweeper deletes Resource Groups and Storage Accounts leaked by killed or failed test runs.
+//
+// Leaked resources are recognized by names generated the same way the tests generate them, and by their age.
+// Names made of random characters only, without a common part, are not recognized unless a pattern is given.
+// Without the -delete flag the leaked resources are only listed:
+//
+//	go run ./cmd/sweeper -min-age 6h
+//	go run ./cmd/sweeper -min-age 6h -delete
+//
+// The subscription is read from ARM_SUBSCRIPTION_ID. The access token is read from ARM_ACCESS_TOKEN,
+// or obtained with the Azure CLI when the variable is not set.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/sweeper"
+)
+
+func main() {
+	subscription := flag.String("subscription", os.Getenv("ARM_SUBSCRIPTION_ID"), "ID of the subscription to sweep")
+	minAge := flag.Duration("min-age", 6*time.Hour, "minimum age of a leaked resource, younger ones can belong to running tests")
+	del := flag.Bool("delete", false, "delete the leaked resources, without it they are only listed")
+	includeEmpty := flag.Bool("include-empty", false, "treat empty resource groups, of an unknown age, as leaked")
+	samples := flag.Int("samples", 20, "number of generated names the naming scheme is inferred from")
+	rgPattern := flag.String("rg-pattern", "", "regular expression matching resource group names, overrides the inferred one")
+	saPattern := flag.String("sa-pattern", "", "regular expression matching storage account names, overrides the inferred one")
+	flag.Parse()
+
+	if *subscription == "" {
+		fail("subscription ID is not set, use -subscription or ARM_SUBSCRIPTION_ID")
+	}
+
+	scheme, err := namingScheme(*samples, *rgPattern, *saPattern)
+	if err != nil {
+		fail("cannot build the naming scheme: %v", err)
+	}
+	if scheme.ResourceGroup != nil {
+		fmt.Printf("resource groups matching: %s\n", scheme.ResourceGroup)
+	}
+	if scheme.StorageAccount != nil {
+		fmt.Printf("storage accounts matching: %s\n", scheme.StorageAccount)
+	}
+
+	ctx := context.Background()
+	client := &sweeper.ARMClient{SubscriptionID: *subscription, Token: cachedToken()}
+
+	leaks, err := sweeper.Find(ctx, client, scheme, sweeper.Options{MinAge: *minAge, IncludeEmpty: *includeEmpty})
+	if err != nil {
+		fail("%v", err)
+	}
+	if len(leaks) == 0 {
+		fmt.Println("no leaked resources found")
+		return
+	}
+	for _, l := range leaks {
+		fmt.Println(l)
+	}
+	if !*del {
+		fmt.Println("run with -delete to delete the resources listed above")
+		return
+	}
+	if err := sweeper.Sweep(ctx, client, leaks); err != nil {
+		fail("%v", err)
+	}
+	fmt.Printf("deletion of %d resources started\n", len(leaks))
+}
+
+// namingScheme infers the scheme from names generated by the test skeleton, the same way the tests do.
+// Resource Groups are named with the name prefix followed by the generated Resource Group name. Names without
+// a common part long enough are not recognized, resources of that kind are swept only with an explicit pattern.
+func namingScheme(samples int, rgPattern, saPattern string) (sweeper.Scheme, error) {
+	var groups, accounts []string
+	for i := 0; i < samples; i++ {
+		names, err := testskeleton.GenerateTerraformVarsInfo("azure")
+		if err != nil {
+			return sweeper.Scheme{}, err
+		}
+		groups = append(groups, names.NamePrefix+names.AzureResourceGroupName)
+		accounts = append(accounts, names.AzureStorageAccountName)
+	}
+	var scheme sweeper.Scheme
+	var err error
+	if scheme.ResourceGroup, err = pattern(groups, true, rgPattern, "resource group", "-rg-pattern"); err != nil {
+		return scheme, err
+	}
+	if scheme.StorageAccount, err = pattern(accounts, false, saPattern, "storage account", "-sa-pattern"); err != nil {
+		return scheme, err
+	}
+	if scheme.ResourceGroup == nil && scheme.StorageAccount == nil {
+		return scheme, errors.New("neither resource group nor storage account names can be recognized")
+	}
+	return scheme, nil
+}
+
+// pattern returns the explicit pattern, or the one inferred from samples, or nil when samples have no common part.
+func pattern(samples []string, ignoreCase bool, explicit, kind, flagName string) (*regexp.Regexp, error) {
+	if explicit != "" {
+		return regexp.Compile(explicit)
+	}
+	re, err := sweeper.InferPattern(samples, ignoreCase)
+	if errors.Is(err, sweeper.ErrNoAnchor) {
+		fmt.Fprintf(os.Stderr, "sweeper: %s names are not swept, %v, set %s to sweep them\n", kind, err, flagName)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s names: %w", kind, err)
+	}
+	return re, nil
+}
+
+// cachedToken returns a token source reading ARM_ACCESS_TOKEN or, when it is not set, asking the Azure CLI once.
+func cachedToken() sweeper.TokenSource {
+	var once sync.Once
+	var token string
+	var err error
+	return func(ctx context.Context) (string, error) {
+		once.Do(func() {
+			if token = os.Getenv("ARM_ACCESS_TOKEN"); token != "" {
+				return
+			}
+			var out []byte
+			out, err = exec.CommandContext(ctx, "az", "account", "get-access-token",
+				"--resource", sweeper.DefaultEndpoint+"/", "--query", "accessToken", "--output", "tsv").Output()
+			token = strings.TrimSpace(string(out))
+		})
+		return token, err
+	}
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "sweeper: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,46 @@
+// Command tftemplate renders a template file like `templatefile()` does, without Terraform.
+//
+// Variables are given as expressions of the Terraform language, the result is printed:
+//
+//	go run ./cmd/tftemplate -C examples/gwlb_with_vmseries -var data_gateway_ip='"10.0.1.1"' -var ai_instr_key=null \
+//	  -var ai_update_interval=5 templates/bootstrap-gwlb.tftpl
+//
+// Run it without variables to list the ones the template references.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/tftemplate"
+)
+
+func main() {
+	dir := flag.String("C", ".", "directory of the module calling templatefile(), the template path is relative to it")
+	vars := tftemplate.Vars{}
+	flag.Var(vars, "var", "a variable given to the template, as name=expression, repeatable")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: tftemplate [-C dir] [-var name=expression]... template")
+		os.Exit(2)
+	}
+
+	if len(vars) == 0 {
+		names, err := tftemplate.Variables(*dir, flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+	out, err := tftemplate.Render(*dir, flag.Arg(0), vars)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(out)
+}
@@ -0,0 +1,125 @@
+// Command varcheck verifies variable files of examples against their variables, without Terraform.
+//
+// For every directory given, the variable files are checked for values of a wrong type, unknown keys of objects
+// and required variables that are not set. For examples, keys referencing other parts of the variable files
+// (like `subnet_key` of a VM-Series interface) are verified too, see varcheck.ExampleRules.
+// Variable files of the test matrix, kept in `testdata/matrix`, are checked as overlays of the example's variable files:
+//
+//	go run ./cmd/varcheck examples/*
+//	go run ./cmd/varcheck -var-file custom.tfvars -provided name_prefix examples/common_vmseries
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/varcheck"
+)
+
+func main() {
+	var varFiles []string
+	flag.Func("var-file", "variable file relative to the checked directory, can be repeated, defaults to example.tfvars", func(s string) error {
+		varFiles = append(varFiles, s)
+		return nil
+	})
+	provided := flag.String("provided", "", "comma separated names of variables set outside of the variable files")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: varcheck [-var-file file]... [-provided names] dir...")
+		os.Exit(2)
+	}
+	if len(varFiles) == 0 {
+		varFiles = []string{"example.tfvars"}
+	}
+	var opts varcheck.Options
+	if *provided != "" {
+		opts.Provided = strings.Split(*provided, ",")
+	}
+
+	failed := false
+	for _, dir := range flag.Args() {
+		problems, err := checkDir(dir, varFiles, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+			failed = true
+			continue
+		}
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		failed = failed || len(problems) > 0
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// checkDir checks the variable files of a single directory and the overlays of its test matrix.
+func checkDir(dir string, varFiles []string, opts varcheck.Options) ([]varcheck.Problem, error) {
+	vars, err := varcheck.LoadModule(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, f := range varFiles {
+		files = append(files, filepath.Join(dir, f))
+	}
+	problems, err := varcheck.Check(vars, files, opts)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	rules, hasRules := varcheck.ExampleRules[filepath.Base(abs)]
+	if hasRules {
+		refProblems, err := varcheck.CheckReferences(files, rules)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, refProblems...)
+	}
+
+	overlays, err := filepath.Glob(filepath.Join(dir, "testdata", "matrix", "*.tfvars"))
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range overlays {
+		overlayProblems, err := varcheck.Check(vars, []string{overlay}, varcheck.Options{Overlay: true})
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, overlayProblems...)
+		if hasRules {
+			// an overlay replaces whole variables, references are verified together with the variable files
+			refProblems, err := varcheck.CheckReferences(append(append([]string{}, files...), overlay), rules)
+			if err != nil {
+				return nil, err
+			}
+			problems = append(problems, newProblems(refProblems, problems)...)
+		}
+	}
+	return problems, nil
+}
+
+// newProblems returns problems which are not reported yet, so that problems of the variable files are not repeated
+// for every overlay.
+func newProblems(problems, reported []varcheck.Problem) []varcheck.Problem {
+	seen := map[string]bool{}
+	for _, p := range reported {
+		seen[p.String()] = true
+	}
+	var out []varcheck.Problem
+	for _, p := range problems {
+		if !seen[p.String()] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+// Command varlint verifies documentation and types of variables of modules, without Terraform.
+//
+// Variables without a description, attributes documented in a description which the module never reads (or read
+// but not documented) and descriptions listing allowed values without a validation block are reported:
+//
+//	go run ./cmd/varlint modules/*
+//
+// With -any, variables of type `any`, or of a type containing it like `map(any)`, are reported as well. Free-form
+// maps like `tags` are expected to stay `map(any)`, the report is only a hint where object() would document more:
+//
+//	go run ./cmd/varlint -any modules/vmss
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/varlint"
+)
+
+func main() {
+	anyTypes := flag.Bool("any", false, "report variables of type any")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: varlint [-any] dir...")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, dir := range flag.Args() {
+		problems, err := varlint.Lint(dir, *anyTypes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+			failed = true
+			continue
+		}
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		failed = failed || len(problems) > 0
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
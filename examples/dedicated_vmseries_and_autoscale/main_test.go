@@ -3,60 +3,51 @@ package dedicated_vmseries_and_autoscale
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/logger"
-	"github.com/gruntwork-io/terratest/modules/terraform"
-
-	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/exampletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
-func CreateTerraformOptions(t *testing.T) *terraform.Options {
-	// prepare random prefix
-	randomNames, _ := testskeleton.GenerateTerraformVarsInfo("azure")
-
-	// define options for Terraform
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: ".",
-		VarFiles:     []string{"example.tfvars"},
-		Vars: map[string]interface{}{
-			"name_prefix":         randomNames.NamePrefix,
-			"resource_group_name": randomNames.AzureResourceGroupName,
+var spec = exampletest.Spec{
+	PlanAssertions: []planassert.Assertion{
+		planassert.CreatedCount(`module.vmss[*].azurerm_linux_virtual_machine_scale_set.this`, 2),
+		planassert.CreatedWithLength(`module.vmss[*].azurerm_linux_virtual_machine_scale_set.this`, "network_interface", 3),
+		planassert.AttributeNull(`module.vmss[*].azurerm_linux_virtual_machine_scale_set.this`, "network_interface.*.ip_configuration.*.public_ip_address"),
+		planassert.CreatedCount(`module.vmss[*].azurerm_monitor_autoscale_setting.this[0]`, 2),
+		planassert.IsCreated(`module.load_balancer["public"].azurerm_lb.lb`),
+		planassert.IsCreated(`module.load_balancer["private"].azurerm_lb.lb`),
+	},
+	Matrix: []exampletest.Variant{
+		{
+			Name: "zones_enabled",
+			Vars: map[string]interface{}{"enable_zones": true},
+			PlanAssertions: []planassert.Assertion{
+				planassert.CreatedWithLength(`module.vmss[*].azurerm_linux_virtual_machine_scale_set.this`, "zones", 3),
+				planassert.AttributeEquals(`module.vmss[*].azurerm_linux_virtual_machine_scale_set.this`, "zone_balance", true),
+			},
 		},
-		Logger:               logger.Default,
-		Lock:                 true,
-		Upgrade:              true,
-		SetVarsAfterVarFiles: true,
-	})
-
-	return terraformOptions
+		{
+			Name:                  "existing_resource_group",
+			ExistingResourceGroup: true,
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(`azurerm_resource_group.this*`),
+			},
+		},
+	},
+	ExpectedOutputs: []string{"username", "password", "lb_frontend_ips"},
 }
 
 func TestValidate(t *testing.T) {
-	testskeleton.ValidateCode(t, nil)
+	exampletest.Validate(t)
 }
 
 func TestPlan(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// plan test infrastructure and verify outputs
-	testskeleton.PlanInfraCheckErrors(t, terraformOptions, assertList, "No errors are expected")
+	exampletest.Plan(t, spec)
 }
 
 func TestApply(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// deploy test infrastructure and verify outputs and check if there are no planned changes after deployment
-	testskeleton.DeployInfraCheckOutputs(t, terraformOptions, assertList)
+	exampletest.Apply(t, spec)
 }
 
 func TestIdempotence(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// deploy test infrastructure and verify outputs and check if there are no planned changes after deployment
-	testskeleton.DeployInfraCheckOutputsVerifyChanges(t, terraformOptions, assertList)
+	exampletest.Idempotence(t, spec)
 }
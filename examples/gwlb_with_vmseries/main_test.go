@@ -1,79 +1,78 @@
 package gwlb_with_vmseries
 
 import (
-	"fmt"
-	"log"
-	"os"
 	"testing"
 
-	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
-	"github.com/gruntwork-io/terratest/modules/logger"
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/exampletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
-func CreateTerraformOptions(t *testing.T) *terraform.Options {
-	// prepare random prefix
-	randomNames, _ := testskeleton.GenerateTerraformVarsInfo("azure")
-	storageDefinition := fmt.Sprintf("{ bootstrap = { name = \"%s\", public_snet_key = \"public\", private_snet_key = \"private\", intranet_cidr = \"10.0.1.0/24\"} }", randomNames.AzureStorageAccountName)
-
-	// copy the init-cfg.sample.txt file to init-cfg.txt for test purposes
-	_, err := os.Stat("files/init-cfg.txt")
-	if err != nil {
-		buff, err := os.ReadFile("files/init-cfg.sample.txt")
-		if err != nil {
-			log.Fatal(err)
-		}
-		err = os.WriteFile("files/init-cfg.txt", buff, 0644)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-
-	// define options for Terraform
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: ".",
-		VarFiles:     []string{"example.tfvars"},
-		Vars: map[string]interface{}{
-			"name_prefix":         randomNames.NamePrefix,
-			"resource_group_name": randomNames.AzureResourceGroupName,
-			"bootstrap_storages":  storageDefinition,
+var spec = exampletest.Spec{
+	FixtureFiles: map[string]string{"files/init-cfg.txt": "files/init-cfg.sample.txt"},
+	BootstrapStorage: &exampletest.BootstrapStorage{
+		Variable:     "bootstrap_storages",
+		IntranetCIDR: "10.0.1.0/24",
+	},
+	PlanAssertions: []planassert.Assertion{
+		planassert.CreatedCount(`module.vmseries[*].azurerm_virtual_machine.this`, 2),
+		planassert.CreatedWithLength(`module.vmseries[*].azurerm_virtual_machine.this`, "network_interface_ids", 2),
+		planassert.PublicIPOnInterfaces("-mgmt-nic"),
+		planassert.NoPublicIPOnInterfaces("-data-nic"),
+		planassert.CreatedCount(`module.vmseries[*].azurerm_network_interface_backend_address_pool_association.this[*-data"]`, 2),
+		planassert.AttributeEquals(`module.gwlb["gwlb"].azurerm_lb.this`, "sku", "Gateway"),
+		planassert.AttributeEquals(`module.gwlb["gwlb"].azurerm_lb_probe.this`, "port", float64(80)),
+		planassert.CreatedWithLength(`module.gwlb["gwlb"].azurerm_lb_backend_address_pool.this["ext-int"]`, "tunnel_interface", 2),
+		planassert.IsCreated(`module.appvm["app1vm01"].azurerm_virtual_machine.this`),
+	},
+	Matrix: []exampletest.Variant{
+		{
+			Name: "zones_disabled",
+			Vars: map[string]interface{}{"enable_zones": false},
+			PlanAssertions: []planassert.Assertion{
+				planassert.AttributeNull(`module.vmseries[*].azurerm_virtual_machine.this`, "zones"),
+			},
 		},
-		Logger:               logger.Default,
-		Lock:                 true,
-		Upgrade:              true,
-		SetVarsAfterVarFiles: true,
-	})
-
-	return terraformOptions
+		{
+			Name:                  "existing_resource_group",
+			ExistingResourceGroup: true,
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(`azurerm_resource_group.this*`),
+			},
+		},
+		{
+			Name:     "availability_sets",
+			VarFiles: []string{"testdata/matrix/availability_sets.tfvars"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.CreatedCount(`azurerm_availability_set.this[*]`, 1),
+				planassert.CreatedCount(`module.vmseries[*].azurerm_virtual_machine.this`, 2),
+				planassert.AttributeNull(`module.vmseries[*].azurerm_virtual_machine.this`, "zones"),
+			},
+		},
+		{
+			Name: "application_insights",
+			Vars: map[string]interface{}{"application_insights": map[string]interface{}{}},
+			PlanAssertions: []planassert.Assertion{
+				planassert.CreatedCount(`module.ai[*].azurerm_application_insights.this`, 2),
+			},
+		},
+	},
+	ExpectedOutputs: []string{"username", "password", "vmseries_mgmt_ips"},
+	// the example prefixes the name of an existing Resource Group as well
+	PrefixedExistingResourceGroup: true,
 }
 
 func TestValidate(t *testing.T) {
-	testskeleton.ValidateCode(t, nil)
+	exampletest.Validate(t)
 }
 
 func TestPlan(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// plan test infrastructure and verify outputs
-	testskeleton.PlanInfraCheckErrors(t, terraformOptions, assertList, "No errors are expected")
+	exampletest.Plan(t, spec)
 }
 
 func TestApply(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// deploy test infrastructure and verify outputs and check if there are no planned changes after deployment
-	testskeleton.DeployInfraCheckOutputs(t, terraformOptions, assertList)
+	exampletest.Apply(t, spec)
 }
 
 func TestIdempotence(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// deploy test infrastructure and verify outputs and check if there are no planned changes after deployment
-	testskeleton.DeployInfraCheckOutputsVerifyChanges(t, terraformOptions, assertList)
+	exampletest.Idempotence(t, spec)
 }
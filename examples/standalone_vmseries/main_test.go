@@ -3,60 +3,58 @@ package standalone_vmseries
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/logger"
-	"github.com/gruntwork-io/terratest/modules/terraform"
-
-	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/exampletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
-func CreateTerraformOptions(t *testing.T) *terraform.Options {
-	// prepare random prefix
-	randomNames, _ := testskeleton.GenerateTerraformVarsInfo("azure")
-
-	// define options for Terraform
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: ".",
-		VarFiles:     []string{"example.tfvars"},
-		Vars: map[string]interface{}{
-			"name_prefix":         randomNames.NamePrefix,
-			"resource_group_name": randomNames.AzureResourceGroupName,
+var spec = exampletest.Spec{
+	Matrix: []exampletest.Variant{
+		{
+			Name: "zones_enabled",
+			Vars: map[string]interface{}{"enable_zones": true},
+			PlanAssertions: []planassert.Assertion{
+				planassert.AttributeEquals(`module.vmseries[*].azurerm_virtual_machine.this`, "zones.0", "1"),
+			},
 		},
-		Logger:               logger.Default,
-		Lock:                 true,
-		Upgrade:              true,
-		SetVarsAfterVarFiles: true,
-	})
-
-	return terraformOptions
+		{
+			Name:                  "existing_resource_group",
+			ExistingResourceGroup: true,
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(`azurerm_resource_group.this*`),
+			},
+		},
+		{
+			Name:     "availability_sets",
+			VarFiles: []string{"testdata/matrix/availability_sets.tfvars"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.CreatedCount(`azurerm_availability_set.this[*]`, 1),
+				planassert.CreatedCount(`module.vmseries[*].azurerm_virtual_machine.this`, 1),
+				planassert.AttributeNull(`module.vmseries[*].azurerm_virtual_machine.this`, "zones"),
+			},
+		},
+		{
+			Name: "application_insights",
+			Vars: map[string]interface{}{"application_insights": map[string]interface{}{}},
+			PlanAssertions: []planassert.Assertion{
+				planassert.CreatedCount(`module.ai[*].azurerm_application_insights.this`, 1),
+			},
+		},
+	},
+	ExpectedOutputs: []string{"username", "password", "vmseries_mgmt_ips"},
 }
 
 func TestValidate(t *testing.T) {
-	testskeleton.ValidateCode(t, nil)
+	exampletest.Validate(t)
 }
 
 func TestPlan(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// plan test infrastructure and verify outputs
-	testskeleton.PlanInfraCheckErrors(t, terraformOptions, assertList, "No errors are expected")
+	exampletest.Plan(t, spec)
 }
 
 func TestApply(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// deploy test infrastructure and verify outputs and check if there are no planned changes after deployment
-	testskeleton.DeployInfraCheckOutputs(t, terraformOptions, assertList)
+	exampletest.Apply(t, spec)
 }
 
 func TestIdempotence(t *testing.T) {
-	// define options for Terraform
-	terraformOptions := CreateTerraformOptions(t)
-	// prepare list of items to check
-	assertList := []testskeleton.AssertExpression{}
-	// deploy test infrastructure and verify outputs and check if there are no planned changes after deployment
-	testskeleton.DeployInfraCheckOutputsVerifyChanges(t, terraformOptions, assertList)
+	exampletest.Idempotence(t, spec)
 }
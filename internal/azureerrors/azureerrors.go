@@ -0,0 +1,229 @@
+// Package azureerrors is a catalogue of errors reported by Azure and the azurerm provider.
+//
+// Each known error is classified, so that tests retry transient failures, stop early on errors
+// that will not go away by themselves and point out the ones that need a manual action,
+// like accepting the Marketplace terms of the VM-Series image.
+package azureerrors
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Class describes what to do with an error.
+type Class string
+
+const (
+	// Retry is a transient error, the same operation is expected to succeed when run again.
+	Retry Class = "retry"
+	// FailFast is an error in the code or the inputs, retrying will not help.
+	FailFast Class = "fail-fast"
+	// NeedsHuman is an error that requires a manual action on the subscription, i.e. accepting Marketplace terms.
+	NeedsHuman Class = "needs-human"
+	// Unknown is an error not found in the catalogue.
+	Unknown Class = "unknown"
+)
+
+// precedence of classes when an output matches more than one pattern, the more specific action wins
+var precedence = map[Class]int{NeedsHuman: 3, FailFast: 2, Retry: 1}
+
+// Pattern is a single entry in the catalogue.
+type Pattern struct {
+	// Name identifies the error, usually it is the Azure error code.
+	Name string
+	// Expression is a regular expression matched against the output of a Terraform command.
+	Expression string
+	// Class is what to do when the error occurs.
+	Class Class
+	// Hint is an explanation shown in the test output.
+	Hint string
+
+	re *regexp.Regexp
+}
+
+// Catalogue lists all known errors.
+var Catalogue = compile([]Pattern{
+	// transient errors
+	{
+		Name:       "RetryableError",
+		Expression: `RetryableError`,
+		Class:      Retry,
+		Hint:       "Azure reported the operation as retryable, i.e. a NIC is still being associated with a VM or a Load Balancer.",
+	},
+	{
+		Name:       "AnotherOperationInProgress",
+		Expression: `AnotherOperationInProgress|OperationNotAllowed.*another operation is in progress`,
+		Class:      Retry,
+		Hint:       "Another operation on the same resource, usually a subnet or a VNET, is in progress.",
+	},
+	{
+		Name:       "InUseSubnetCannotBeDeleted",
+		Expression: `InUseSubnetCannotBeDeleted`,
+		Class:      Retry,
+		Hint:       "A subnet is still used by a NIC that is being deleted.",
+	},
+	{
+		Name:       "NicReservedForAnotherVm",
+		Expression: `NicReservedForAnotherVm`,
+		Class:      Retry,
+		Hint:       "A NIC stays reserved for 180 seconds after its VM is deleted.",
+	},
+	{
+		Name:       "TooManyRequests",
+		Expression: `TooManyRequests|StatusCode=429`,
+		Class:      Retry,
+		Hint:       "Azure Resource Manager requests are throttled.",
+	},
+	{
+		Name:       "ServerError",
+		Expression: `InternalServerError|ServiceUnavailable|GatewayTimeout|StatusCode=50[0234]`,
+		Class:      Retry,
+		Hint:       "Azure Resource Manager reported a server side error.",
+	},
+	{
+		Name:       "ConnectionError",
+		Expression: `connection reset by peer|TLS handshake timeout|i/o timeout`,
+		Class:      Retry,
+		Hint:       "The connection to Azure failed.",
+	},
+
+	// errors in the code or inputs
+	{
+		Name:       "InvalidConfiguration",
+		Expression: `Error: (Invalid|Unsupported|Missing required) (argument|attribute|reference|value|function call|block type)`,
+		Class:      FailFast,
+		Hint:       "The Terraform code is invalid.",
+	},
+	{
+		Name:       "ConditionFailed",
+		Expression: `Error: (Invalid value for variable|Resource precondition failed|Resource postcondition failed)`,
+		Class:      FailFast,
+		Hint:       "A variable validation or a resource condition failed, verify the inputs.",
+	},
+	{
+		Name:       "InvalidParameter",
+		Expression: `InvalidParameter|InvalidRequestFormat`,
+		Class:      FailFast,
+		Hint:       "Azure rejected the request, verify the values passed to the resource.",
+	},
+	{
+		Name:       "SkuNotAvailable",
+		Expression: `SkuNotAvailable`,
+		Class:      FailFast,
+		Hint:       "The VM size is not available in the region or zone, change the VM size or the location.",
+	},
+	{
+		Name:       "ResourceAlreadyExists",
+		Expression: `already exists - to be managed via Terraform this resource needs to be imported`,
+		Class:      FailFast,
+		Hint:       "A resource with the same name exists, probably leaked by a previous test run.",
+	},
+
+	// errors that need a manual action
+	{
+		Name:       "MarketplaceTermsNotAccepted",
+		Expression: `Legal terms have not been accepted|have not accepted the legal terms|ResourcePurchaseValidationFailed`,
+		Class:      NeedsHuman,
+		Hint:       "Marketplace terms of the image are not accepted, run `az vm image terms accept --publisher paloaltonetworks --offer <offer> --plan <sku>`.",
+	},
+	{
+		// Also raised for unaccepted terms, retrying it would only delay the failure.
+		Name:       "MarketplacePurchaseEligibilityFailed",
+		Expression: `MarketplacePurchaseEligibilityFailed`,
+		Class:      NeedsHuman,
+		Hint:       "The subscription cannot purchase the Marketplace image, accept its terms or verify that the subscription allows Marketplace purchases.",
+	},
+	{
+		Name:       "QuotaExceeded",
+		Expression: `QuotaExceeded|exceeding approved .* quota`,
+		Class:      NeedsHuman,
+		Hint:       "A subscription quota is exceeded, clean up leaked resources or request a quota increase.",
+	},
+	{
+		Name:       "AuthorizationFailed",
+		Expression: `AuthorizationFailed|LinkedAuthorizationFailed`,
+		Class:      NeedsHuman,
+		Hint:       "The identity running the tests lacks permissions.",
+	},
+	{
+		Name:       "SubscriptionNotRegistered",
+		Expression: `MissingSubscriptionRegistration|SubscriptionNotRegistered`,
+		Class:      NeedsHuman,
+		Hint:       "A resource provider is not registered in the subscription, run `az provider register --namespace <namespace>`.",
+	},
+	{
+		Name:       "AuthenticationFailed",
+		Expression: `building AzureRM Client|Please run 'az login'|InvalidAuthenticationToken`,
+		Class:      NeedsHuman,
+		Hint:       "Authentication to Azure failed, verify the credentials.",
+	},
+})
+
+func compile(patterns []Pattern) []Pattern {
+	for i := range patterns {
+		patterns[i].re = regexp.MustCompile(patterns[i].Expression)
+	}
+	return patterns
+}
+
+// Classification is the result of classifying an error.
+type Classification struct {
+	Class Class
+	// Pattern is the catalogue entry that matched, nil for unknown errors.
+	Pattern *Pattern
+	// Match is the part of the output that matched the pattern.
+	Match string
+}
+
+// String returns a single line description for the test output.
+func (c Classification) String() string {
+	if c.Pattern == nil {
+		return fmt.Sprintf("[%s] error not found in the Azure error catalogue", c.Class)
+	}
+	return fmt.Sprintf("[%s] %s (%q): %s", c.Class, c.Pattern.Name, c.Match, c.Pattern.Hint)
+}
+
+// Classify finds the catalogue entry matching the output of a failed command. When more than one entry matches,
+// the one requiring a manual action wins over the one failing fast, which in turn wins over a retry.
+func Classify(output string) Classification {
+	result := Classification{Class: Unknown}
+	for i := range Catalogue {
+		p := &Catalogue[i]
+		if result.Pattern != nil && precedence[p.Class] <= precedence[result.Class] {
+			continue
+		}
+		if m := p.re.FindString(output); m != "" {
+			result = Classification{Class: p.Class, Pattern: p, Match: strings.TrimSpace(m)}
+		}
+	}
+	return result
+}
+
+// RetryableErrors returns all transient errors in the format of terraform.Options.RetryableTerraformErrors.
+func RetryableErrors() map[string]string {
+	errors := map[string]string{}
+	for _, p := range Catalogue {
+		if p.Class == Retry {
+			errors[p.Expression] = p.Name + ": " + p.Hint
+		}
+	}
+	return errors
+}
+
+// TimeBetweenRetries is longer than Terratest's default, Azure operations block resources for minutes.
+const TimeBetweenRetries = 30 * time.Second
+
+// WithRetryableErrors returns a copy of the options with Terratest's default and Azure specific retryable errors.
+func WithRetryableErrors(t testing.TestingT, options *terraform.Options) *terraform.Options {
+	options = terraform.WithDefaultRetryableErrors(t, options)
+	for k, v := range RetryableErrors() {
+		options.RetryableTerraformErrors[k] = v
+	}
+	options.TimeBetweenRetries = TimeBetweenRetries
+	return options
+}
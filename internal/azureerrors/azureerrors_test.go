@@ -0,0 +1,119 @@
+package azureerrors
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// termsNotAccepted is the error of a VM created from an image whose Marketplace terms are not accepted.
+const termsNotAccepted = `Error: creating Linux Virtual Machine (Subscription: "00000000-0000-0000-0000-000000000000"
+Resource Group Name: "example-rg"
+Virtual Machine Name: "fw-1"): performing CreateOrUpdate: unexpected status 400 with error: MarketplacePurchaseEligibilityFailed: Marketplace purchase eligibilty check returned errors. See inner errors for details.
+Details: [{"code":"BadRequest","message":"Offer with PublisherId: 'paloaltonetworks', OfferId: 'vmseries-flex' cannot be purchased due to validation errors. For more information see details. Correlation Id: '11111111-1111-1111-1111-111111111111' You have not accepted the legal terms on this subscription: '00000000-0000-0000-0000-000000000000' for this plan. Before the subscription can be used, you need to accept the legal terms of the image. To read and accept legal terms, use the Azure CLI commands described at https://go.microsoft.com/fwlink/?linkid=2110637 or the PowerShell commands available at https://go.microsoft.com/fwlink/?linkid=862451. Alternatively, deploying via the Azure portal provides a UI experience for reading and accepting the legal terms. Offer details: publisher='paloaltonetworks' offer = 'vmseries-flex', sku = 'byol', Correlation Id: '11111111-1111-1111-1111-111111111111'."}]`
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		output string
+		class  Class
+		name   string
+	}{
+		{
+			`Error: updating Network Interface "fw-1-public" (Resource Group "rg"): network.InterfacesClient#CreateOrUpdate: Failure sending request: StatusCode=0 -- Original Error: Code="RetryableError" Message="A retryable error occurred."`,
+			Retry, "RetryableError",
+		},
+		{
+			`Error: creating/updating Subnet "private": network.SubnetsClient#CreateOrUpdate: Failure sending request: StatusCode=0 -- Original Error: Code="AnotherOperationInProgress" Message="Another operation on this or dependent resource is in progress."`,
+			Retry, "AnotherOperationInProgress",
+		},
+		{
+			`Error: deleting Subnet "mgmt": Code="InUseSubnetCannotBeDeleted" Message="Subnet mgmt is in use by /subscriptions/.../ipConfigurations/primary and cannot be deleted."`,
+			Retry, "InUseSubnetCannotBeDeleted",
+		},
+		{
+			`Error: creating Linux Virtual Machine "fw-1": compute.VirtualMachinesClient#CreateOrUpdate: Failure sending request: StatusCode=400 -- Original Error: Code="MarketplacePurchaseEligibilityFailed" Message="Marketplace purchase eligibilty check returned errors."`,
+			NeedsHuman, "MarketplacePurchaseEligibilityFailed",
+		},
+		{
+			termsNotAccepted,
+			NeedsHuman, "MarketplaceTermsNotAccepted",
+		},
+		{
+			`Error: creating Linux Virtual Machine "fw-1": StatusCode=400 -- Original Error: Code="ResourcePurchaseValidationFailed" Message="User failed validation to purchase resources. Error message: 'Legal terms have not been accepted for this item on this subscription.'"`,
+			NeedsHuman, "MarketplaceTermsNotAccepted",
+		},
+		{
+			`Code="OperationNotAllowed" Message="Operation could not be completed as it results in exceeding approved standardDSv2Family Cores quota."`,
+			NeedsHuman, "QuotaExceeded",
+		},
+		{
+			`Error: Resource precondition failed
+
+  on ../../modules/vmss/main.tf line 55:
+Either password or ssh_key must be set in order to have access to the device`,
+			FailFast, "ConditionFailed",
+		},
+		{
+			`Error: Unsupported argument
+
+  on main.tf line 12, in module "vnet":
+  12:   subnet = {}`,
+			FailFast, "InvalidConfiguration",
+		},
+		{
+			// needs-human wins over a retry reported in the same output
+			`Code="RetryableError" ... Code="AuthorizationFailed" Message="The client does not have authorization"`,
+			NeedsHuman, "AuthorizationFailed",
+		},
+		{
+			`Error: something completely different`,
+			Unknown, "",
+		},
+	}
+	for _, c := range cases {
+		got := Classify(c.output)
+		name := ""
+		if got.Pattern != nil {
+			name = got.Pattern.Name
+		}
+		if got.Class != c.class || name != c.name {
+			t.Errorf("Classify(%.60q...) = %s, %s, want %s, %s", c.output, got.Class, name, c.class, c.name)
+		}
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	names := map[string]bool{}
+	for _, p := range Catalogue {
+		if names[p.Name] {
+			t.Errorf("duplicate pattern %s", p.Name)
+		}
+		names[p.Name] = true
+		if p.Hint == "" {
+			t.Errorf("pattern %s has no hint", p.Name)
+		}
+		if _, ok := precedence[p.Class]; !ok {
+			t.Errorf("pattern %s has an invalid class %q", p.Name, p.Class)
+		}
+	}
+}
+
+func TestRetryableErrors(t *testing.T) {
+	errors := RetryableErrors()
+	for expression, hint := range errors {
+		re := regexp.MustCompile(expression)
+		if re.MatchString(termsNotAccepted) || re.MatchString("Legal terms have not been accepted") {
+			t.Errorf("%s: missing Marketplace terms must not be retried", hint)
+		}
+	}
+	if _, ok := errors["RetryableError"]; !ok {
+		t.Error("RetryableError should be retried")
+	}
+}
+
+func TestClassificationString(t *testing.T) {
+	s := Classify(`Code="SkuNotAvailable"`).String()
+	if !strings.HasPrefix(s, "[fail-fast] SkuNotAvailable") {
+		t.Errorf("unexpected description: %s", s)
+	}
+}
@@ -0,0 +1,145 @@
+// Package azurenames verifies names of Azure resources against the naming rules of Azure, so that a name
+// too long or with a forbidden character fails a plan test instead of an apply.
+//
+// The rules, by Terraform resource types, are listed in Rules. Names are checked in a planned infrastructure,
+// see CheckPlan, or in a name template of the `name_templater` module, see CheckTemplate.
+package azurenames
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+// Validate returns every way the name breaks the rule.
+func (r Rule) Validate(name string) []string {
+	var problems []string
+	if n := utf8.RuneCountInString(name); n < r.Min || n > r.Max {
+		problems = append(problems, fmt.Sprintf("is %d characters long, %s allows %d to %d", n, r.Resource, r.Min, r.Max))
+	}
+	return append(problems, r.characters(name)...)
+}
+
+// characters returns every way characters of the name break the rule, regardless of its length.
+func (r Rule) characters(name string) []string {
+	var problems []string
+	forbidden := map[rune]bool{}
+	var chars []string
+	for _, c := range name {
+		if !r.Allowed.MatchString(string(c)) && !forbidden[c] {
+			forbidden[c] = true
+			chars = append(chars, fmt.Sprintf("%q", c))
+		}
+	}
+	if len(chars) > 0 {
+		problems = append(problems, fmt.Sprintf("contains %s, %s allows %s", strings.Join(chars, ", "), r.Resource, r.AllowedText))
+	}
+	if name == "" {
+		return problems
+	}
+	first, _ := utf8.DecodeRuneInString(name)
+	if r.Start != nil && !r.Start.MatchString(string(first)) {
+		problems = append(problems, fmt.Sprintf("starts with %q, it has to start with %s", first, r.StartText))
+	}
+	last, _ := utf8.DecodeLastRuneInString(name)
+	if r.End != nil && !r.End.MatchString(string(last)) {
+		problems = append(problems, fmt.Sprintf("ends with %q, it has to end with %s", last, r.EndText))
+	}
+	if r.Repeated != "" && strings.Contains(name, r.Repeated) {
+		problems = append(problems, fmt.Sprintf("contains %q, %s does not allow it", r.Repeated, r.Resource))
+	}
+	return problems
+}
+
+// Validate returns every way the name of a resource of the Terraform resource type breaks the naming rules.
+// Resource types without rules are not verified.
+func Validate(resourceType, name string) []string {
+	for _, r := range Rules[resourceType] {
+		if r.Attribute == "name" {
+			return r.Validate(name)
+		}
+	}
+	return nil
+}
+
+// Violation is a planned name breaking a naming rule.
+type Violation struct {
+	Address   string
+	Attribute string
+	Name      string
+	Problem   string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s %q %s", v.Address, v.Attribute, v.Name, v.Problem)
+}
+
+// CheckPlan verifies names of all resources planned for creation. Names not known until apply are skipped.
+func CheckPlan(plan *planassert.Plan) []Violation {
+	var violations []Violation
+	for _, rc := range plan.Changes {
+		if !rc.Created() {
+			continue
+		}
+		for _, r := range Rules[rc.Type] {
+			for _, v := range rc.Attribute(r.Attribute) {
+				name, ok := v.Value.(string)
+				if v.Unknown || !ok {
+					continue
+				}
+				for _, p := range r.Validate(name) {
+					violations = append(violations, Violation{Address: rc.Address, Attribute: r.Attribute, Name: name, Problem: p})
+				}
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Address < violations[j].Address })
+	return violations
+}
+
+// ValidNames verifies that all names known in the plan follow the naming rules of Azure.
+func ValidNames() planassert.Assertion {
+	return planassert.Assertion{
+		Description: "planned names follow the Azure naming rules",
+		Check: func(plan *planassert.Plan) error {
+			violations := CheckPlan(plan)
+			if len(violations) == 0 {
+				return nil
+			}
+			lines := make([]string, 0, len(violations))
+			for _, v := range violations {
+				lines = append(lines, v.String())
+			}
+			return fmt.Errorf("\n%s", strings.Join(lines, "\n"))
+		},
+	}
+}
+
+// CheckTemplate verifies the parts of a name template (a `format()` string with a single `%s`) which are
+// the same for every resource, and returns the number of characters left for the formatted names.
+func CheckTemplate(resourceType, template string) (budget int, problems []string) {
+	var r *Rule
+	for i := range Rules[resourceType] {
+		if Rules[resourceType][i].Attribute == "name" {
+			r = &Rules[resourceType][i]
+		}
+	}
+	if r == nil {
+		return 0, []string{fmt.Sprintf("there are no naming rules for %s", resourceType)}
+	}
+	fixed := strings.Replace(template, "%s", "", 1)
+	budget = r.Max - utf8.RuneCountInString(fixed)
+	if budget < 1 {
+		problems = append(problems, fmt.Sprintf("leaves no characters for names, %s allows up to %d", r.Resource, r.Max))
+	}
+	// a valid single character stands for the name, so that the start and the end of the template are verified
+	placeholder := "a"
+	if !r.Allowed.MatchString(placeholder) {
+		placeholder = "0"
+	}
+	problems = append(problems, r.characters(strings.Replace(template, "%s", placeholder, 1))...)
+	return budget, problems
+}
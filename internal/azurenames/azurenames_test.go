@@ -0,0 +1,117 @@
+package azurenames
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/nametemplate"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		resourceType, name string
+		want               []string
+	}{
+		{"azurerm_storage_account", "examplebootstrap01", nil},
+		{"azurerm_storage_account", "ab", []string{"is 2 characters long, Microsoft.Storage/storageAccounts allows 3 to 24"}},
+		{"azurerm_storage_account", "example-Bootstrap", []string{
+			`contains '-', 'B', Microsoft.Storage/storageAccounts allows lowercase letters and numbers`,
+		}},
+		{"azurerm_virtual_network", "example-transit", nil},
+		{"azurerm_virtual_network", "-transit.", []string{
+			"starts with '-', it has to start with an alphanumeric",
+			"ends with '.', it has to end with an alphanumeric or an underscore",
+		}},
+		{"azurerm_network_security_group", strings.Repeat("n", 81), []string{
+			"is 81 characters long, Microsoft.Network/networkSecurityGroups allows 1 to 80",
+		}},
+		{"azurerm_linux_virtual_machine", "example-fw-1", nil},
+		{"azurerm_linux_virtual_machine", "example_fw.1-", []string{
+			`contains '_', '.', Microsoft.Compute/virtualMachines allows characters other than spaces, control characters and ~ ! @ # $ % ^ & * ( ) = + _ [ ] { } \ | ; : . ' " , < > / ?`,
+			"ends with '-', it has to end with a character other than a period or a hyphen",
+		}},
+		{"azurerm_storage_share", "bootstrap--share", []string{`contains "--", Microsoft.Storage/storageAccounts/fileServices/shares does not allow it`}},
+		{"azurerm_resource_group", "example (rg)", []string{
+			`contains ' ', Microsoft.Resources/resourceGroups allows letters, digits, underscores, periods, parentheses and hyphens`,
+		}},
+		{"azurerm_monitor_autoscale_setting", "example-autoscale", nil},
+		{"azurerm_log_analytics_workspace", "log", []string{
+			"is 3 characters long, Microsoft.OperationalInsights/workspaces allows 4 to 63",
+		}},
+		{"azurerm_storage_share_file", "any name at all", nil},
+	} {
+		got := Validate(tc.resourceType, tc.name)
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s %q: got problems:\n%s\nwant:\n%s", tc.resourceType, tc.name, strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	data, err := os.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planassert.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range CheckPlan(plan) {
+		got = append(got, v.String())
+	}
+	want := []string{
+		`module.bootstrap["sa"].azurerm_storage_account.this[0]: name "example-Bootstrap-storage" is 25 characters long, Microsoft.Storage/storageAccounts allows 3 to 24`,
+		`module.bootstrap["sa"].azurerm_storage_account.this[0]: name "example-Bootstrap-storage" contains '-', 'B', Microsoft.Storage/storageAccounts allows lowercase letters and numbers`,
+		`module.vmseries["fw-1"].azurerm_virtual_machine.this: name "example-fw_1" contains '_', Microsoft.Compute/virtualMachines allows characters other than spaces, control characters and ~ ! @ # $ % ^ & * ( ) = + _ [ ] { } \ | ; : . ' " , < > / ?`,
+		`module.vmseries["fw-1"].azurerm_virtual_machine.this: os_profile.*.computer_name "example-fw_1" contains '_', Microsoft.Compute/virtualMachines allows characters other than spaces, control characters and ~ ! @ # $ % ^ & * ( ) = + _ [ ] { } \ | ; : . ' " , < > / ?`,
+		`module.vmss["common"].azurerm_linux_virtual_machine_scale_set.this: computer_name_prefix "example-autoscale-common-vmseries-firewalls-scale-set-prefix" is 60 characters long, Microsoft.Compute/virtualMachineScaleSets allows 1 to 58`,
+		`module.vmss["common"].azurerm_linux_virtual_machine_scale_set.this: network_interface.*.ip_configuration.*.public_ip_address.*.name "public-pip-" ends with '-', it has to end with an alphanumeric or an underscore`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if err := ValidNames().Check(plan); err == nil {
+		t.Error("ValidNames accepted invalid names")
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		resourceType, template string
+		budget                 int
+		problems               []string
+	}{
+		{"azurerm_virtual_network", "example-%s-vnet", 51, nil},
+		{"azurerm_storage_account", "examplest%s", 15, nil},
+		{"azurerm_storage_account", "example-st-%s", 13, []string{
+			`contains '-', Microsoft.Storage/storageAccounts allows lowercase letters and numbers`,
+		}},
+		{"azurerm_storage_account", "examplebootstrapstorage%s", 1, nil},
+		{"azurerm_storage_account", "examplebootstrapstorage1%s", 0, []string{
+			"leaves no characters for names, Microsoft.Storage/storageAccounts allows up to 24",
+		}},
+		{"azurerm_network_interface", "%s-nic-", 75, []string{"ends with '-', it has to end with an alphanumeric or an underscore"}},
+		{"azurerm_storage_share_file", "%s", 0, []string{"there are no naming rules for azurerm_storage_share_file"}},
+	} {
+		budget, problems := CheckTemplate(tc.resourceType, tc.template)
+		if budget != tc.budget || strings.Join(problems, "\n") != strings.Join(tc.problems, "\n") {
+			t.Errorf("%s %q: got %d, %q, want %d, %q", tc.resourceType, tc.template, budget, problems, tc.budget, tc.problems)
+		}
+	}
+}
+
+// TestTemplaterTypes verifies that every resource type of the `name_templater` module known to have naming rules
+// is mapped to a Terraform resource type with rules.
+func TestTemplaterTypes(t *testing.T) {
+	for templaterType, resourceType := range TemplaterTypes {
+		if _, ok := nametemplate.DefaultAbbreviations[templaterType]; !ok {
+			t.Errorf("%s is not a resource type of name_templater", templaterType)
+		}
+		if len(Rules[resourceType]) == 0 {
+			t.Errorf("%s is mapped to %s, which has no rules", templaterType, resourceType)
+		}
+	}
+}
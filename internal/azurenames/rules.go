@@ -0,0 +1,186 @@
+package azurenames
+
+import "regexp"
+
+// Rule restricts a name of a resource of an Azure resource type, following
+// https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/resource-name-rules.
+type Rule struct {
+	// Resource is the Azure resource type, i.e. `Microsoft.Storage/storageAccounts`.
+	Resource string
+	// Attribute is a dot separated path of the attribute holding the name, see planassert.ResourceChange.Attribute.
+	Attribute string
+	Min, Max  int
+	// Allowed matches a single allowed character, AllowedText describes the allowed characters.
+	Allowed     *regexp.Regexp
+	AllowedText string
+	// Start and End, when set, match the first and the last character.
+	Start, End         *regexp.Regexp
+	StartText, EndText string
+	// Repeated, when set, is a sequence which cannot appear in the name, i.e. consecutive hyphens.
+	Repeated string
+}
+
+var (
+	alphanumerics = regexp.MustCompile(`^[0-9A-Za-z]$`)
+
+	// networkRule is the rule of most resources of Microsoft.Network.
+	networkRule = Rule{
+		Allowed:     regexp.MustCompile(`^[0-9A-Za-z_.-]$`),
+		AllowedText: "alphanumerics, underscores, periods and hyphens",
+		Start:       alphanumerics,
+		StartText:   "an alphanumeric",
+		End:         regexp.MustCompile(`^[0-9A-Za-z_]$`),
+		EndText:     "an alphanumeric or an underscore",
+	}
+
+	// vmRule is the rule of Linux virtual machines and scale sets, and of their host names.
+	vmRule = Rule{
+		Allowed:     regexp.MustCompile(`^[^\s\p{Cc}~!@#$%^&*()=+_\[\]{}\\|;:.'",<>/?]$`),
+		AllowedText: "characters other than spaces, control characters and ~ ! @ # $ % ^ & * ( ) = + _ [ ] { } \\ | ; : . ' \" , < > / ?",
+		End:         regexp.MustCompile(`^[^.-]$`),
+		EndText:     "a character other than a period or a hyphen",
+	}
+
+	// insightsRule is the rule of Application Insights and autoscale settings.
+	insightsRule = Rule{
+		Allowed:     regexp.MustCompile(`^[^\p{Cc}%&\\?/]$`),
+		AllowedText: `characters other than control characters and % & \ ? /`,
+		End:         regexp.MustCompile(`^[^ .]$`),
+		EndText:     "a character other than a space or a period",
+	}
+)
+
+func rule(base Rule, resource, attribute string, min, max int) Rule {
+	base.Resource, base.Attribute, base.Min, base.Max = resource, attribute, min, max
+	return base
+}
+
+// Rules are naming rules by Terraform resource types. Only resource types created by the modules are listed.
+var Rules = map[string][]Rule{
+	"azurerm_resource_group": {{
+		Resource:    "Microsoft.Resources/resourceGroups",
+		Attribute:   "name",
+		Min:         1,
+		Max:         90,
+		Allowed:     regexp.MustCompile(`^[\p{L}\p{N}_.()-]$`),
+		AllowedText: "letters, digits, underscores, periods, parentheses and hyphens",
+		End:         regexp.MustCompile(`^[^.]$`),
+		EndText:     "a character other than a period",
+	}},
+
+	"azurerm_virtual_network":         {rule(networkRule, "Microsoft.Network/virtualNetworks", "name", 2, 64)},
+	"azurerm_subnet":                  {rule(networkRule, "Microsoft.Network/virtualNetworks/subnets", "name", 1, 80)},
+	"azurerm_virtual_network_peering": {rule(networkRule, "Microsoft.Network/virtualNetworks/virtualNetworkPeerings", "name", 1, 80)},
+	"azurerm_network_security_group":  {rule(networkRule, "Microsoft.Network/networkSecurityGroups", "name", 1, 80)},
+	"azurerm_network_security_rule":   {rule(networkRule, "Microsoft.Network/networkSecurityGroups/securityRules", "name", 1, 80)},
+	"azurerm_route_table":             {rule(networkRule, "Microsoft.Network/routeTables", "name", 1, 80)},
+	"azurerm_route":                   {rule(networkRule, "Microsoft.Network/routeTables/routes", "name", 1, 80)},
+	"azurerm_public_ip":               {rule(networkRule, "Microsoft.Network/publicIPAddresses", "name", 1, 80)},
+	"azurerm_public_ip_prefix":        {rule(networkRule, "Microsoft.Network/publicIPPrefixes", "name", 1, 80)},
+	"azurerm_nat_gateway":             {rule(networkRule, "Microsoft.Network/natGateways", "name", 1, 80)},
+	"azurerm_network_interface":       {rule(networkRule, "Microsoft.Network/networkInterfaces", "name", 1, 80)},
+	"azurerm_application_gateway":     {rule(networkRule, "Microsoft.Network/applicationGateways", "name", 1, 80)},
+	"azurerm_virtual_network_gateway": {rule(networkRule, "Microsoft.Network/virtualNetworkGateways", "name", 1, 80)},
+	"azurerm_local_network_gateway":   {rule(networkRule, "Microsoft.Network/localNetworkGateways", "name", 1, 80)},
+	"azurerm_bastion_host":            {rule(networkRule, "Microsoft.Network/bastionHosts", "name", 1, 80)},
+	"azurerm_lb_backend_address_pool": {rule(networkRule, "Microsoft.Network/loadBalancers/backendAddressPools", "name", 1, 80)},
+	"azurerm_lb_probe":                {rule(networkRule, "Microsoft.Network/loadBalancers/probes", "name", 1, 80)},
+	"azurerm_lb_rule":                 {rule(networkRule, "Microsoft.Network/loadBalancers/loadBalancingRules", "name", 1, 80)},
+	"azurerm_lb_outbound_rule":        {rule(networkRule, "Microsoft.Network/loadBalancers/outboundRules", "name", 1, 80)},
+	"azurerm_virtual_network_gateway_connection": {
+		rule(networkRule, "Microsoft.Network/connections", "name", 1, 80),
+	},
+	"azurerm_lb": {
+		rule(networkRule, "Microsoft.Network/loadBalancers", "name", 1, 80),
+		rule(networkRule, "Microsoft.Network/loadBalancers/frontendIPConfigurations", "frontend_ip_configuration.*.name", 1, 80),
+	},
+
+	"azurerm_availability_set": {rule(networkRule, "Microsoft.Compute/availabilitySets", "name", 1, 80)},
+	"azurerm_managed_disk": {{
+		Resource:    "Microsoft.Compute/disks",
+		Attribute:   "name",
+		Min:         1,
+		Max:         80,
+		Allowed:     regexp.MustCompile(`^[0-9A-Za-z_-]$`),
+		AllowedText: "alphanumerics, underscores and hyphens",
+	}},
+	"azurerm_virtual_machine": {
+		rule(vmRule, "Microsoft.Compute/virtualMachines", "name", 1, 64),
+		rule(vmRule, "Microsoft.Compute/virtualMachines", "os_profile.*.computer_name", 1, 64),
+	},
+	"azurerm_linux_virtual_machine": {
+		rule(vmRule, "Microsoft.Compute/virtualMachines", "name", 1, 64),
+		rule(vmRule, "Microsoft.Compute/virtualMachines", "computer_name", 1, 64),
+	},
+	"azurerm_linux_virtual_machine_scale_set": {
+		rule(vmRule, "Microsoft.Compute/virtualMachineScaleSets", "name", 1, 64),
+		rule(vmRule, "Microsoft.Compute/virtualMachineScaleSets", "computer_name_prefix", 1, 58),
+		rule(networkRule, "Microsoft.Network/publicIPAddresses", "network_interface.*.ip_configuration.*.public_ip_address.*.name", 1, 80),
+	},
+
+	"azurerm_storage_account": {{
+		Resource:    "Microsoft.Storage/storageAccounts",
+		Attribute:   "name",
+		Min:         3,
+		Max:         24,
+		Allowed:     regexp.MustCompile(`^[0-9a-z]$`),
+		AllowedText: "lowercase letters and numbers",
+	}},
+	"azurerm_storage_share": {{
+		Resource:    "Microsoft.Storage/storageAccounts/fileServices/shares",
+		Attribute:   "name",
+		Min:         3,
+		Max:         63,
+		Allowed:     regexp.MustCompile(`^[0-9a-z-]$`),
+		AllowedText: "lowercase letters, numbers and hyphens",
+		Start:       regexp.MustCompile(`^[0-9a-z]$`),
+		StartText:   "a lowercase letter or a number",
+		End:         regexp.MustCompile(`^[0-9a-z]$`),
+		EndText:     "a lowercase letter or a number",
+		Repeated:    "--",
+	}},
+
+	"azurerm_application_insights":      {rule(insightsRule, "Microsoft.Insights/components", "name", 1, 260)},
+	"azurerm_monitor_autoscale_setting": {rule(insightsRule, "Microsoft.Insights/autoscalesettings", "name", 1, 260)},
+	"azurerm_log_analytics_workspace": {{
+		Resource:    "Microsoft.OperationalInsights/workspaces",
+		Attribute:   "name",
+		Min:         4,
+		Max:         63,
+		Allowed:     regexp.MustCompile(`^[0-9A-Za-z-]$`),
+		AllowedText: "alphanumerics and hyphens",
+		Start:       alphanumerics,
+		StartText:   "an alphanumeric",
+		End:         alphanumerics,
+		EndText:     "an alphanumeric",
+	}},
+}
+
+// TemplaterTypes maps resource types of the `name_templater` module to Terraform resource types.
+var TemplaterTypes = map[string]string{
+	"vnet":                      "azurerm_virtual_network",
+	"subnet":                    "azurerm_subnet",
+	"vnet_peering":              "azurerm_virtual_network_peering",
+	"virtual_network_gateway":   "azurerm_virtual_network_gateway",
+	"nsg":                       "azurerm_network_security_group",
+	"nsg_rule":                  "azurerm_network_security_rule",
+	"route_table":               "azurerm_route_table",
+	"udr":                       "azurerm_route",
+	"public_ip":                 "azurerm_public_ip",
+	"public_ip_prefix":          "azurerm_public_ip_prefix",
+	"nat_gw":                    "azurerm_nat_gateway",
+	"load_balancer":             "azurerm_lb",
+	"application_gw":            "azurerm_application_gateway",
+	"storage_account":           "azurerm_storage_account",
+	"file_share":                "azurerm_storage_share",
+	"application_insights":      "azurerm_application_insights",
+	"log_analytics_workspace":   "azurerm_log_analytics_workspace",
+	"network_interface":         "azurerm_network_interface",
+	"availability_set":          "azurerm_availability_set",
+	"os_disk":                   "azurerm_managed_disk",
+	"data_disk":                 "azurerm_managed_disk",
+	"virtual_machine":           "azurerm_linux_virtual_machine",
+	"virtual_machine_scale_set": "azurerm_linux_virtual_machine_scale_set",
+	"resource_group":            "azurerm_resource_group",
+	"bastion":                   "azurerm_bastion_host",
+}
@@ -0,0 +1,243 @@
+// Package bootstrappkg builds bootstrap packages of VM-Series firewalls for the bootstrap module.
+//
+// A bootstrap package is a File Share with the `config`, `content`, `software`, `plugins` and `license` directories.
+// The module creates the directories and uploads files given with `files`, a map of local paths to paths in the
+// package, and the files of `bootstrap_files_dir`, which has the layout of the package. Only the five directories
+// are created, a file has to be placed directly in one of them, and the firewall ignores files it does not
+// recognize by their names, i.e. a content update not named `panupv2-all-contents-*`.
+package bootstrappkg
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/initcfg"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/panosconfig"
+)
+
+// Directories are the directories of a bootstrap package, created by the module.
+var Directories = []string{"config", "content", "software", "plugins", "license"}
+
+// names match file names the firewall reads from each directory, described by namesText.
+var (
+	names = map[string]*regexp.Regexp{
+		"config":   regexp.MustCompile(`^(([0-9A-Za-z-]+-)?init-cfg\.txt|bootstrap\.xml)$`),
+		"content":  regexp.MustCompile(`^panup(v[0-9])?-all-(contents|apps|antivirus|wildfire)-[0-9]+-[0-9]+$`),
+		"software": regexp.MustCompile(`^PanOS_vm-[0-9]+\.[0-9]+\.[0-9]+(-h[0-9]+)?$`),
+		"plugins":  regexp.MustCompile(`^[a-z][a-z0-9_]*-[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.]+)?$`),
+		"license":  regexp.MustCompile(`^(authcodes|.+\.key)$`),
+	}
+	namesText = map[string]string{
+		"config":   "init-cfg.txt, <uuid or serial number>-init-cfg.txt or bootstrap.xml",
+		"content":  "an update like panupv2-all-contents-8729-8157 or panup-all-antivirus-4222-4735",
+		"software": "an image like PanOS_vm-10.2.3",
+		"plugins":  "a plugin like vm_series-2.0.3",
+		"license":  "authcodes or a license key file with the .key extension",
+	}
+	authCode = regexp.MustCompile(`^[A-Za-z0-9]{6,32}$`)
+)
+
+// Package maps paths in a bootstrap package, like `config/init-cfg.txt`, to local paths of the files.
+type Package map[string]string
+
+// Problem is a file the firewall would not read.
+type Problem struct {
+	// Remote is the path of the file in the package.
+	Remote  string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Remote, p.Message)
+}
+
+// CheckPath returns why the firewall would not read a file placed at the path of a package, or an empty string.
+func CheckPath(remote string) string {
+	dir, name := path.Split(remote)
+	dir = strings.TrimSuffix(dir, "/")
+	re, ok := names[dir]
+	switch {
+	case dir == "":
+		return fmt.Sprintf("is not in a directory, place it in one of %s", strings.Join(Directories, ", "))
+	case !ok:
+		return fmt.Sprintf("directory %s is not created by the module, place it directly in one of %s", dir, strings.Join(Directories, ", "))
+	case !re.MatchString(name):
+		return fmt.Sprintf("%q is not a file read from %s, expected %s", name, dir, namesText[dir])
+	}
+	return ""
+}
+
+// Remotes returns the paths in the package, sorted.
+func (p Package) Remotes() []string {
+	remotes := make([]string, 0, len(p))
+	for r := range p {
+		remotes = append(remotes, r)
+	}
+	sort.Strings(remotes)
+	return remotes
+}
+
+// Check verifies paths of all files and contents of `init-cfg.txt`, `bootstrap.xml` and `authcodes`.
+func (p Package) Check() []Problem {
+	var problems []Problem
+	report := func(remote, format string, args ...interface{}) {
+		problems = append(problems, Problem{Remote: remote, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, remote := range p.Remotes() {
+		if msg := CheckPath(remote); msg != "" {
+			report(remote, "%s", msg)
+			continue
+		}
+		data, err := os.ReadFile(p[remote])
+		if err != nil {
+			report(remote, "%v", err)
+			continue
+		}
+		switch name := path.Base(remote); {
+		case strings.HasSuffix(name, "init-cfg.txt"):
+			config, err := initcfg.Parse(data)
+			if err != nil {
+				report(remote, "%v", err)
+				continue
+			}
+			for _, ip := range initcfg.Validate(config) {
+				report(remote, "%s", ip)
+			}
+		case name == "bootstrap.xml":
+			config, err := panosconfig.Parse(data)
+			if err != nil {
+				report(remote, "%v", err)
+				continue
+			}
+			for _, cp := range panosconfig.Check(config) {
+				report(remote, "%s", cp)
+			}
+		case name == "authcodes":
+			codes := strings.Fields(string(data))
+			if len(codes) == 0 {
+				report(remote, "has no auth codes")
+			}
+			for _, code := range codes {
+				if !authCode.MatchString(code) {
+					report(remote, "%q is not an auth code", code)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// Read returns the package laid out in dir, like `bootstrap_files_dir` of the module.
+func Read(dir string) (Package, error) {
+	p := Package{}
+	err := filepath.WalkDir(dir, func(local string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, local)
+		if err != nil {
+			return err
+		}
+		p[filepath.ToSlash(rel)] = local
+		return nil
+	})
+	return p, err
+}
+
+// Build copies files of the package to dir with the layout of a bootstrap package, all directories are created
+// even when empty. It returns the package of the copies.
+func (p Package) Build(dir string) (Package, error) {
+	for _, d := range Directories {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			return nil, err
+		}
+	}
+	built := Package{}
+	for _, remote := range p.Remotes() {
+		local := filepath.Join(dir, filepath.FromSlash(remote))
+		if err := copyFile(p[remote], local); err != nil {
+			return nil, err
+		}
+		built[remote] = local
+	}
+	return built, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// MD5 returns the `files_md5` map of the module, hex encoded MD5 hashes of the files by their local paths.
+func (p Package) MD5() (map[string]string, error) {
+	sums := map[string]string{}
+	for _, local := range p {
+		data, err := os.ReadFile(local)
+		if err != nil {
+			return nil, err
+		}
+		sum := md5.Sum(data)
+		sums[filepath.ToSlash(local)] = hex.EncodeToString(sum[:])
+	}
+	return sums, nil
+}
+
+// Tfvars returns the inputs of the bootstrap module for the package: `files` mapping local paths to paths in the
+// package, or `bootstrap_files_dir` when it is given, and `files_md5`.
+func (p Package) Tfvars(bootstrapFilesDir string) ([]byte, error) {
+	sums, err := p.MD5()
+	if err != nil {
+		return nil, err
+	}
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	if bootstrapFilesDir != "" {
+		body.SetAttributeValue("bootstrap_files_dir", cty.StringVal(filepath.ToSlash(bootstrapFilesDir)))
+	} else {
+		files := map[string]cty.Value{}
+		for remote, local := range p {
+			files[filepath.ToSlash(local)] = cty.StringVal(remote)
+		}
+		body.SetAttributeValue("files", mapVal(files))
+	}
+	md5s := map[string]cty.Value{}
+	for local, sum := range sums {
+		md5s[local] = cty.StringVal(sum)
+	}
+	body.SetAttributeValue("files_md5", mapVal(md5s))
+	return bytes.TrimLeft(hclwrite.Format(f.Bytes()), "\n"), nil
+}
+
+func mapVal(m map[string]cty.Value) cty.Value {
+	if len(m) == 0 {
+		return cty.MapValEmpty(cty.String)
+	}
+	return cty.MapVal(m)
+}
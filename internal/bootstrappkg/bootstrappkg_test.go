@@ -0,0 +1,169 @@
+package bootstrappkg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/healthprobe"
+)
+
+func TestCheckPath(t *testing.T) {
+	for remote, want := range map[string]string{
+		"config/init-cfg.txt":                    "",
+		"config/0123456789ab-init-cfg.txt":       "",
+		"config/bootstrap.xml":                   "",
+		"content/panupv2-all-contents-8729-8157": "",
+		"content/panup-all-antivirus-4222-4735":  "",
+		"software/PanOS_vm-10.2.3-h4":            "",
+		"plugins/vm_series-2.0.3":                "",
+		"license/authcodes":                      "",
+		"license/fw-1.key":                       "",
+		"init-cfg.txt":                           "is not in a directory",
+		"config/files/init-cfg.txt":              "directory config/files is not created by the module",
+		"content/contents-8729-8157":             `"contents-8729-8157" is not a file read from content`,
+		"config/init-cfg.sample.txt":             `"init-cfg.sample.txt" is not a file read from config`,
+		"software/PanOS_vm-10.2":                 `"PanOS_vm-10.2" is not a file read from software`,
+		"license/authcodes.txt":                  `"authcodes.txt" is not a file read from license`,
+	} {
+		got := CheckPath(remote)
+		if !strings.HasPrefix(got, want) || (want == "") != (got == "") {
+			t.Errorf("%s: got %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func write(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, map[string]string{
+		"init-cfg.txt":  "type=static\nip-address=10.0.0.4\n",
+		"bootstrap.xml": `<config><devices><entry name="localhost.localdomain"><network><virtual-router><entry name="vr"><interface><member>ethernet1/1</member></interface></entry></virtual-router></network></entry></devices></config>`,
+		"authcodes":     "D1234567 not-a-code\n",
+		"contents-1-2":  "",
+	})
+	p := Package{
+		"config/init-cfg.txt":  filepath.Join(dir, "init-cfg.txt"),
+		"config/bootstrap.xml": filepath.Join(dir, "bootstrap.xml"),
+		"license/authcodes":    filepath.Join(dir, "authcodes"),
+		"license/fw.key":       filepath.Join(dir, "missing.key"),
+		"content/contents-1-2": filepath.Join(dir, "contents-1-2"),
+	}
+	var got []string
+	for _, problem := range p.Check() {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		`config/bootstrap.xml: virtual-router "vr": interface "ethernet1/1" is not defined`,
+		"config/init-cfg.txt: type is static, netmask has to be set",
+		"config/init-cfg.txt: type is static, default-gateway has to be set",
+		`content/contents-1-2: "contents-1-2" is not a file read from content, expected an update like panupv2-all-contents-8729-8157 or panup-all-antivirus-4222-4735`,
+		`license/authcodes: "not-a-code" is not an auth code`,
+		"license/fw.key: open " + filepath.Join(dir, "missing.key") + ": no such file or directory",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuild(t *testing.T) {
+	src, out := t.TempDir(), filepath.Join(t.TempDir(), "bootstrap")
+	write(t, src, map[string]string{"init-cfg.txt": "type=dhcp-client\n", "authcodes": "D1234567\n"})
+	p := Package{
+		"config/init-cfg.txt": filepath.Join(src, "init-cfg.txt"),
+		"license/authcodes":   filepath.Join(src, "authcodes"),
+	}
+	built, err := p.Build(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range Directories {
+		if fi, err := os.Stat(filepath.Join(out, d)); err != nil || !fi.IsDir() {
+			t.Errorf("directory %s is not created: %v", d, err)
+		}
+	}
+	read, err := Read(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, built) {
+		t.Errorf("read %v, built %v", read, built)
+	}
+
+	sums, err := built.MD5()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// md5 of "type=dhcp-client\n" and "D1234567\n".
+	initCfg, authcodes := filepath.ToSlash(built["config/init-cfg.txt"]), filepath.ToSlash(built["license/authcodes"])
+	want := map[string]string{
+		initCfg:   "b8618c6aa469d4665532a0498efbd8be",
+		authcodes: "c42ceade042e06c8b0bc7f24f8e50dff",
+	}
+	if !reflect.DeepEqual(sums, want) {
+		t.Errorf("got md5 %v, want %v", sums, want)
+	}
+
+	tfvars, err := built.Tfvars(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hclwrite aligns the values, compare with single spaces.
+	if got := strings.Join(strings.Fields(string(tfvars)), " "); !strings.HasPrefix(got, `bootstrap_files_dir = "`+filepath.ToSlash(out)+`"`) ||
+		!strings.Contains(got, `"`+initCfg+`" = "b8618c6aa469d4665532a0498efbd8be"`) || strings.Contains(got, "files = ") {
+		t.Errorf("got tfvars:\n%s", got)
+	}
+	tfvars, err = built.Tfvars("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(strings.Fields(string(tfvars)), " "); !strings.Contains(got, `"`+authcodes+`" = "license/authcodes"`) || strings.Contains(got, "bootstrap_files_dir") {
+		t.Errorf("got tfvars:\n%s", got)
+	}
+}
+
+// TestExamples verifies files the examples place in bootstrap packages.
+func TestExamples(t *testing.T) {
+	files, err := filepath.Glob("../../examples/*/example.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		vars, err := healthprobe.ReadVarFiles(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vmseries, ok := vars["vmseries"]
+		if !ok {
+			continue
+		}
+		for key, vm := range vmseries.AsValueMap() {
+			if !vm.Type().IsObjectType() || !vm.Type().HasAttribute("bootstrap_storage") {
+				continue
+			}
+			storage := vm.GetAttr("bootstrap_storage")
+			if !storage.Type().IsObjectType() || !storage.Type().HasAttribute("static_files") {
+				continue
+			}
+			for _, remote := range storage.GetAttr("static_files").AsValueMap() {
+				if msg := CheckPath(remote.AsString()); msg != "" {
+					t.Errorf("%s: vmseries[%q]: %s: %s", file, key, remote.AsString(), msg)
+				}
+			}
+		}
+	}
+}
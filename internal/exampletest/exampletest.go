@@ -0,0 +1,455 @@
+// Package exampletest holds the Terratest harness shared by all examples.
+//
+// Each example describes what makes it different from the others with a Spec
+// (extra variables, variable files, fixture files, bootstrap storage shape) and
+// delegates the actual Validate, Plan, Apply and Idempotence flows to this package.
+//
+// Plan, Apply and Idempotence run in a temporary copy of the example and the modules it calls,
+// so that no files are written to the source tree and tests can run in parallel.
+// Apply and Idempotence are split into stages which can be skipped with test_structure, see runStages.
+package exampletest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/azureerrors"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/azurenames"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/initcfg"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/modulecheck"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/plansnapshot"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/varcheck"
+)
+
+// DefaultVarFile is the variables file every example ships with.
+const DefaultVarFile = "example.tfvars"
+
+// DefaultGoldenFile is the plan snapshot every example is compared with, relative to the example's directory.
+const DefaultGoldenFile = "testdata/plan.golden"
+
+// update regenerates golden plan snapshots instead of comparing them, run with `go test -run Plan -update`.
+var update = flag.Bool("update", false, "regenerate golden plan snapshots")
+
+// Spec describes how to run tests for a single example.
+type Spec struct {
+	// TerraformDir is the path to the example code, defaults to the current directory.
+	TerraformDir string
+	// VarFiles is a list of variable files passed to Terraform, defaults to `example.tfvars`.
+	VarFiles []string
+	// Vars are additional variables set on top of the variable files.
+	Vars map[string]interface{}
+	// FixtureFiles is a map where key is a file required by the example and value is a file
+	// it is created from, both relative to TerraformDir. Files are created in the test's working copy,
+	// files that already exist are not overwritten.
+	FixtureFiles map[string]string
+	// BootstrapStorage, when set, overrides the bootstrap Storage Account definition so that
+	// a randomly named Storage Account is used.
+	BootstrapStorage *BootstrapStorage
+	// PlanAssertions verify the planned topology in the Plan test. When empty, the plan is only checked for errors.
+	PlanAssertions []planassert.Assertion
+	// GoldenFile is the plan snapshot path relative to TerraformDir, defaults to `testdata/plan.golden`.
+	// The snapshot is compared for every example which has one, the `-update` flag regenerates it. Without a snapshot
+	// the comparison is skipped, after the plan is verified. Generated root modules, run InPlace, have no snapshot.
+	GoldenFile string
+	// ExpectedOutputs are names of outputs that have to be set after deployment, they are checked in the verify stage.
+	ExpectedOutputs []string
+	// InPlace runs Terraform directly in TerraformDir instead of in its temporary working copy.
+	// It is meant for directories that are temporary already, like generated root modules.
+	InPlace bool
+	// Matrix are variants the example is planned with in addition to its variable files, see Variant.
+	Matrix []Variant
+	// PrefixedExistingResourceGroup is set for examples which source an existing Resource Group by its name
+	// prefixed with `name_prefix`, like `gwlb_with_vmseries`, instead of by `resource_group_name` as is.
+	PrefixedExistingResourceGroup bool
+}
+
+// BootstrapStorage describes a shape of the variable defining bootstrap Storage Accounts.
+type BootstrapStorage struct {
+	// Variable is the name of the example's variable, i.e. `bootstrap_storage` or `bootstrap_storages`.
+	Variable string
+	// Key is the key of the Storage Account definition in the variable, defaults to `bootstrap`.
+	Key string
+	// PublicSubnetKey defaults to `public`.
+	PublicSubnetKey string
+	// PrivateSubnetKey defaults to `private`.
+	PrivateSubnetKey string
+	// IntranetCIDR is the CIDR used to build a static route to the protected networks.
+	IntranetCIDR string
+}
+
+// Names are the random names generated for a single test run.
+type Names struct {
+	NamePrefix         string
+	ResourceGroupName  string
+	StorageAccountName string
+}
+
+// generateNames is a variable so that unit tests can build options without the skeleton's randomness.
+var generateNames = func() (Names, error) {
+	randomNames, err := testskeleton.GenerateTerraformVarsInfo("azure")
+	if err != nil {
+		return Names{}, err
+	}
+	return Names{
+		NamePrefix:         randomNames.NamePrefix,
+		ResourceGroupName:  randomNames.AzureResourceGroupName,
+		StorageAccountName: randomNames.AzureStorageAccountName,
+	}, nil
+}
+
+// value renders the bootstrap storage definition for the given Storage Account name.
+func (b BootstrapStorage) value(storageAccountName string) map[string]interface{} {
+	definition := map[string]interface{}{
+		"name":             storageAccountName,
+		"public_snet_key":  orDefault(b.PublicSubnetKey, "public"),
+		"private_snet_key": orDefault(b.PrivateSubnetKey, "private"),
+	}
+	if b.IntranetCIDR != "" {
+		definition["intranet_cidr"] = b.IntranetCIDR
+	}
+	return map[string]interface{}{orDefault(b.Key, "bootstrap"): definition}
+}
+
+// TerraformOptions prepares fixture files and returns Terraform options for the example described by the spec.
+func TerraformOptions(t *testing.T, spec Spec) *terraform.Options {
+	terraformOptions, _ := newOptions(t, spec, "")
+	return terraformOptions
+}
+
+// newOptions returns Terraform options together with the random names they were built with. The working copy is
+// created in root, or in a temporary directory removed when the test finishes when root is empty.
+func newOptions(t *testing.T, spec Spec, root string) (*terraform.Options, Names) {
+	// prepare random prefix
+	names, err := generateNames()
+	if err != nil {
+		t.Fatalf("cannot generate random names: %v", err)
+	}
+
+	dir := orDefault(spec.TerraformDir, ".")
+	if !spec.InPlace {
+		if root == "" {
+			root = t.TempDir()
+		}
+		dir = workingCopy(t, dir, root)
+	}
+	prepareFixtureFiles(t, dir, spec.FixtureFiles)
+
+	vars := map[string]interface{}{
+		"name_prefix":         names.NamePrefix,
+		"resource_group_name": names.ResourceGroupName,
+	}
+	if spec.BootstrapStorage != nil {
+		vars[spec.BootstrapStorage.Variable] = spec.BootstrapStorage.value(names.StorageAccountName)
+	}
+	for k, v := range spec.Vars {
+		vars[k] = v
+	}
+
+	varFiles := spec.VarFiles
+	if len(varFiles) == 0 {
+		varFiles = []string{DefaultVarFile}
+	}
+
+	// define options for Terraform
+	return azureerrors.WithRetryableErrors(t, &terraform.Options{
+		TerraformDir:         dir,
+		VarFiles:             varFiles,
+		Vars:                 vars,
+		Logger:               logger.Default,
+		Lock:                 true,
+		Upgrade:              true,
+		SetVarsAfterVarFiles: true,
+	}), names
+}
+
+// masks returns placeholders for all random values, so that snapshots are stable between runs.
+func (n Names) masks() []plansnapshot.Mask {
+	return []plansnapshot.Mask{
+		{Value: n.NamePrefix, Placeholder: "<name_prefix>"},
+		{Value: n.ResourceGroupName, Placeholder: "<resource_group_name>"},
+		{Value: n.StorageAccountName, Placeholder: "<storage_account_name>"},
+	}
+}
+
+// goldenPath returns the path to the example's plan snapshot.
+func goldenPath(spec Spec) string {
+	return filepath.Join(orDefault(spec.TerraformDir, "."), orDefault(spec.GoldenFile, DefaultGoldenFile))
+}
+
+// workingCopy copies the example, together with the modules it calls, to the root directory and returns the path
+// to the copy. Terraform and the example itself (i.e. `local_file` resources) write files to the example's
+// directory, this way they never end up in the source tree and tests of the same example can run concurrently.
+func workingCopy(t *testing.T, dir, root string) string {
+	src, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatalf("cannot locate example: %v", err)
+	}
+	// keep the relative layout, examples refer to modules with `../../modules/<name>`
+	dst := filepath.Join(root, filepath.Base(filepath.Dir(src)), filepath.Base(src))
+	if err := copyDir(src, dst); err != nil {
+		t.Fatalf("cannot copy example: %v", err)
+	}
+	modules := filepath.Join(src, "..", "..", "modules")
+	if info, err := os.Stat(modules); err == nil && info.IsDir() {
+		if err := copyDir(modules, filepath.Join(root, "modules")); err != nil {
+			t.Fatalf("cannot copy modules: %v", err)
+		}
+	}
+	return dst
+}
+
+// skipCopy reports whether a file is Terraform's local state or test data saved by stages, which must not be shared
+// between test runs.
+func skipCopy(name string) bool {
+	return name == ".terraform" || name == ".test-data" || strings.HasPrefix(name, "terraform.tfstate")
+}
+
+// copyDir recursively copies a directory, preserving file modes and symbolic links.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && skipCopy(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		default:
+			buff, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, buff, info.Mode().Perm())
+		}
+	})
+}
+
+// prepareFixtureFiles creates files required by an example from their samples, unless they already exist.
+// A sample of `init-cfg.txt` is verified first, the firewall would silently ignore its mistakes.
+func prepareFixtureFiles(t *testing.T, dir string, files map[string]string) {
+	for dst, src := range files {
+		dstPath := filepath.Join(dir, dst)
+		if _, err := os.Stat(dstPath); err == nil {
+			continue
+		}
+		buff, err := os.ReadFile(filepath.Join(dir, src))
+		if err != nil {
+			t.Fatalf("cannot read fixture source: %v", err)
+		}
+		if filepath.Base(dst) == "init-cfg.txt" {
+			if err := checkInitCfg(buff); err != nil {
+				t.Fatalf("fixture source %s: %v", src, err)
+			}
+		}
+		if err := os.WriteFile(dstPath, buff, 0644); err != nil {
+			t.Fatalf("cannot write fixture file: %v", err)
+		}
+	}
+}
+
+// checkInitCfg verifies the content of `init-cfg.txt`.
+func checkInitCfg(data []byte) error {
+	config, err := initcfg.Parse(data)
+	if err != nil {
+		return err
+	}
+	problems := initcfg.Validate(config)
+	if len(problems) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(problems))
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// Validate runs `terraform validate` against the example.
+func Validate(t *testing.T) {
+	testskeleton.ValidateCode(t, nil)
+}
+
+// Plan plans the example and verifies that no errors are reported and that planned names are accepted by Azure.
+// When the spec defines PlanAssertions, they are run against the saved plan as well.
+// The plan is compared with the example's golden plan snapshot.
+// When the spec defines a Matrix, the example is planned with each variant in a separate subtest,
+// next to the `default` subtest planning it with its own variable files.
+func Plan(t *testing.T, spec Spec) {
+	if len(spec.Matrix) == 0 {
+		planVariant(t, spec, nil)
+		return
+	}
+	t.Run(DefaultVariant, func(t *testing.T) {
+		t.Parallel()
+		planVariant(t, spec, nil)
+	})
+	for _, variant := range spec.Matrix {
+		variant := variant
+		t.Run(variant.Name, func(t *testing.T) {
+			// every variant has its own working copy, they can be planned concurrently
+			t.Parallel()
+			planVariant(t, spec, &variant)
+		})
+	}
+}
+
+// planVariant plans the example with the variant's inputs applied, or with the spec's ones when the variant is nil.
+// Plan snapshots describe the example's own variable files, they are not compared for variants.
+func planVariant(t *testing.T, spec Spec, variant *Variant) {
+	golden := goldenPath(spec)
+	snapshot := variant == nil && !spec.InPlace
+	if variant != nil {
+		spec = variant.spec(spec)
+	}
+
+	// define options for Terraform
+	terraformOptions, names := newOptions(t, spec, "")
+	if err := checkVariableFiles(terraformOptions); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkModuleCalls(terraformOptions.TerraformDir); err != nil {
+		t.Fatal(err)
+	}
+	if variant != nil && variant.ExistingResourceGroup {
+		existingResourceGroup(t, terraformOptions, names, spec.PrefixedExistingResourceGroup)
+	}
+	// plan test infrastructure, any error fails the test, then verify the planned topology
+	plan, err := planassert.LoadE(t, terraformOptions)
+	if err != nil {
+		fatalClassified(t, err)
+	}
+	// names known at plan time are verified for every example, so that Azure does not reject them on apply
+	assertions := append([]planassert.Assertion{azurenames.ValidNames()}, spec.PlanAssertions...)
+	planassert.Check(t, plan, assertions)
+	if snapshot {
+		checkSnapshot(t, golden, plansnapshot.Render(plan, names.masks()))
+	}
+}
+
+// checkVariableFiles verifies the variable files against the example's variables without Terraform,
+// so that a malformed file fails the test before providers are downloaded.
+func checkVariableFiles(options *terraform.Options) error {
+	vars, err := varcheck.LoadModule(options.TerraformDir)
+	if err != nil {
+		return fmt.Errorf("cannot read variables: %w", err)
+	}
+	var files []string
+	for _, f := range options.VarFiles {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(options.TerraformDir, f)
+		}
+		files = append(files, f)
+	}
+	var provided []string
+	for name := range options.Vars {
+		provided = append(provided, name)
+	}
+	problems, err := varcheck.Check(vars, files, varcheck.Options{Provided: provided})
+	if err != nil {
+		return fmt.Errorf("cannot check variable files: %w", err)
+	}
+	// the working copy keeps the name of the example
+	dir, err := filepath.Abs(options.TerraformDir)
+	if err != nil {
+		return err
+	}
+	if rules, ok := varcheck.ExampleRules[filepath.Base(dir)]; ok {
+		refProblems, err := varcheck.CheckReferences(files, rules)
+		if err != nil {
+			return fmt.Errorf("cannot check references in variable files: %w", err)
+		}
+		problems = append(problems, refProblems...)
+	}
+	if len(problems) > 0 {
+		var lines []string
+		for _, p := range problems {
+			lines = append(lines, p.String())
+		}
+		return fmt.Errorf("variable files do not match the variables:\n%s", strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// checkModuleCalls verifies calls of local modules against their variables and outputs without Terraform,
+// so that an example not following a change of a module fails the test before providers are downloaded.
+func checkModuleCalls(dir string) error {
+	problems, err := modulecheck.Check(dir)
+	if err != nil {
+		return fmt.Errorf("cannot check module calls: %w", err)
+	}
+	if len(problems) > 0 {
+		var lines []string
+		for _, p := range problems {
+			lines = append(lines, p.String())
+		}
+		return fmt.Errorf("module calls do not match the modules:\n%s", strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// checkSnapshot compares the plan snapshot with the golden file, or regenerates it when `-update` is set.
+func checkSnapshot(t *testing.T, golden, actual string) {
+	diff, err := plansnapshot.Compare(golden, actual, *update)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// the plan itself is verified already, only the comparison is skipped until the snapshot is committed
+		t.Skipf("no plan snapshot found in %s, run with -update to create it and commit it", golden)
+	case err != nil:
+		t.Fatalf("cannot compare plan snapshot: %v", err)
+	case *update:
+		t.Logf("plan snapshot written to %s", golden)
+	case diff != "":
+		t.Errorf("planned resources differ from %s, review the diff and run with -update if the change is expected:\n%s", golden, diff)
+	}
+}
+
+// Apply deploys the example, verifies its outputs and destroys it.
+// The test is run in stages, see runStages.
+func Apply(t *testing.T, spec Spec) {
+	runStages(t, spec, StageSetup, StageApply, StageVerify, StageDestroy)
+}
+
+// Idempotence deploys the example, verifies its outputs, verifies there are no planned changes after deployment
+// and destroys it. It covers everything the Apply test does, there is no need to run both.
+func Idempotence(t *testing.T, spec Spec) {
+	runStages(t, spec, StageSetup, StageApply, StageVerify, StageIdempotence, StageDestroy)
+}
+
+// fatalClassified fails the test with the error and its classification found in the Azure error catalogue.
+func fatalClassified(t *testing.T, err error) {
+	t.Helper()
+	t.Fatalf("%v\nerror classification: %s", err, azureerrors.Classify(err.Error()))
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
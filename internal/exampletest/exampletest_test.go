@@ -0,0 +1,193 @@
+package exampletest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func stubNames(t *testing.T) {
+	original := generateNames
+	generateNames = func() (Names, error) {
+		return Names{NamePrefix: "abcd-", ResourceGroupName: "rg", StorageAccountName: "abcdstorage"}, nil
+	}
+	t.Cleanup(func() { generateNames = original })
+}
+
+func TestTerraformOptionsDefaults(t *testing.T) {
+	stubNames(t)
+
+	options := TerraformOptions(t, Spec{InPlace: true})
+
+	if options.TerraformDir != "." {
+		t.Errorf("TerraformDir = %q, want %q", options.TerraformDir, ".")
+	}
+	if !reflect.DeepEqual(options.VarFiles, []string{DefaultVarFile}) {
+		t.Errorf("VarFiles = %v, want %v", options.VarFiles, []string{DefaultVarFile})
+	}
+	want := map[string]interface{}{"name_prefix": "abcd-", "resource_group_name": "rg"}
+	if !reflect.DeepEqual(options.Vars, want) {
+		t.Errorf("Vars = %v, want %v", options.Vars, want)
+	}
+	if !options.SetVarsAfterVarFiles {
+		t.Error("SetVarsAfterVarFiles should be enabled")
+	}
+	if _, ok := options.RetryableTerraformErrors["RetryableError"]; !ok {
+		t.Error("Azure retryable errors should be configured")
+	}
+}
+
+func TestTerraformOptionsBootstrapStorage(t *testing.T) {
+	stubNames(t)
+
+	options := TerraformOptions(t, Spec{
+		InPlace:          true,
+		Vars:             map[string]interface{}{"enable_zones": false},
+		BootstrapStorage: &BootstrapStorage{Variable: "bootstrap_storages", IntranetCIDR: "10.0.1.0/24"},
+	})
+
+	want := map[string]interface{}{
+		"bootstrap": map[string]interface{}{
+			"name":             "abcdstorage",
+			"public_snet_key":  "public",
+			"private_snet_key": "private",
+			"intranet_cidr":    "10.0.1.0/24",
+		},
+	}
+	if !reflect.DeepEqual(options.Vars["bootstrap_storages"], want) {
+		t.Errorf("bootstrap_storages = %v, want %v", options.Vars["bootstrap_storages"], want)
+	}
+	if options.Vars["enable_zones"] != false {
+		t.Errorf("enable_zones = %v, want false", options.Vars["enable_zones"])
+	}
+}
+
+func TestPrepareFixtureFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sample.txt"), []byte("type=dhcp-client\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("keep me\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prepareFixtureFiles(t, dir, map[string]string{
+		"created.txt":  "sample.txt",
+		"existing.txt": "sample.txt",
+	})
+
+	for file, want := range map[string]string{"created.txt": "type=dhcp-client\n", "existing.txt": "keep me\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestWorkingCopy(t *testing.T) {
+	stubNames(t)
+
+	src := t.TempDir()
+	for file, content := range map[string]string{
+		"examples/ex/main.tf":                     `module "m" { source = "../../modules/m" }`,
+		"examples/ex/files/init-cfg.sample.txt":   "type=dhcp-client\n",
+		"examples/ex/.terraform/modules/m.json":   "{}",
+		"examples/ex/terraform.tfstate":           "{}",
+		"modules/m/main.tf":                       "",
+		"modules/m/.terraform/providers/registry": "",
+	} {
+		path := filepath.Join(src, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	example := filepath.Join(src, "examples", "ex")
+	options := TerraformOptions(t, Spec{
+		TerraformDir: example,
+		FixtureFiles: map[string]string{"files/init-cfg.txt": "files/init-cfg.sample.txt"},
+	})
+
+	dir := options.TerraformDir
+	if dir == example {
+		t.Fatal("Terraform should not run in the source directory")
+	}
+	for _, file := range []string{"main.tf", "files/init-cfg.txt", "../../modules/m/main.tf"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("working copy: %v", err)
+		}
+	}
+	for _, file := range []string{".terraform", "terraform.tfstate", "../../modules/m/.terraform"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			t.Errorf("working copy should not contain %s", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(example, "files", "init-cfg.txt")); err == nil {
+		t.Error("fixture files should not be created in the source tree")
+	}
+}
+
+func TestCheckVariableFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"variables.tf":   "variable \"location\" {\n  type = string\n}\n\nvariable \"name_prefix\" {\n  type = string\n}\n",
+		"example.tfvars": "location = \"North Europe\"\n",
+		"broken.tfvars":  "location = [\"North Europe\"]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options := &terraform.Options{
+		TerraformDir: dir,
+		VarFiles:     []string{DefaultVarFile},
+		Vars:         map[string]interface{}{"name_prefix": "test-"},
+	}
+	if err := checkVariableFiles(options); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	options.VarFiles = []string{"broken.tfvars"}
+	if err := checkVariableFiles(options); err == nil || !strings.Contains(err.Error(), "location: string required") {
+		t.Errorf("expected a type mismatch, got %v", err)
+	}
+}
+
+func TestCheckModuleCalls(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"modules/vnet/variables.tf": "variable \"name\" {\n  type = string\n}\n",
+		"modules/vnet/outputs.tf":   "output \"id\" {\n  value = var.name\n}\n",
+		"examples/vnet/main.tf":     "module \"vnet\" {\n  source = \"../../modules/vnet\"\n\n  name = \"vnet\"\n}\n\noutput \"id\" {\n  value = module.vnet.id\n}\n",
+		"examples/broken/main.tf":   "module \"vnet\" {\n  source = \"../../modules/vnet\"\n\n  vnet_name = \"vnet\"\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := checkModuleCalls(filepath.Join(root, "examples", "vnet")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := checkModuleCalls(filepath.Join(root, "examples", "broken"))
+	if err == nil || !strings.Contains(err.Error(), `unsupported argument "vnet_name"`) || !strings.Contains(err.Error(), `required variable "name"`) {
+		t.Errorf("expected problems of the module call, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package exampletest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/azureerrors"
+)
+
+// PlanFailure plans the example and verifies that planning fails with an error containing the expected message,
+// usually the `error_message` of a variable validation or a resource precondition.
+func PlanFailure(t *testing.T, spec Spec, expected string) {
+	terraformOptions, _ := newOptions(t, spec, "")
+	out, err := terraform.InitAndPlanE(t, terraformOptions)
+	if err == nil {
+		t.Fatalf("plan succeeded, expected it to fail with %q", expected)
+	}
+	if !containsDiagnostic(out+"\n"+err.Error(), expected) {
+		t.Fatalf("plan failed with an unexpected error, expected %q: %v\nerror classification: %s",
+			expected, err, azureerrors.Classify(err.Error()))
+	}
+}
+
+// containsDiagnostic reports whether Terraform output contains the message. Terraform draws a frame around
+// diagnostics and wraps long messages, so both are compared with the frame removed and whitespace collapsed.
+func containsDiagnostic(output, message string) bool {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		lines = append(lines, strings.TrimLeft(line, "│╷╵ \t"))
+	}
+	return strings.Contains(
+		strings.Join(strings.Fields(strings.Join(lines, " ")), " "),
+		strings.Join(strings.Fields(message), " "),
+	)
+}
@@ -0,0 +1,35 @@
+package exampletest
+
+import "testing"
+
+func TestContainsDiagnostic(t *testing.T) {
+	output := `
+Planning failed. Terraform encountered an error while generating this plan.
+
+╷
+│ Error: Resource precondition failed
+│
+│   on ../../modules/bootstrap/main.tf line 43, in resource "azurerm_storage_account" "this":
+│   43:       condition     = var.storage_acl == true ? (length(var.storage_allow_vnet_subnet_ids) > 0 || length(var.storage_allow_inbound_public_ips) > 0) : true
+│     ├────────────────
+│     │ var.storage_acl is true
+│
+│ If 'storage_acl' is set to true, at least on of
+│ 'storage_allow_vnet_subnet_ids' or 'storage_allow_inbound_public_ips' must
+│ be a non-empty list.
+╵
+`
+	cases := []struct {
+		message string
+		want    bool
+	}{
+		{"If 'storage_acl' is set to true, at least on of 'storage_allow_vnet_subnet_ids' or 'storage_allow_inbound_public_ips' must be a non-empty list.", true},
+		{"Resource precondition failed", true},
+		{"'storage_acl' must be set to true", false},
+	}
+	for _, c := range cases {
+		if got := containsDiagnostic(output, c.message); got != c.want {
+			t.Errorf("containsDiagnostic(%q) = %v, want %v", c.message, got, c.want)
+		}
+	}
+}
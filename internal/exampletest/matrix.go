@@ -0,0 +1,120 @@
+package exampletest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/azureerrors"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+// DefaultVariant is the name of the subtest planning an example with its own variable files only.
+const DefaultVariant = "default"
+
+// Variant is a named combination of inputs an example is planned with, on top of its variable files.
+// Variants cover combinations a single `example.tfvars` never exercises, like zones disabled or an existing Resource Group.
+type Variant struct {
+	// Name identifies the variant, it is used as a subtest name.
+	Name string
+	// VarFiles are variable files relative to TerraformDir, passed after the spec's ones so that their values win.
+	// Use them to override complex variables, i.e. `testdata/matrix/availability_sets.tfvars`.
+	VarFiles []string
+	// Vars override variables set by the variable files and the spec.
+	Vars map[string]interface{}
+	// ExistingResourceGroup creates a Resource Group before planning and runs the example
+	// with `create_resource_group = false`, so that the example sources that group.
+	ExistingResourceGroup bool
+	// PlanAssertions verify the planned topology. They replace the spec's ones, which usually do not hold for other inputs.
+	PlanAssertions []planassert.Assertion
+}
+
+// spec returns the example's spec with the variant's inputs applied.
+func (v Variant) spec(spec Spec) Spec {
+	varFiles := spec.VarFiles
+	if len(varFiles) == 0 {
+		varFiles = []string{DefaultVarFile}
+	}
+	spec.VarFiles = append(append([]string{}, varFiles...), v.VarFiles...)
+
+	vars := map[string]interface{}{}
+	for k, val := range spec.Vars {
+		vars[k] = val
+	}
+	for k, val := range v.Vars {
+		vars[k] = val
+	}
+	spec.Vars = vars
+	spec.PlanAssertions = v.PlanAssertions
+	return spec
+}
+
+// existingResourceGroupCode is a root module creating the Resource Group sourced by an example.
+// Variable files of the example are passed to it to read the location, values of other variables are ignored.
+const existingResourceGroupCode = `# Code generated by internal/exampletest. DO NOT EDIT.
+
+provider "azurerm" {
+  features {}
+}
+
+variable "name" {
+  type = string
+}
+
+variable "location" {
+  type = string
+}
+
+resource "azurerm_resource_group" "this" {
+  name     = var.name
+  location = var.location
+}
+`
+
+// existingResourceGroup creates the Resource Group the example would create itself and switches the example
+// to source it instead. The Resource Group is destroyed when the test finishes. With prefixed, the example adds
+// `name_prefix` to `resource_group_name` of a sourced Resource Group itself.
+func existingResourceGroup(t *testing.T, options *terraform.Options, names Names, prefixed bool) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(existingResourceGroupCode), 0644); err != nil {
+		t.Fatalf("cannot write root module of the existing Resource Group: %v", err)
+	}
+	var varFiles []string
+	for _, f := range options.VarFiles {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(options.TerraformDir, f)
+		}
+		varFiles = append(varFiles, f)
+	}
+
+	name := names.NamePrefix + names.ResourceGroupName
+	rgOptions := azureerrors.WithRetryableErrors(t, &terraform.Options{
+		TerraformDir:         dir,
+		VarFiles:             varFiles,
+		Vars:                 map[string]interface{}{"name": name},
+		Logger:               logger.Default,
+		Lock:                 true,
+		Upgrade:              true,
+		SetVarsAfterVarFiles: true,
+	})
+	// registered after t.TempDir, so it runs before the directory is removed
+	t.Cleanup(func() {
+		if _, err := terraform.DestroyE(t, rgOptions); err != nil {
+			t.Errorf("cannot destroy the existing Resource Group %s: %v\nerror classification: %s", name, err, azureerrors.Classify(err.Error()))
+		}
+	})
+	if _, err := terraform.InitAndApplyE(t, rgOptions); err != nil {
+		fatalClassified(t, fmt.Errorf("cannot create the existing Resource Group: %w", err))
+	}
+
+	// most examples source an existing Resource Group by its full name, without the prefix
+	options.Vars["create_resource_group"] = false
+	options.Vars["resource_group_name"] = name
+	if prefixed {
+		options.Vars["resource_group_name"] = names.ResourceGroupName
+	}
+}
@@ -0,0 +1,40 @@
+package exampletest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+func TestVariantSpec(t *testing.T) {
+	spec := Spec{
+		Vars:           map[string]interface{}{"enable_zones": true, "tags": map[string]string{"a": "b"}},
+		PlanAssertions: []planassert.Assertion{planassert.IsCreated("azurerm_resource_group.this[0]")},
+	}
+	variant := Variant{
+		Name:     "availability_sets",
+		VarFiles: []string{"testdata/matrix/availability_sets.tfvars"},
+		Vars:     map[string]interface{}{"enable_zones": false},
+	}
+
+	got := variant.spec(spec)
+
+	if want := []string{DefaultVarFile, "testdata/matrix/availability_sets.tfvars"}; !reflect.DeepEqual(got.VarFiles, want) {
+		t.Errorf("VarFiles = %v, want %v", got.VarFiles, want)
+	}
+	if got.Vars["enable_zones"] != false || got.Vars["tags"] == nil {
+		t.Errorf("variables are not merged: %v", got.Vars)
+	}
+	if len(got.PlanAssertions) != 0 {
+		t.Errorf("spec assertions are not replaced: %v", got.PlanAssertions)
+	}
+	if spec.Vars["enable_zones"] != true {
+		t.Error("the spec is modified")
+	}
+
+	spec.VarFiles = []string{"custom.tfvars"}
+	if got := variant.spec(spec).VarFiles; got[0] != "custom.tfvars" || len(got) != 2 {
+		t.Errorf("VarFiles = %v, the spec's variable files have to come first", got)
+	}
+}
@@ -0,0 +1,190 @@
+package exampletest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+// Names of the stages of Apply and Idempotence tests, in the order they are run.
+const (
+	// StageSetup creates a working copy of the example and Terraform options.
+	StageSetup = "setup"
+	// StageApply deploys the example.
+	StageApply = "apply"
+	// StageVerify reads the outputs and verifies that the expected ones are set.
+	StageVerify = "verify"
+	// StageIdempotence verifies there are no changes planned after deployment.
+	StageIdempotence = "idempotence"
+	// StageDestroy destroys the deployment and removes the working copy and the saved Terraform options.
+	StageDestroy = "destroy"
+)
+
+// stager runs the stages of a single test and keeps the state they share.
+type stager struct {
+	t    *testing.T
+	spec Spec
+	// dir is the example's directory, Terraform options and the path of the working copy are saved in its
+	// `.test-data` directory.
+	dir string
+	// persisted is set when a stage is skipped, the stages of the test can span several runs then, and so does
+	// the working copy.
+	persisted bool
+	options   *terraform.Options
+}
+
+func newStager(t *testing.T, spec Spec) *stager {
+	dir, err := filepath.Abs(orDefault(spec.TerraformDir, "."))
+	if err != nil {
+		t.Fatalf("cannot locate example: %v", err)
+	}
+	return &stager{t: t, spec: spec, dir: dir, persisted: test_structure.SkipStageEnvVarSet()}
+}
+
+// workingCopyData is the name the path of a persisted working copy is saved with.
+const workingCopyData = "WorkingCopy"
+
+// setup creates the working copy, and saves its path and Terraform options to the example's directory when stages
+// are persisted. A persisted working copy is not removed with the test, so that the next run finds its state.
+func (s *stager) setup() {
+	if !s.persisted {
+		s.options, _ = newOptions(s.t, s.spec, "")
+		return
+	}
+	if test_structure.IsTestDataPresent(s.t, optionsPath(s.dir)) {
+		s.t.Fatalf("%s contains a previous deployment, resume it with SKIP_%s=true or destroy it first", s.dir, StageSetup)
+	}
+	var root string
+	if !s.spec.InPlace {
+		var err error
+		if root, err = os.MkdirTemp("", "exampletest-"); err != nil {
+			s.t.Fatalf("cannot create working copy: %v", err)
+		}
+		test_structure.SaveString(s.t, s.dir, workingCopyData, root)
+	}
+	s.options, _ = newOptions(s.t, s.spec, root)
+	test_structure.SaveTerraformOptions(s.t, s.dir, s.options)
+}
+
+// optionsPath is the file test_structure saves Terraform options to.
+func optionsPath(dir string) string {
+	return test_structure.FormatTestDataPath(dir, "TerraformOptions.json")
+}
+
+// terraformOptions returns options created in the setup stage, loading them from the example's directory when setup
+// was skipped.
+func (s *stager) terraformOptions() *terraform.Options {
+	if s.options != nil {
+		return s.options
+	}
+	if !test_structure.IsTestDataPresent(s.t, optionsPath(s.dir)) {
+		s.t.Fatalf("the %s stage was skipped and %s has no options of a previous run, run it with SKIP_%s=true to keep the deployment",
+			StageSetup, s.dir, StageDestroy)
+	}
+	s.options = test_structure.LoadTerraformOptions(s.t, s.dir)
+	s.options.Logger = logger.Default
+	return s.options
+}
+
+func (s *stager) apply() {
+	if _, err := terraform.InitAndApplyE(s.t, s.terraformOptions()); err != nil {
+		fatalClassified(s.t, fmt.Errorf("cannot deploy infrastructure: %w", err))
+	}
+}
+
+func (s *stager) verify() {
+	outputs, err := terraform.OutputAllE(s.t, s.terraformOptions())
+	if err != nil {
+		fatalClassified(s.t, fmt.Errorf("cannot read outputs: %w", err))
+	}
+	for _, name := range s.spec.ExpectedOutputs {
+		if isEmptyOutput(outputs[name]) {
+			s.t.Errorf("output %q is not set after deployment", name)
+		}
+	}
+}
+
+func isEmptyOutput(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func (s *stager) idempotence() {
+	exitCode, err := terraform.PlanExitCodeE(s.t, s.terraformOptions())
+	switch {
+	case err != nil:
+		fatalClassified(s.t, fmt.Errorf("cannot plan infrastructure after deployment: %w", err))
+	case exitCode != terraform.DefaultSuccessExitCode:
+		s.t.Errorf("changes are planned right after deployment, the example is not idempotent (plan exit code %d)", exitCode)
+	}
+}
+
+func (s *stager) destroy() {
+	// the setup stage failed or was skipped, there can only be a deployment of a previous run
+	if s.options == nil && !(s.persisted && test_structure.IsTestDataPresent(s.t, optionsPath(s.dir))) {
+		return
+	}
+	if _, err := terraform.DestroyE(s.t, s.terraformOptions()); err != nil {
+		// keep the saved options, the destroy stage can be run again
+		fatalClassified(s.t, fmt.Errorf("cannot destroy infrastructure: %w", err))
+	}
+	if !s.persisted {
+		return
+	}
+	if path := test_structure.FormatTestDataPath(s.dir, workingCopyData+".json"); test_structure.IsTestDataPresent(s.t, path) {
+		if err := os.RemoveAll(test_structure.LoadString(s.t, s.dir, workingCopyData)); err != nil {
+			s.t.Errorf("cannot remove working copy: %v", err)
+		}
+	}
+	test_structure.CleanupTestDataFolder(s.t, s.dir)
+}
+
+// runStages runs the given stages in order with test_structure.RunTestStage. The destroy stage, when given, is always
+// run last, also when any of the earlier stages fails.
+//
+// A stage is skipped by setting `SKIP_<stage>`. When any stage is skipped, the working copy is kept until the destroy
+// stage and its path and the options are saved in the example's directory, so that a later run can skip the stages
+// already done. For example,
+// to keep a deployment for debugging and later verify and destroy it:
+//
+//	SKIP_destroy=true make examples/dedicated_vmseries ACTION=Idempotence
+//	SKIP_setup=true SKIP_apply=true make examples/dedicated_vmseries ACTION=Idempotence
+func runStages(t *testing.T, spec Spec, stages ...string) {
+	s := newStager(t, spec)
+	steps := map[string]func(){
+		StageSetup:       s.setup,
+		StageApply:       s.apply,
+		StageVerify:      s.verify,
+		StageIdempotence: s.idempotence,
+	}
+	for _, stage := range stages {
+		if stage == StageDestroy {
+			// a failed stage stops the test, destroy has to be deferred before any stage is run
+			defer test_structure.RunTestStage(t, StageDestroy, s.destroy)
+		}
+	}
+	for _, stage := range stages {
+		if stage == StageDestroy {
+			continue
+		}
+		fn, ok := steps[stage]
+		if !ok {
+			panic(fmt.Sprintf("unknown stage %q", stage))
+		}
+		test_structure.RunTestStage(t, stage, fn)
+	}
+}
@@ -0,0 +1,88 @@
+package exampletest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+func testExample(t *testing.T) string {
+	example := filepath.Join(t.TempDir(), "examples", "ex")
+	if err := os.MkdirAll(example, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(example, "main.tf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return example
+}
+
+func TestStagesNotPersisted(t *testing.T) {
+	stubNames(t)
+	example := testExample(t)
+
+	s := newStager(t, Spec{TerraformDir: example})
+	s.setup()
+	if s.options.TerraformDir == example {
+		t.Error("Terraform should run in a working copy when no stage is skipped")
+	}
+	if _, err := os.Stat(optionsPath(example)); err == nil {
+		t.Error("options should not be saved when no stage is skipped")
+	}
+}
+
+func TestStagesPersisted(t *testing.T) {
+	stubNames(t)
+	example := testExample(t)
+	spec := Spec{TerraformDir: example}
+
+	// a run keeping the deployment works in a working copy kept after the test and saves the options
+	t.Setenv("SKIP_"+StageDestroy, "true")
+	setup := newStager(t, spec)
+	setup.setup()
+	copied := setup.options.TerraformDir
+	if copied == example {
+		t.Errorf("TerraformDir = %q, want a working copy", copied)
+	}
+	if _, err := os.Stat(optionsPath(example)); err != nil {
+		t.Fatalf("options are not saved: %v", err)
+	}
+	root := test_structure.LoadString(t, example, workingCopyData)
+	t.Cleanup(func() { os.RemoveAll(root) })
+	if rel, err := filepath.Rel(root, copied); err != nil || strings.HasPrefix(rel, "..") {
+		t.Errorf("working copy %q is not in the saved directory %q", copied, root)
+	}
+
+	// a later run skipping setup continues with the same directory and variables
+	t.Setenv("SKIP_"+StageSetup, "true")
+	resumed := newStager(t, spec)
+	options := resumed.terraformOptions()
+	if options.TerraformDir != copied {
+		t.Errorf("TerraformDir = %q, want %q", options.TerraformDir, copied)
+	}
+	if _, err := os.Stat(filepath.Join(copied, "main.tf")); err != nil {
+		t.Errorf("working copy is removed: %v", err)
+	}
+	if options.Vars["name_prefix"] != "abcd-" {
+		t.Errorf("name_prefix = %v, want abcd-", options.Vars["name_prefix"])
+	}
+	if options.Logger == nil {
+		t.Error("Logger should be set on loaded options")
+	}
+}
+
+func TestIsEmptyOutput(t *testing.T) {
+	for _, v := range []interface{}{nil, "", []interface{}{}, map[string]interface{}{}} {
+		if !isEmptyOutput(v) {
+			t.Errorf("isEmptyOutput(%#v) = false", v)
+		}
+	}
+	for _, v := range []interface{}{"panadmin", false, []interface{}{"a"}, map[string]interface{}{"a": 1}} {
+		if isEmptyOutput(v) {
+			t.Errorf("isEmptyOutput(%#v) = true", v)
+		}
+	}
+}
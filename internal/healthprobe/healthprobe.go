@@ -0,0 +1,183 @@
+// Package healthprobe verifies that VM-Series firewalls answer health probes of the load balancers they are
+// a backend of.
+//
+// Azure probes from 168.63.129.16. The firewall answers on the interface in the backend pool only when an interface
+// management profile of the interface permits that address and enables the service listening on the probed port,
+// i.e. `http` for port 80, and when the reply is routed back through the same interface, usually by a static route
+// named `lb_health_check`. Otherwise every firewall is marked unhealthy and the load balancer drops all traffic.
+package healthprobe
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/panosconfig"
+)
+
+// ProbeSource is the address health probes of Azure come from.
+const ProbeSource = "168.63.129.16"
+
+// Probe is a health probe of a load balancer.
+type Probe struct {
+	// LoadBalancer names the load balancer in problems.
+	LoadBalancer string
+	Port         int
+	// Protocol is Tcp, Http or Https, like the `protocol` of `azurerm_lb_probe`, Tcp when empty.
+	Protocol string
+}
+
+// services are services of an interface management profile by the ports they listen on.
+var services = map[int]string{
+	22:  "ssh",
+	80:  "http",
+	443: "https",
+}
+
+// Check returns reasons why the firewall configuration does not answer the probe on the interface.
+func Check(config *panosconfig.Config, iface string, probe Probe) []string {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s probe %s/%d: ", probe.LoadBalancer, protocol(probe), probe.Port)+fmt.Sprintf(format, args...))
+	}
+
+	if len(config.Devices) == 0 {
+		report("the configuration has no devices")
+		return problems
+	}
+	network := config.Devices[0].Network
+
+	service, ok := services[probe.Port]
+	switch {
+	case !ok:
+		report("no service of an interface management profile listens on port %d", probe.Port)
+	case protocol(probe) == "Http" && service != "http", protocol(probe) == "Https" && service != "https":
+		report("the firewall serves %s on port %d", service, probe.Port)
+	}
+
+	profileName, found := managementProfile(network, iface)
+	switch {
+	case !found:
+		report("interface %s is not a layer3 interface of the firewall", iface)
+		return problems
+	case profileName == "":
+		report("interface %s has no interface management profile", iface)
+	default:
+		profile, ok := findProfile(network, profileName)
+		if !ok {
+			report("interface management profile %q of interface %s is not defined", profileName, iface)
+			break
+		}
+		if service != "" && !enabled(profile, service) {
+			report("interface management profile %q does not enable %s", profileName, service)
+		}
+		if !permits(profile) {
+			report("interface management profile %q does not permit %s", profileName, ProbeSource)
+		}
+	}
+
+	router, route := probeRoute(network, iface)
+	switch {
+	case router == "":
+		report("interface %s is not in a virtual router", iface)
+	case route == nil:
+		report("virtual router %q has no route to %s", router, ProbeSource)
+	case route.Interface != iface:
+		via := route.Interface
+		if route.NextVR != "" {
+			via = "virtual router " + route.NextVR
+		}
+		report("static route %q of virtual router %q sends replies to %s through %s, not %s", route.Name, router, ProbeSource, via, iface)
+	}
+	return problems
+}
+
+func protocol(p Probe) string {
+	if p.Protocol == "" {
+		return "Tcp"
+	}
+	return p.Protocol
+}
+
+// managementProfile returns the interface management profile of a layer3 interface or a subinterface.
+func managementProfile(network panosconfig.Network, name string) (string, bool) {
+	for _, e := range network.Ethernet {
+		if e.Layer3 == nil {
+			continue
+		}
+		if e.Name == name {
+			return e.Layer3.ManagementProfile, true
+		}
+		for _, u := range e.Layer3.Units {
+			if u.Name == name {
+				return u.ManagementProfile, true
+			}
+		}
+	}
+	return "", false
+}
+
+func findProfile(network panosconfig.Network, name string) (panosconfig.ManagementProfile, bool) {
+	for _, p := range network.ManagementProfiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return panosconfig.ManagementProfile{}, false
+}
+
+func enabled(p panosconfig.ManagementProfile, service string) bool {
+	switch service {
+	case "http":
+		return p.HTTP == "yes"
+	case "https":
+		return p.HTTPS == "yes"
+	case "ssh":
+		return p.SSH == "yes"
+	}
+	return false
+}
+
+// permits tells whether the profile accepts the probe source, a profile without permitted addresses accepts all.
+func permits(p panosconfig.ManagementProfile) bool {
+	if len(p.PermittedIP) == 0 {
+		return true
+	}
+	source := net.ParseIP(ProbeSource)
+	for _, e := range p.PermittedIP {
+		if e.Name == ProbeSource {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(e.Name); err == nil && cidr.Contains(source) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeRoute returns the virtual router of the interface and its most specific static route to the probe source.
+func probeRoute(network panosconfig.Network, iface string) (string, *panosconfig.StaticRoute) {
+	source := net.ParseIP(ProbeSource)
+	for _, vr := range network.VirtualRouters {
+		member := false
+		for _, name := range vr.Interfaces {
+			member = member || name == iface
+		}
+		if !member {
+			continue
+		}
+		var best *panosconfig.StaticRoute
+		bestOnes := -1
+		for i, r := range vr.StaticRoutes {
+			_, cidr, err := net.ParseCIDR(strings.TrimSpace(r.Destination))
+			if err != nil || !cidr.Contains(source) {
+				continue
+			}
+			if ones, _ := cidr.Mask.Size(); ones > bestOnes {
+				best, bestOnes = &vr.StaticRoutes[i], ones
+			}
+		}
+		return vr.Name, best
+	}
+	return "", nil
+}
@@ -0,0 +1,320 @@
+package healthprobe
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/panosconfig"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/tftemplate"
+)
+
+// config is a firewall answering probes on ethernet1/1 only, the profile and the route are replaced by cases.
+const config = `<config>
+  <devices>
+    <entry name="localhost.localdomain">
+      <network>
+        <interface>
+          <ethernet>
+            <entry name="ethernet1/1">
+              <layer3>
+                <interface-management-profile>lb_health_check</interface-management-profile>
+              </layer3>
+            </entry>
+            <entry name="ethernet1/2">
+              <layer3/>
+            </entry>
+          </ethernet>
+        </interface>
+        <profiles>
+          <interface-management-profile>
+            PROFILE
+          </interface-management-profile>
+        </profiles>
+        <virtual-router>
+          <entry name="default">
+            <interface>
+              <member>ethernet1/1</member>
+              <member>ethernet1/2</member>
+            </interface>
+            <routing-table>
+              <ip>
+                <static-route>
+                  ROUTES
+                </static-route>
+              </ip>
+            </routing-table>
+          </entry>
+        </virtual-router>
+      </network>
+    </entry>
+  </devices>
+</config>`
+
+const (
+	httpProfile = `<entry name="lb_health_check"><http>yes</http><permitted-ip><entry name="168.63.129.16"/></permitted-ip></entry>`
+	lbRoute     = `<entry name="lb_health_check"><interface>ethernet1/1</interface><destination>168.63.129.16/32</destination></entry>`
+	defaultVia2 = `<entry name="default"><interface>ethernet1/2</interface><destination>0.0.0.0/0</destination></entry>`
+)
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name, profile, routes, iface string
+		probe                        Probe
+		want                         []string
+	}{
+		{
+			name:    "http",
+			profile: httpProfile,
+			routes:  lbRoute + defaultVia2,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+		},
+		{
+			name:    "http probe",
+			profile: httpProfile,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80, Protocol: "Http"},
+		},
+		{
+			name:    "all addresses permitted",
+			profile: `<entry name="lb_health_check"><https>yes</https></entry>`,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 443, Protocol: "Https"},
+		},
+		{
+			name:    "https not enabled",
+			profile: httpProfile,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 443},
+			want:    []string{`lb probe Tcp/443: interface management profile "lb_health_check" does not enable https`},
+		},
+		{
+			name:    "https on the http port",
+			profile: httpProfile,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80, Protocol: "Https"},
+			want:    []string{"lb probe Https/80: the firewall serves http on port 80"},
+		},
+		{
+			name:    "port without a service",
+			profile: httpProfile,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 8080},
+			want:    []string{"lb probe Tcp/8080: no service of an interface management profile listens on port 8080"},
+		},
+		{
+			name:    "probe source not permitted",
+			profile: `<entry name="lb_health_check"><http>yes</http><permitted-ip><entry name="10.0.0.0/8"/></permitted-ip></entry>`,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+			want:    []string{`lb probe Tcp/80: interface management profile "lb_health_check" does not permit 168.63.129.16`},
+		},
+		{
+			name:    "interface without a profile",
+			profile: httpProfile,
+			routes:  defaultVia2,
+			iface:   "ethernet1/2",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+			want:    []string{"lb probe Tcp/80: interface ethernet1/2 has no interface management profile"},
+		},
+		{
+			name:    "missing profile",
+			profile: `<entry name="other"><http>yes</http></entry>`,
+			routes:  lbRoute,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+			want:    []string{`lb probe Tcp/80: interface management profile "lb_health_check" of interface ethernet1/1 is not defined`},
+		},
+		{
+			name:    "replies through another interface",
+			profile: httpProfile,
+			routes:  defaultVia2,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+			want:    []string{`lb probe Tcp/80: static route "default" of virtual router "default" sends replies to 168.63.129.16 through ethernet1/2, not ethernet1/1`},
+		},
+		{
+			name:    "no route",
+			profile: httpProfile,
+			iface:   "ethernet1/1",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+			want:    []string{`lb probe Tcp/80: virtual router "default" has no route to 168.63.129.16`},
+		},
+		{
+			name:    "unknown interface",
+			profile: httpProfile,
+			iface:   "ethernet1/3",
+			probe:   Probe{LoadBalancer: "lb", Port: 80},
+			want:    []string{"lb probe Tcp/80: interface ethernet1/3 is not a layer3 interface of the firewall"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			xml := strings.NewReplacer("PROFILE", tc.profile, "ROUTES", tc.routes).Replace(config)
+			c, err := panosconfig.Parse([]byte(xml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Check(c, tc.iface, tc.probe)
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	vars, err := ReadVarFiles("../../examples/gwlb_with_vmseries/example.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Targets(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe := Probe{LoadBalancer: `gateway_load_balancers["gwlb"]`, Port: 80}
+	want := []Target{
+		{Firewall: "vms01", Name: "vmseries01", Template: "templates/bootstrap-gwlb.tftpl", Interface: "ethernet1/1", Probe: probe},
+		{Firewall: "vms02", Name: "vmseries02", Template: "templates/bootstrap-gwlb.tftpl", Interface: "ethernet1/1", Probe: probe},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got targets %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got target %+v, want %+v", got[i], want[i])
+		}
+	}
+
+	vars["load_balancers"] = cty.ObjectVal(map[string]cty.Value{
+		"public": cty.ObjectVal(map[string]cty.Value{"probe_port": cty.StringVal("443")}),
+	})
+	vars["vmseries"] = cty.ObjectVal(map[string]cty.Value{
+		"fw": cty.ObjectVal(map[string]cty.Value{
+			"bootstrap_storage": cty.ObjectVal(map[string]cty.Value{"template_bootstrap_xml": cty.StringVal("t.tmpl")}),
+			"interfaces": cty.TupleVal([]cty.Value{
+				cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("mgmt")}),
+				cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("private")}),
+				cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("public"), "load_balancer_key": cty.StringVal("public")}),
+			}),
+		}),
+	})
+	got, err = Targets(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Interface != "ethernet1/2" || got[0].Probe.Port != 443 {
+		t.Errorf("got targets %+v", got)
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	for _, tc := range []struct {
+		example, firewall string
+		want              map[string]string
+	}{
+		{
+			example:  "dedicated_vmseries",
+			firewall: "fw-obew-1",
+			want: map[string]string{
+				"private_azure_router_ip": `"10.0.0.17"`,
+				"data_gateway_ip":         `"10.0.0.17"`,
+				"public_azure_router_ip":  `"10.0.0.33"`,
+				"private_network_cidr":    `"10.100.0.0/16"`,
+				"mgmt_profile_appgw_cidr": `[]`,
+				"ai_instr_key":            `null`,
+				"ai_update_interval":      `5`,
+			},
+		},
+		{
+			example:  "gwlb_with_vmseries",
+			firewall: "vms01",
+			want: map[string]string{
+				"data_gateway_ip":         `"10.0.1.17"`,
+				"private_network_cidr":    `"10.0.1.0/24"`,
+				"mgmt_profile_appgw_cidr": `[]`,
+				"ai_instr_key":            `null`,
+				"ai_update_interval":      `5`,
+			},
+		},
+	} {
+		t.Run(tc.example, func(t *testing.T) {
+			vars, err := ReadVarFiles("../../examples/" + tc.example + "/example.tfvars")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for name, val := range TemplateVars(vars, tc.firewall) {
+				b, err := ctyjson.Marshal(val, val.Type())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[name] = string(b)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestExamples verifies that firewalls of the examples answer the probes of their load balancers.
+func TestExamples(t *testing.T) {
+	files, err := filepath.Glob("../../examples/*/example.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked := 0
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		vars, err := ReadVarFiles(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := vars["vmseries"]; !ok {
+			continue
+		}
+		targets, err := Targets(vars)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		for _, target := range targets {
+			names, err := tftemplate.Variables(dir, target.Template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			given := TemplateVars(vars, target.Firewall)
+			for _, name := range names {
+				if _, ok := given[name]; !ok {
+					t.Errorf("%s: vmseries[%q]: %s cannot be derived from the variables", file, target.Firewall, name)
+				}
+			}
+			out, err := tftemplate.Render(dir, target.Template, given)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := panosconfig.Parse([]byte(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range Check(config, target.Interface, target.Probe) {
+				t.Errorf("%s: vmseries[%q] %s: %s", file, target.Firewall, target.Interface, p)
+			}
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Fatal("no probed interfaces found in the examples")
+	}
+}
@@ -0,0 +1,170 @@
+package healthprobe
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DefaultProbePort is the default `probe_port` of the loadbalancer module.
+const DefaultProbePort = 80
+
+// Target is an interface of a firewall probed by a load balancer, as configured by the variables of an example.
+type Target struct {
+	// Firewall is the key of the firewall in the `vmseries` variable.
+	Firewall string
+	// Name is the `name` of the firewall.
+	Name string
+	// Template is the `bootstrap_storage.template_bootstrap_xml` of the firewall, relative to the example.
+	Template string
+	// Interface is the firewall's interface, the first interface of a VM is the management one,
+	// the next ones are `ethernet1/1`, `ethernet1/2` and so on.
+	Interface string
+	Probe     Probe
+}
+
+// Targets returns interfaces of firewalls bootstrapped from a template which are in a backend pool of a load
+// balancer, sorted by firewalls and interfaces. An interface with a `load_balancer_key` is probed by an entry of
+// `load_balancers`, with its `probe_port` or the loadbalancer module's default. An interface with a `gwlb_key` and
+// `enable_backend_pool` is probed by the `health_probe` of an entry of `gateway_load_balancers`.
+func Targets(vars map[string]cty.Value) ([]Target, error) {
+	vmseries, ok := vars["vmseries"]
+	if !ok || !isMap(vmseries) {
+		return nil, fmt.Errorf("vmseries is not set")
+	}
+	var targets []Target
+	for key, vm := range vmseries.AsValueMap() {
+		template, ok := stringAttr(attr(vm, "bootstrap_storage"), "template_bootstrap_xml")
+		if !ok {
+			continue
+		}
+		name, _ := stringAttr(vm, "name")
+		interfaces := attr(vm, "interfaces")
+		if interfaces.IsNull() || !interfaces.CanIterateElements() {
+			continue
+		}
+		for i, iface := range interfaces.AsValueSlice() {
+			if i == 0 {
+				continue
+			}
+			probe, ok, err := interfaceProbe(vars, iface)
+			if err != nil {
+				return nil, fmt.Errorf("vmseries[%q].interfaces[%d]: %w", key, i, err)
+			}
+			if ok {
+				targets = append(targets, Target{
+					Firewall:  key,
+					Name:      name,
+					Template:  template,
+					Interface: fmt.Sprintf("ethernet1/%d", i),
+					Probe:     probe,
+				})
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Firewall != targets[j].Firewall {
+			return targets[i].Firewall < targets[j].Firewall
+		}
+		return targets[i].Interface < targets[j].Interface
+	})
+	return targets, nil
+}
+
+// interfaceProbe returns the probe of the load balancer the interface is a backend of.
+func interfaceProbe(vars map[string]cty.Value, iface cty.Value) (Probe, bool, error) {
+	if key, ok := stringAttr(iface, "load_balancer_key"); ok {
+		lb := attr(vars["load_balancers"], key)
+		if lb.IsNull() {
+			return Probe{}, false, fmt.Errorf("load_balancers[%q] is not set", key)
+		}
+		port := DefaultProbePort
+		if p, ok := numberAttr(lb, "probe_port"); ok {
+			port = p
+		}
+		return Probe{LoadBalancer: fmt.Sprintf("load_balancers[%q]", key), Port: port}, true, nil
+	}
+
+	enabled := attr(iface, "enable_backend_pool")
+	if key, ok := stringAttr(iface, "gwlb_key"); ok && enabled.Type() == cty.Bool && enabled.True() {
+		gwlb := attr(vars["gateway_load_balancers"], key)
+		if gwlb.IsNull() {
+			return Probe{}, false, fmt.Errorf("gateway_load_balancers[%q] is not set", key)
+		}
+		hp := attr(gwlb, "health_probe")
+		port, ok := numberAttr(hp, "port")
+		if !ok {
+			return Probe{}, false, fmt.Errorf("gateway_load_balancers[%q].health_probe.port is not set", key)
+		}
+		protocol, _ := stringAttr(hp, "protocol")
+		return Probe{LoadBalancer: fmt.Sprintf("gateway_load_balancers[%q]", key), Port: port, Protocol: protocol}, true, nil
+	}
+	return Probe{}, false, nil
+}
+
+// ReadVarFiles returns values of variables set in variable files, a later file overrides an earlier one.
+func ReadVarFiles(files ...string) (map[string]cty.Value, error) {
+	vars := map[string]cty.Value{}
+	parser := hclparse.NewParser()
+	for _, path := range files {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for name, a := range file.Body.(*hclsyntax.Body).Attributes {
+			val, diags := a.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			vars[name] = val
+		}
+	}
+	return vars, nil
+}
+
+func isMap(val cty.Value) bool {
+	return val != cty.NilVal && !val.IsNull() && val.IsWhollyKnown() && (val.Type().IsObjectType() || val.Type().IsMapType())
+}
+
+// attr returns an attribute of an object or an element of a map, or null.
+func attr(val cty.Value, name string) cty.Value {
+	switch {
+	case !isMap(val):
+		return cty.NullVal(cty.DynamicPseudoType)
+	case val.Type().IsObjectType() && val.Type().HasAttribute(name):
+		return val.GetAttr(name)
+	case val.Type().IsMapType() && val.HasIndex(cty.StringVal(name)).True():
+		return val.Index(cty.StringVal(name))
+	}
+	return cty.NullVal(cty.DynamicPseudoType)
+}
+
+func stringAttr(val cty.Value, name string) (string, bool) {
+	v := attr(val, name)
+	if v.IsNull() || !v.IsKnown() || v.Type() != cty.String {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// numberAttr returns an attribute set as a number or as a string holding a number, like `probe_port`.
+func numberAttr(val cty.Value, name string) (int, bool) {
+	v := attr(val, name)
+	if v.IsNull() || !v.IsKnown() {
+		return 0, false
+	}
+	switch v.Type() {
+	case cty.Number:
+		n, acc := v.AsBigFloat().Int64()
+		return int(n), acc == big.Exact
+	case cty.String:
+		var n int
+		_, err := fmt.Sscanf(v.AsString(), "%d", &n)
+		return n, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,118 @@
+package healthprobe
+
+import (
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduleeval"
+)
+
+// DefaultAIUpdateInterval is the `ai_update_interval` the examples give to the templates when it is not set.
+const DefaultAIUpdateInterval = 5
+
+// TemplateVars returns the variables the examples give to the bootstrap template of a firewall in their `local_file`
+// resources, derived from the variable files instead of the deployed modules:
+//
+//   - `private_azure_router_ip`, `public_azure_router_ip` and `data_gateway_ip` are the first hosts of the subnets
+//     of the firewall's VNET, as declared in `vnets`,
+//   - `private_network_cidr` is the `intranet_cidr` of the bootstrap storage or the first address space of the VNET,
+//   - `mgmt_profile_appgw_cidr` are the address prefixes of the subnets of `appgws`,
+//   - `ai_update_interval` defaults to DefaultAIUpdateInterval,
+//   - `ai_instr_key` is null, as if Application Insights were not deployed.
+//
+// A value which cannot be derived, like a router IP of a subnet sourced from an existing VNET, is not set.
+func TemplateVars(vars map[string]cty.Value, firewall string) map[string]cty.Value {
+	vm := attr(vars["vmseries"], firewall)
+	common := vars["vmseries_common"]
+	storage := attr(vm, "bootstrap_storage")
+	name, _ := stringAttr(storage, "name")
+	shared := attr(vars["bootstrap_storage"], name)
+	// a setting of the firewall's bootstrap storage, or of the storage it refers to
+	setting := func(name string) cty.Value {
+		if v := attr(storage, name); !v.IsNull() {
+			return v
+		}
+		return attr(shared, name)
+	}
+
+	vnetKey, ok := stringAttr(vm, "vnet_key")
+	if !ok {
+		vnetKey, _ = stringAttr(common, "vnet_key")
+	}
+	vnet := attr(vars["vnets"], vnetKey)
+	routerIP := func(subnetKey cty.Value) (cty.Value, bool) {
+		if subnetKey.IsNull() || !subnetKey.IsKnown() || subnetKey.Type() != cty.String {
+			return cty.NilVal, false
+		}
+		cidr := first(attr(attr(attr(vnet, "subnets"), subnetKey.AsString()), "address_prefixes"))
+		if cidr.IsNull() {
+			return cty.NilVal, false
+		}
+		ip, err := moduleeval.Functions(".")["cidrhost"].Call([]cty.Value{cidr, cty.NumberIntVal(1)})
+		return ip, err == nil
+	}
+
+	out := map[string]cty.Value{"ai_instr_key": cty.NullVal(cty.String)}
+	if ip, ok := routerIP(setting("private_snet_key")); ok {
+		out["private_azure_router_ip"] = ip
+	}
+	if ip, ok := routerIP(setting("public_snet_key")); ok {
+		out["public_azure_router_ip"] = ip
+	}
+	interfaces := attr(vm, "interfaces")
+	if !interfaces.IsNull() && interfaces.CanIterateElements() && interfaces.LengthInt() > 1 {
+		if ip, ok := routerIP(attr(interfaces.AsValueSlice()[1], "subnet_key")); ok {
+			out["data_gateway_ip"] = ip
+		}
+	}
+
+	if cidr := setting("intranet_cidr"); !cidr.IsNull() {
+		out["private_network_cidr"] = cidr
+	} else if cidr := first(attr(vnet, "address_space")); !cidr.IsNull() {
+		out["private_network_cidr"] = cidr
+	}
+
+	out["ai_update_interval"] = cty.NumberIntVal(DefaultAIUpdateInterval)
+	for _, v := range []cty.Value{setting("ai_update_interval"), attr(vm, "ai_update_interval"), attr(common, "ai_update_interval")} {
+		if !v.IsNull() {
+			out["ai_update_interval"] = v
+			break
+		}
+	}
+
+	var appgwCIDRs []cty.Value
+	complete := true
+	if appgws := vars["appgws"]; isMap(appgws) {
+		// in the order of keys, like a `for` expression over a map
+		byKey := appgws.AsValueMap()
+		keys := make([]string, 0, len(byKey))
+		for k := range byKey {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			appgw := byKey[k]
+			vnetKey, _ := stringAttr(appgw, "vnet_key")
+			subnetKey, _ := stringAttr(appgw, "subnet_key")
+			prefixes := attr(attr(attr(attr(vars["vnets"], vnetKey), "subnets"), subnetKey), "address_prefixes")
+			if prefixes.IsNull() || !prefixes.IsWhollyKnown() || !prefixes.CanIterateElements() {
+				complete = false
+				continue
+			}
+			appgwCIDRs = append(appgwCIDRs, prefixes.AsValueSlice()...)
+		}
+	}
+	if complete {
+		out["mgmt_profile_appgw_cidr"] = cty.TupleVal(appgwCIDRs)
+	}
+	return out
+}
+
+// first returns the first element of a list, or null.
+func first(val cty.Value) cty.Value {
+	if val == cty.NilVal || val.IsNull() || !val.IsWhollyKnown() || !val.CanIterateElements() || val.LengthInt() == 0 {
+		return cty.NullVal(cty.String)
+	}
+	return val.AsValueSlice()[0]
+}
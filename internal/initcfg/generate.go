@@ -0,0 +1,71 @@
+package initcfg
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Remote is the path of `init-cfg.txt` in a bootstrap package.
+const Remote = "config/init-cfg.txt"
+
+// FileName returns the path of the generated `init-cfg.txt` of a firewall, relative to an example, next to the
+// `bootstrap.xml` files rendered by the examples.
+func FileName(key string) string {
+	return fmt.Sprintf("files/%s-init-cfg.txt", key)
+}
+
+// Firewall is a generated `init-cfg.txt` of an entry of the `vmseries` variable of an example.
+type Firewall struct {
+	Key    string
+	Config Config
+	// StaticFiles is the `bootstrap_storage.static_files` map of the firewall, the bootstrap module's `files`,
+	// with the generated file uploaded as `init-cfg.txt`.
+	StaticFiles map[string]string
+}
+
+// Generate returns a configuration for every firewall of the `vmseries` variable, sorted by keys. It is the base
+// configuration with `hostname` set to the name of the firewall. Other files of `static_files` are kept.
+func Generate(base Config, vmseries cty.Value) ([]Firewall, error) {
+	if vmseries.IsNull() || !vmseries.IsWhollyKnown() || !(vmseries.Type().IsObjectType() || vmseries.Type().IsMapType()) {
+		return nil, fmt.Errorf("vmseries has to be a map of firewalls")
+	}
+	var firewalls []Firewall
+	for key, vm := range vmseries.AsValueMap() {
+		name, err := stringAttr(vm, "name")
+		if err != nil {
+			return nil, fmt.Errorf("vmseries[%q]: %w", key, err)
+		}
+		files := map[string]string{}
+		if storage, ok := attr(vm, "bootstrap_storage"); ok {
+			if static, ok := attr(storage, "static_files"); ok && (static.Type().IsObjectType() || static.Type().IsMapType()) {
+				for local, remote := range static.AsValueMap() {
+					if remote.Type() == cty.String && remote.AsString() != Remote {
+						files[local] = remote.AsString()
+					}
+				}
+			}
+		}
+		files[FileName(key)] = Remote
+		firewalls = append(firewalls, Firewall{Key: key, Config: base.Set("hostname", name), StaticFiles: files})
+	}
+	sort.Slice(firewalls, func(i, j int) bool { return firewalls[i].Key < firewalls[j].Key })
+	return firewalls, nil
+}
+
+func attr(val cty.Value, name string) (cty.Value, bool) {
+	if val.IsNull() || !val.Type().IsObjectType() || !val.Type().HasAttribute(name) {
+		return cty.NilVal, false
+	}
+	v := val.GetAttr(name)
+	return v, !v.IsNull()
+}
+
+func stringAttr(val cty.Value, name string) (string, error) {
+	v, ok := attr(val, name)
+	if !ok || v.Type() != cty.String {
+		return "", fmt.Errorf("%s is not a string", name)
+	}
+	return v.AsString(), nil
+}
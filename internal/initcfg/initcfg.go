@@ -0,0 +1,83 @@
+// Package initcfg reads, verifies and writes `init-cfg.txt`, the basic configuration of a VM-Series firewall
+// read from its bootstrap package on the first boot.
+//
+// The file is a list of `key=value` lines, see
+// https://docs.paloaltonetworks.com/vm-series/10-2/vm-series-deployment/bootstrap-the-vm-series-firewall/create-the-init-cfgtxt-file/init-cfgtxt-file-components.
+// The firewall ignores unknown keys and values it cannot parse, so a typo leaves a firewall without its management
+// address or not registered to Panorama, which is noticed only after it boots. Validate reports such mistakes.
+package initcfg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Entry is a single `key=value` line.
+type Entry struct {
+	Key   string
+	Value string
+	// Line is the line number in the parsed file, 0 for entries added with Set.
+	Line int
+}
+
+// Config is the content of `init-cfg.txt`, in the order of lines.
+type Config []Entry
+
+// Parse reads `init-cfg.txt`. Empty lines are skipped, a line without `=` is an error.
+func Parse(data []byte) (Config, error) {
+	var c Config
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q is not a key=value pair", n, line)
+		}
+		c = append(c, Entry{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value), Line: n})
+	}
+	return c, scanner.Err()
+}
+
+// Get returns the value of the first entry with the key.
+func (c Config) Get(key string) (string, bool) {
+	for _, e := range c {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set returns a copy of the configuration with the value of the key replaced, or appended when the key is not set.
+func (c Config) Set(key, value string) Config {
+	out := make(Config, 0, len(c)+1)
+	found := false
+	for _, e := range c {
+		if e.Key == key {
+			if found {
+				continue
+			}
+			found = true
+			e.Value = value
+		}
+		out = append(out, e)
+	}
+	if !found {
+		out = append(out, Entry{Key: key, Value: value})
+	}
+	return out
+}
+
+// Bytes returns the file content, a line per entry.
+func (c Config) Bytes() []byte {
+	var b bytes.Buffer
+	for _, e := range c {
+		fmt.Fprintf(&b, "%s=%s\n", e.Key, e.Value)
+	}
+	return b.Bytes()
+}
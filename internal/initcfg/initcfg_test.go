@@ -0,0 +1,193 @@
+package initcfg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte("type=dhcp-client\r\n\nhostname = fw-1\nplugin-op-commands=azure-gwlb-inspect:enable"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		{Key: "type", Value: "dhcp-client", Line: 1},
+		{Key: "hostname", Value: "fw-1", Line: 3},
+		{Key: "plugin-op-commands", Value: "azure-gwlb-inspect:enable", Line: 4},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %v, want %v", c, want)
+	}
+	if got := string(c.Bytes()); got != "type=dhcp-client\nhostname=fw-1\nplugin-op-commands=azure-gwlb-inspect:enable\n" {
+		t.Errorf("got file %q", got)
+	}
+	if _, err := Parse([]byte("type=static\nip-address 10.0.0.4\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got error %v, want an error of line 2", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	c := Config{{Key: "type", Value: "dhcp-client", Line: 1}, {Key: "hostname", Value: "a", Line: 2}, {Key: "hostname", Value: "b", Line: 3}}
+	got := c.Set("hostname", "fw-1")
+	want := Config{{Key: "type", Value: "dhcp-client", Line: 1}, {Key: "hostname", Value: "fw-1", Line: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if v, _ := c.Get("hostname"); v != "a" {
+		t.Error("Set modified the configuration")
+	}
+	if v, ok := c.Set("dgname", "dg").Get("dgname"); !ok || v != "dg" {
+		t.Error("Set did not append a key")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name, file string
+		want       []string
+	}{
+		{
+			name: "dhcp",
+			file: "type=dhcp-client\nplugin-op-commands=azure-gwlb-inspect:enable\ndhcp-send-hostname=yes\n",
+		},
+		{
+			name: "static with panorama",
+			file: "type=static\nip-address=10.0.0.4\nnetmask=255.255.255.0\ndefault-gateway=10.0.0.1\nhostname=fw-1\n" +
+				"panorama-server=panorama.example.com\npanorama-server-2=10.1.0.4\ntplname=azure-stack\ndgname=azure\n" +
+				"vm-auth-key=755036225328715\nop-command-modes=mgmt-interface-swap,jumbo-frame\nauthcodes=D1234567\n",
+		},
+		{
+			name: "empty values are not set",
+			file: "type=dhcp-client\nhostname=\npanorama-server=\ntplname=\ndgname=\n",
+		},
+		{
+			name: "unknown and repeated keys",
+			file: "type=dhcp-client\nhost-name=fw-1\ntype=static\n",
+			want: []string{`line 2: unknown key "host-name", the firewall ignores it`, "line 3: type is already set on line 1"},
+		},
+		{
+			name: "malformed values",
+			file: "type=dhcp\nhostname=-fw\nop-command-modes=mgmt-interface-swap, jumbo-frame\n" +
+				"plugin-op-commands=azure-gwlb-inspect enable\ndhcp-send-hostname=true\n",
+			want: []string{
+				`line 1: type "dhcp" has to be one of static, dhcp-client`,
+				`line 2: hostname "-fw" has to be up to 63 letters, digits, periods, underscores and hyphens, starting with a letter or a digit`,
+				`line 3: op-command-modes "mgmt-interface-swap, jumbo-frame" element " jumbo-frame" has to be one of multi-vsys, jumbo-frame, mgmt-interface-swap`,
+				`line 4: plugin-op-commands "azure-gwlb-inspect enable" element "azure-gwlb-inspect enable" has to be a plugin command, i.e. azure-gwlb-inspect:enable`,
+				`line 5: dhcp-send-hostname "true" has to be one of yes, no`,
+			},
+		},
+		{
+			name: "incomplete static",
+			file: "type=static\nip-address=10.0.0.256\nnetmask=255.0.255.0\ndhcp-send-client-id=yes\n",
+			want: []string{
+				"type is static, default-gateway has to be set",
+				`line 2: ip-address "10.0.0.256" has to be an IPv4 address`,
+				`line 3: netmask "255.0.255.0" has to be an IPv4 netmask with contiguous ones, i.e. 255.255.255.0`,
+				"line 4: dhcp-send-client-id is set with type static, it applies only to dhcp-client",
+			},
+		},
+		{
+			name: "address with dhcp",
+			file: "type=dhcp-client\nip-address=10.0.0.4\n",
+			want: []string{"line 2: ip-address is set with type dhcp-client, the address is assigned by DHCP"},
+		},
+		{
+			name: "panorama settings without panorama",
+			file: "type=dhcp-client\ntplname=stack\ndgname=dg\nvm-auth-key=123\nvm-series-auto-registration-pin-id=abc\n",
+			want: []string{
+				"line 2: tplname is set without panorama-server, the firewall ignores it",
+				"line 3: dgname is set without panorama-server, the firewall ignores it",
+				"line 4: vm-auth-key \"123\" has to be the numeric key generated by `request bootstrap vm-auth-key generate`",
+				"line 4: vm-auth-key is set without panorama-server, the firewall ignores it",
+				"line 5: vm-series-auto-registration-pin-id is set without vm-series-auto-registration-pin-value, the firewall ignores it",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Parse([]byte(tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range Validate(c) {
+				got = append(got, p.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+// TestExamples verifies the samples the example tests bootstrap firewalls with.
+func TestExamples(t *testing.T) {
+	files, err := filepath.Glob("../../examples/*/files/init-cfg*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no init-cfg.txt samples found")
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		for _, p := range Validate(c) {
+			t.Errorf("%s: %s", file, p)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	base := Config{{Key: "type", Value: "dhcp-client", Line: 1}}
+	vmseries := cty.ObjectVal(map[string]cty.Value{
+		"vms02": cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal("vmseries02"),
+			"bootstrap_storage": cty.ObjectVal(map[string]cty.Value{
+				"key": cty.StringVal("bootstrap"),
+				"static_files": cty.ObjectVal(map[string]cty.Value{
+					"files/init-cfg.txt": cty.StringVal(Remote),
+					"files/authcodes":    cty.StringVal("license/authcodes"),
+				}),
+			}),
+		}),
+		"vms01": cty.ObjectVal(map[string]cty.Value{
+			"name": cty.StringVal("vmseries01"),
+		}),
+	})
+	got, err := Generate(base, vmseries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Firewall{
+		{
+			Key:         "vms01",
+			Config:      Config{{Key: "type", Value: "dhcp-client", Line: 1}, {Key: "hostname", Value: "vmseries01"}},
+			StaticFiles: map[string]string{"files/vms01-init-cfg.txt": Remote},
+		},
+		{
+			Key:         "vms02",
+			Config:      Config{{Key: "type", Value: "dhcp-client", Line: 1}, {Key: "hostname", Value: "vmseries02"}},
+			StaticFiles: map[string]string{"files/vms02-init-cfg.txt": Remote, "files/authcodes": "license/authcodes"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := Generate(base, cty.ObjectVal(map[string]cty.Value{"vms01": cty.EmptyObjectVal})); err == nil {
+		t.Error("a firewall without a name generated")
+	}
+}
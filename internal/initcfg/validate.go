@@ -0,0 +1,217 @@
+package initcfg
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Key describes a key of `init-cfg.txt`.
+type Key struct {
+	// Check returns why a value is not valid, or an empty string.
+	Check func(value string) string
+	// Requires are keys which have to be set together with this one, the key is ignored otherwise.
+	Requires []string
+}
+
+// Problem is a mistake in `init-cfg.txt`.
+type Problem struct {
+	// Line is 0 for problems of the whole file.
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+var (
+	hostnameRe      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+	nameRe          = regexp.MustCompile(`^[^\s]{1,63}$`)
+	vmAuthKeyRe     = regexp.MustCompile(`^[0-9]{8,32}$`)
+	authCodeRe      = regexp.MustCompile(`^[A-Za-z0-9]{6,32}$`)
+	pluginCommandRe = regexp.MustCompile(`^[a-z0-9-]+(:[A-Za-z0-9._-]+)?$`)
+	tokenRe         = regexp.MustCompile(`^[^\s]+$`)
+)
+
+func oneOf(values ...string) func(string) string {
+	return func(v string) string {
+		for _, allowed := range values {
+			if v == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("has to be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func matches(re *regexp.Regexp, text string) func(string) string {
+	return func(v string) string {
+		if re.MatchString(v) {
+			return ""
+		}
+		return "has to be " + text
+	}
+}
+
+func ipv4(v string) string {
+	if ip := net.ParseIP(v); ip == nil || ip.To4() == nil {
+		return "has to be an IPv4 address"
+	}
+	return ""
+}
+
+func ipv6(v string) string {
+	if ip := net.ParseIP(v); ip == nil || ip.To4() != nil {
+		return "has to be an IPv6 address"
+	}
+	return ""
+}
+
+func ipv6Prefix(v string) string {
+	if ip, _, err := net.ParseCIDR(v); err != nil || ip.To4() != nil {
+		return "has to be an IPv6 address with a prefix length, i.e. 2001:db8::10/64"
+	}
+	return ""
+}
+
+func netmask(v string) string {
+	ip := net.ParseIP(v).To4()
+	if ip == nil {
+		return "has to be an IPv4 netmask"
+	}
+	if ones, bits := net.IPMask(ip).Size(); ones == 0 && bits == 0 {
+		return "has to be an IPv4 netmask with contiguous ones, i.e. 255.255.255.0"
+	}
+	return ""
+}
+
+// server is an IP address or an FQDN.
+func server(v string) string {
+	if net.ParseIP(v) != nil || hostnameRe.MatchString(v) && strings.Contains(v, ".") && len(v) <= 253 {
+		return ""
+	}
+	return "has to be an IP address or a fully qualified domain name"
+}
+
+// list checks every element of a comma separated list.
+func list(check func(string) string) func(string) string {
+	return func(v string) string {
+		for _, e := range strings.Split(v, ",") {
+			if e == "" {
+				return "has an empty element, a list is comma separated without spaces"
+			}
+			if p := check(e); p != "" {
+				return fmt.Sprintf("element %q %s", e, p)
+			}
+		}
+		return ""
+	}
+}
+
+var yesNo = oneOf("yes", "no")
+
+// Keys are keys the firewall reads from `init-cfg.txt`.
+var Keys = map[string]Key{
+	"type":                 {Check: oneOf("static", "dhcp-client")},
+	"ip-address":           {Check: ipv4},
+	"netmask":              {Check: netmask},
+	"default-gateway":      {Check: ipv4},
+	"ipv6-address":         {Check: ipv6Prefix},
+	"ipv6-default-gateway": {Check: ipv6, Requires: []string{"ipv6-address"}},
+	"hostname":             {Check: matches(hostnameRe, "up to 63 letters, digits, periods, underscores and hyphens, starting with a letter or a digit")},
+	"dns-primary":          {Check: ipv4},
+	"dns-secondary":        {Check: ipv4, Requires: []string{"dns-primary"}},
+
+	"panorama-server":   {Check: server},
+	"panorama-server-2": {Check: server, Requires: []string{"panorama-server"}},
+	"tplname":           {Check: matches(nameRe, "a template stack name without spaces"), Requires: []string{"panorama-server"}},
+	"dgname":            {Check: matches(nameRe, "a device group name without spaces"), Requires: []string{"panorama-server"}},
+	"cgname":            {Check: matches(nameRe, "a collector group name without spaces"), Requires: []string{"panorama-server"}},
+	"vm-auth-key":       {Check: matches(vmAuthKeyRe, "the numeric key generated by `request bootstrap vm-auth-key generate`"), Requires: []string{"panorama-server"}},
+	"auth-key":          {Check: matches(tokenRe, "a device registration auth key without spaces"), Requires: []string{"panorama-server"}},
+
+	"authcodes":                             {Check: list(matches(authCodeRe, "an alphanumeric auth code"))},
+	"vm-series-auto-registration-pin-id":    {Check: matches(tokenRe, "a PIN ID without spaces"), Requires: []string{"vm-series-auto-registration-pin-value"}},
+	"vm-series-auto-registration-pin-value": {Check: matches(tokenRe, "a PIN value without spaces"), Requires: []string{"vm-series-auto-registration-pin-id"}},
+
+	"op-command-modes":   {Check: list(oneOf("multi-vsys", "jumbo-frame", "mgmt-interface-swap"))},
+	"op-cmd-dpdk-pkt-io": {Check: oneOf("on", "off")},
+	"plugin-op-commands": {Check: list(matches(pluginCommandRe, "a plugin command, i.e. azure-gwlb-inspect:enable"))},
+
+	"dhcp-send-hostname":          {Check: yesNo},
+	"dhcp-send-client-id":         {Check: yesNo},
+	"dhcp-accept-server-hostname": {Check: yesNo},
+	"dhcp-accept-server-domain":   {Check: yesNo},
+}
+
+// Validate returns unknown and repeated keys, malformed values, keys set without the keys they require
+// and settings contradicting the type of the management interface.
+func Validate(c Config) []Problem {
+	var problems []Problem
+	report := func(line int, format string, args ...interface{}) {
+		problems = append(problems, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]int{}
+	for _, e := range c {
+		key, ok := Keys[e.Key]
+		if !ok {
+			report(e.Line, "unknown key %q, the firewall ignores it", e.Key)
+			continue
+		}
+		if line, ok := seen[e.Key]; ok {
+			report(e.Line, "%s is already set on line %d", e.Key, line)
+			continue
+		}
+		seen[e.Key] = e.Line
+		if e.Value == "" {
+			// an empty value is a placeholder, the firewall treats it as not set
+			continue
+		}
+		if p := key.Check(e.Value); p != "" {
+			report(e.Line, "%s %q %s", e.Key, e.Value, p)
+		}
+	}
+
+	for _, e := range c {
+		if seen[e.Key] != e.Line || e.Value == "" {
+			continue
+		}
+		for _, required := range Keys[e.Key].Requires {
+			if v, _ := c.Get(required); v == "" {
+				report(e.Line, "%s is set without %s, the firewall ignores it", e.Key, required)
+			}
+		}
+	}
+
+	switch t, _ := c.Get("type"); t {
+	case "":
+		report(0, "type is not set, the management interface is not configured")
+	case "static":
+		for _, key := range []string{"ip-address", "netmask", "default-gateway"} {
+			if v, _ := c.Get(key); v == "" {
+				report(0, "type is static, %s has to be set", key)
+			}
+		}
+		for _, e := range c {
+			if strings.HasPrefix(e.Key, "dhcp-") && e.Value != "" {
+				report(e.Line, "%s is set with type static, it applies only to dhcp-client", e.Key)
+			}
+		}
+	case "dhcp-client":
+		for _, e := range c {
+			if (e.Key == "ip-address" || e.Key == "netmask" || e.Key == "default-gateway") && e.Value != "" {
+				report(e.Line, "%s is set with type dhcp-client, the address is assigned by DHCP", e.Key)
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
@@ -0,0 +1,506 @@
+// Package moduleapi compares interfaces of modules between two revisions and classifies the changes.
+//
+// Users consume the modules pinned by a tag. An upgrade breaks them when a variable is removed, a new variable
+// is required, a default or a type changes, an output is removed, or when a resource changes its address,
+// which makes Terraform destroy and re-create it. Every change found is classified with the semantic versioning
+// bump it requires, the release as a whole needs the highest of them.
+package moduleapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Bump is a semantic versioning bump.
+type Bump int
+
+const (
+	None Bump = iota
+	Patch
+	Minor
+	Major
+)
+
+func (b Bump) String() string {
+	switch b {
+	case Patch:
+		return "patch"
+	case Minor:
+		return "minor"
+	case Major:
+		return "major"
+	}
+	return "none"
+}
+
+// ParseBump parses the name of a bump.
+func ParseBump(s string) (Bump, error) {
+	for _, b := range []Bump{None, Patch, Minor, Major} {
+		if b.String() == s {
+			return b, nil
+		}
+	}
+	return None, fmt.Errorf("unknown bump %q, expected one of: none, patch, minor, major", s)
+}
+
+// Variable is a variable declared by a module.
+type Variable struct {
+	Name string
+	// Type is cty.DynamicPseudoType for variables of type `any` and variables without a type.
+	Type     cty.Type
+	Required bool
+	// Default is the default value, cty.NilVal when it is not set or is not a literal.
+	Default cty.Value
+	// DefaultSource is the normalized source of the default value.
+	DefaultSource string
+	Nullable      bool
+	Range         hcl.Range
+}
+
+// Output is an output declared by a module.
+type Output struct {
+	Name  string
+	Range hcl.Range
+}
+
+// Resource is a resource or a module call, both are kept in the state under their address.
+type Resource struct {
+	// Address is the address within the module, i.e. `azurerm_subnet.this` or `module.bootstrap`.
+	Address string
+	// Type is the type of a resource, or the source of a module call.
+	Type string
+	// Mode is `count`, `for_each` or empty for a single instance.
+	Mode string
+	// Keys is the normalized source of the `count` or `for_each` expression.
+	Keys string
+	// Instances are keys of instances, i.e. `[0]` or `["mgmt"]`, or a single empty key for a resource without
+	// `count` and `for_each`. They are known only when the expression is built of literals, see instances.
+	Instances      []string
+	InstancesKnown bool
+	// Attributes are normalized sources of arguments, by argument name. Nested blocks are kept by their type
+	// with an empty source.
+	Attributes map[string]string
+	Range      hcl.Range
+}
+
+// Moved is a `moved` block.
+type Moved struct {
+	From, To string
+	Range    hcl.Range
+}
+
+// Module is the interface of a module.
+type Module struct {
+	Variables map[string]*Variable
+	Outputs   map[string]*Output
+	Resources map[string]*Resource
+	Moved     []Moved
+	// Files are the sources the module was parsed from, by file name.
+	Files map[string][]byte
+}
+
+// Parse parses the module from its `.tf` files, by file name. Names are used in ranges of the parsed elements.
+func Parse(files map[string][]byte) (*Module, error) {
+	m := &Module{
+		Variables: map[string]*Variable{},
+		Outputs:   map[string]*Output{},
+		Resources: map[string]*Resource{},
+		Files:     files,
+	}
+	parser := hclparse.NewParser()
+	var diags hcl.Diagnostics
+	for _, name := range sortedKeys(files) {
+		src := files[name]
+		file, fileDiags := parser.ParseHCL(src, name)
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			diags = append(diags, m.decode(block, src)...)
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return m, nil
+}
+
+func (m *Module) decode(block *hclsyntax.Block, src []byte) hcl.Diagnostics {
+	switch {
+	case block.Type == "variable" && len(block.Labels) == 1:
+		v, diags := decodeVariable(block, src)
+		if v != nil {
+			m.Variables[v.Name] = v
+		}
+		return diags
+	case block.Type == "output" && len(block.Labels) == 1:
+		m.Outputs[block.Labels[0]] = &Output{Name: block.Labels[0], Range: block.DefRange()}
+	case block.Type == "resource" && len(block.Labels) == 2:
+		m.addResource(block.Labels[0]+"."+block.Labels[1], block.Labels[0], block, src)
+	case block.Type == "module" && len(block.Labels) == 1:
+		source := ""
+		if attr, ok := block.Body.Attributes["source"]; ok {
+			source = normalize(attr.Expr, src)
+		}
+		m.addResource("module."+block.Labels[0], source, block, src)
+	case block.Type == "moved":
+		from, fromOk := block.Body.Attributes["from"]
+		to, toOk := block.Body.Attributes["to"]
+		if !fromOk || !toOk {
+			return nil
+		}
+		fromTraversal, diags := hcl.AbsTraversalForExpr(from.Expr)
+		toTraversal, toDiags := hcl.AbsTraversalForExpr(to.Expr)
+		diags = append(diags, toDiags...)
+		if diags.HasErrors() {
+			return diags
+		}
+		m.Moved = append(m.Moved, Moved{From: FormatTraversal(fromTraversal), To: FormatTraversal(toTraversal), Range: block.DefRange()})
+	}
+	return nil
+}
+
+// metaArguments are arguments of resources and module calls which do not describe the managed object.
+var metaArguments = map[string]bool{
+	"count":      true,
+	"for_each":   true,
+	"depends_on": true,
+	"provider":   true,
+	"providers":  true,
+	"source":     true,
+	"version":    true,
+}
+
+func (m *Module) addResource(address, typ string, block *hclsyntax.Block, src []byte) {
+	r := &Resource{
+		Address:        address,
+		Type:           typ,
+		Instances:      []string{""},
+		InstancesKnown: true,
+		Attributes:     map[string]string{},
+		Range:          block.DefRange(),
+	}
+	for _, mode := range []string{"count", "for_each"} {
+		if attr, ok := block.Body.Attributes[mode]; ok {
+			r.Mode = mode
+			r.Keys = normalize(attr.Expr, src)
+			r.Instances, r.InstancesKnown = instances(mode, attr.Expr)
+		}
+	}
+	for name, attr := range block.Body.Attributes {
+		if !metaArguments[name] {
+			r.Attributes[name] = normalize(attr.Expr, src)
+		}
+	}
+	for _, nested := range block.Body.Blocks {
+		if nested.Type != "lifecycle" {
+			r.Attributes[nested.Type] = ""
+		}
+	}
+	m.Resources[address] = r
+}
+
+func decodeVariable(block *hclsyntax.Block, src []byte) (*Variable, hcl.Diagnostics) {
+	v := &Variable{
+		Name:     block.Labels[0],
+		Type:     cty.DynamicPseudoType,
+		Required: true,
+		Nullable: true,
+		Range:    block.DefRange(),
+	}
+	if attr, ok := block.Body.Attributes["type"]; ok {
+		ty, diags := typeexpr.TypeConstraint(attr.Expr)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		v.Type = ty
+	}
+	if attr, ok := block.Body.Attributes["default"]; ok {
+		v.Required = false
+		v.DefaultSource = normalize(attr.Expr, src)
+		if val, diags := attr.Expr.Value(nil); !diags.HasErrors() {
+			v.Default = val
+		}
+	}
+	if attr, ok := block.Body.Attributes["nullable"]; ok {
+		if val, diags := attr.Expr.Value(nil); !diags.HasErrors() && val.Type() == cty.Bool && !val.IsNull() {
+			v.Nullable = val.True()
+		}
+	}
+	return v, nil
+}
+
+// normalize returns the source of an expression without whitespace and comments, so that formatting is not a change.
+// Tokens are joined without a separator, adjacent tokens never need one to be told apart.
+func normalize(expr hclsyntax.Expression, src []byte) string {
+	rng := expr.Range()
+	tokens, _ := hclsyntax.LexExpression(rng.SliceBytes(src), rng.Filename, rng.Start)
+	var parts []string
+	for _, t := range tokens {
+		switch t.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComment, hclsyntax.TokenEOF:
+			continue
+		}
+		parts = append(parts, string(t.Bytes))
+	}
+	return strings.Join(parts, "")
+}
+
+// FormatTraversal renders an absolute traversal, i.e. `module.vnet` or `azurerm_subnet.this["public"]`.
+func FormatTraversal(traversal hcl.Traversal) string {
+	var sb strings.Builder
+	for i, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			sb.WriteString(s.Name)
+		case hcl.TraverseAttr:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(s.Name)
+		case hcl.TraverseIndex:
+			if s.Key.Type() == cty.String {
+				fmt.Fprintf(&sb, "[%q]", s.Key.AsString())
+			} else if s.Key.Type() == cty.Number {
+				fmt.Fprintf(&sb, "[%s]", s.Key.AsBigFloat().String())
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Kind is a kind of change of a module's interface.
+type Kind string
+
+const (
+	ModuleAdded           Kind = "module added"
+	ModuleRemoved         Kind = "module removed"
+	VariableAdded         Kind = "variable added"
+	RequiredVariableAdded Kind = "required variable added"
+	VariableRemoved       Kind = "variable removed"
+	VariableRequired      Kind = "variable became required"
+	VariableOptional      Kind = "variable became optional"
+	DefaultChanged        Kind = "default changed"
+	TypeWidened           Kind = "type widened"
+	TypeNarrowed          Kind = "type narrowed"
+	NullableChanged       Kind = "nullable changed"
+	OutputAdded           Kind = "output added"
+	OutputRemoved         Kind = "output removed"
+	ResourceAdded         Kind = "resource added"
+	ResourceRemoved       Kind = "resource removed"
+	ResourceMoved         Kind = "resource moved"
+	ResourceModeChanged   Kind = "resource mode changed"
+	ResourceKeysChanged   Kind = "resource keys changed"
+	SourceChanged         Kind = "source changed"
+)
+
+// Change is a single change of a module's interface.
+type Change struct {
+	Module string
+	Kind   Kind
+	Bump   Bump
+	// Subject is the changed element, i.e. `variable "name"`.
+	Subject string
+	Detail  string
+	// Range is the location of the element in the new revision, or in the old one when it is removed.
+	Range hcl.Range
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s: %s: %s", c.Bump, c.Kind, c.Subject)
+	if c.Detail != "" {
+		s += ", " + c.Detail
+	}
+	if c.Range.Filename != "" {
+		s = fmt.Sprintf("%s:%d: %s", c.Range.Filename, c.Range.Start.Line, s)
+	}
+	return s
+}
+
+// Suggest returns the bump required by all changes.
+func Suggest(changes []Change) Bump {
+	bump := None
+	for _, c := range changes {
+		if c.Bump > bump {
+			bump = c.Bump
+		}
+	}
+	return bump
+}
+
+// DiffAll compares modules of two revisions, by the module's directory.
+func DiffAll(old, new map[string]*Module) []Change {
+	var changes []Change
+	for _, name := range sortedKeys(old) {
+		if _, ok := new[name]; !ok {
+			changes = append(changes, Change{Module: name, Kind: ModuleRemoved, Bump: Major, Subject: "module " + name})
+		}
+	}
+	for _, name := range sortedKeys(new) {
+		o, ok := old[name]
+		if !ok {
+			changes = append(changes, Change{Module: name, Kind: ModuleAdded, Bump: Minor, Subject: "module " + name})
+			continue
+		}
+		changes = append(changes, Diff(name, o, new[name])...)
+	}
+	return changes
+}
+
+// Diff compares two revisions of a module. When the interface is the same but sources differ,
+// a single change requiring a patch is returned.
+func Diff(name string, old, new *Module) []Change {
+	d := &differ{module: name}
+	d.variables(old, new)
+	d.outputs(old, new)
+	d.resources(old, new)
+	if len(d.changes) == 0 && !sameFiles(old.Files, new.Files) {
+		d.add(SourceChanged, Patch, "module "+name, "", hcl.Range{})
+	}
+	sort.SliceStable(d.changes, func(i, j int) bool { return d.changes[i].Bump > d.changes[j].Bump })
+	return d.changes
+}
+
+type differ struct {
+	module  string
+	changes []Change
+}
+
+func (d *differ) add(kind Kind, bump Bump, subject, detail string, rng hcl.Range) {
+	d.changes = append(d.changes, Change{Module: d.module, Kind: kind, Bump: bump, Subject: subject, Detail: detail, Range: rng})
+}
+
+func (d *differ) variables(old, new *Module) {
+	for _, name := range sortedKeys(old.Variables) {
+		o := old.Variables[name]
+		subject := fmt.Sprintf("variable %q", name)
+		n, ok := new.Variables[name]
+		if !ok {
+			d.add(VariableRemoved, Major, subject, "", o.Range)
+			continue
+		}
+		if !o.Type.Equals(n.Type) {
+			detail := fmt.Sprintf("from %s to %s", typeexpr.TypeString(o.Type), typeexpr.TypeString(n.Type))
+			if o.Type != cty.DynamicPseudoType && convert.GetConversion(o.Type, n.Type) != nil {
+				d.add(TypeWidened, Minor, subject, detail, n.Range)
+			} else {
+				d.add(TypeNarrowed, Major, subject, detail, n.Range)
+			}
+		}
+		switch {
+		case !o.Required && n.Required:
+			d.add(VariableRequired, Major, subject, "the default value is removed", n.Range)
+		case !o.Required && !n.Required && !sameDefault(o, n):
+			d.add(DefaultChanged, Major, subject, fmt.Sprintf("from %s to %s", o.DefaultSource, n.DefaultSource), n.Range)
+		case o.Required && !n.Required:
+			d.add(VariableOptional, Minor, subject, "", n.Range)
+		}
+		if o.Nullable != n.Nullable {
+			if n.Nullable {
+				d.add(NullableChanged, Minor, subject, "null is accepted", n.Range)
+			} else {
+				d.add(NullableChanged, Major, subject, "null is not accepted anymore", n.Range)
+			}
+		}
+	}
+	for _, name := range sortedKeys(new.Variables) {
+		if _, ok := old.Variables[name]; ok {
+			continue
+		}
+		n := new.Variables[name]
+		if n.Required {
+			d.add(RequiredVariableAdded, Major, fmt.Sprintf("variable %q", name), "", n.Range)
+		} else {
+			d.add(VariableAdded, Minor, fmt.Sprintf("variable %q", name), "", n.Range)
+		}
+	}
+}
+
+func (d *differ) outputs(old, new *Module) {
+	for _, name := range sortedKeys(old.Outputs) {
+		if _, ok := new.Outputs[name]; !ok {
+			d.add(OutputRemoved, Major, fmt.Sprintf("output %q", name), "", old.Outputs[name].Range)
+		}
+	}
+	for _, name := range sortedKeys(new.Outputs) {
+		if _, ok := old.Outputs[name]; !ok {
+			d.add(OutputAdded, Minor, fmt.Sprintf("output %q", name), "", new.Outputs[name].Range)
+		}
+	}
+}
+
+func (d *differ) resources(old, new *Module) {
+	moved := map[string]Moved{}
+	for _, m := range new.Moved {
+		moved[m.From] = m
+	}
+	targets := map[string]bool{}
+	for _, address := range sortedKeys(old.Resources) {
+		o := old.Resources[address]
+		n, ok := new.Resources[address]
+		if !ok {
+			if m, ok := moved[address]; ok {
+				d.add(ResourceMoved, Patch, address, "to "+m.To+" with a moved block", m.Range)
+				targets[m.To] = true
+				continue
+			}
+			d.add(ResourceRemoved, Major, address, "existing instances are destroyed unless it is moved", o.Range)
+			continue
+		}
+		switch {
+		case o.Mode != n.Mode:
+			d.add(ResourceModeChanged, Major, address, fmt.Sprintf("from %s to %s, instances change their addresses", modeString(o.Mode), modeString(n.Mode)), n.Range)
+		case n.Mode == "for_each" && o.Keys != n.Keys:
+			d.add(ResourceKeysChanged, Major, address, "the for_each expression changed, instances can change their keys", n.Range)
+		}
+	}
+	for _, address := range sortedKeys(new.Resources) {
+		if _, ok := old.Resources[address]; !ok && !targets[address] {
+			d.add(ResourceAdded, Minor, address, "", new.Resources[address].Range)
+		}
+	}
+}
+
+func sameDefault(o, n *Variable) bool {
+	if o.Default != cty.NilVal && n.Default != cty.NilVal {
+		return o.Default.RawEquals(n.Default)
+	}
+	return o.DefaultSource == n.DefaultSource
+}
+
+func sameFiles(old, new map[string][]byte) bool {
+	if len(old) != len(new) {
+		return false
+	}
+	for name, src := range old {
+		if string(new[name]) != string(src) {
+			return false
+		}
+	}
+	return true
+}
+
+func modeString(mode string) string {
+	if mode == "" {
+		return "a single instance"
+	}
+	return mode
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,346 @@
+package moduleapi
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const oldVariables = `
+variable "name" {
+  type = string
+}
+
+variable "location" {
+  type = string
+}
+
+variable "removed" {
+  type    = string
+  default = null
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "sku" {
+  type    = string
+  default = "Standard"
+}
+
+variable "zones" {
+  type    = list(string)
+  default = ["1", "2", "3"]
+}
+
+variable "size" {
+  type = string
+}
+
+variable "subnets" {
+  type = map(object({
+    address_prefixes = list(string)
+  }))
+  default = {}
+}
+
+variable "password" {
+  type    = string
+  default = null
+}
+
+variable "ssh_keys" {
+  type    = list(string)
+  default = []
+}
+
+variable "admin" {
+  type = string
+}
+`
+
+const newVariables = `
+variable "name" {
+  type = string
+}
+
+variable "location" {
+  type = string
+}
+
+variable "tags" {
+  type    = map(any)
+  default = {}
+}
+
+variable "sku" {
+  type    = string
+  default = "Premium"
+}
+
+variable "zones" {
+  type = list(string)
+  # formatting is not a change
+  default = [
+    "1",
+    "2",
+    "3",
+  ]
+}
+
+variable "size" {
+  type = number
+}
+
+variable "subnets" {
+  type = map(object({
+    address_prefixes = list(string)
+    route_table      = string
+  }))
+  default = {}
+}
+
+variable "password" {
+  type = string
+}
+
+variable "ssh_keys" {
+  type     = list(string)
+  default  = []
+  nullable = false
+}
+
+variable "admin" {
+  type    = string
+  default = "panadmin"
+}
+
+variable "new_optional" {
+  type    = bool
+  default = false
+}
+
+variable "new_required" {
+  type = string
+}
+`
+
+const oldMain = `
+resource "azurerm_virtual_network" "this" {
+  name = var.name
+}
+
+resource "azurerm_subnet" "this" {
+  for_each = var.subnets
+}
+
+resource "azurerm_public_ip" "this" {
+  count = 1
+}
+
+resource "azurerm_route_table" "this" {
+  for_each = var.subnets
+}
+
+resource "azurerm_network_security_group" "this" {
+}
+
+module "bootstrap" {
+  source = "../bootstrap"
+}
+
+output "id" {
+  value = azurerm_virtual_network.this.id
+}
+
+output "subnet_ids" {
+  value = {}
+}
+`
+
+const newMain = `
+resource "azurerm_virtual_network" "this" {
+  name = var.name
+}
+
+resource "azurerm_subnet" "this" {
+  for_each = var.subnets
+}
+
+resource "azurerm_public_ip" "this" {
+  for_each = toset(["mgmt"])
+}
+
+resource "azurerm_route_table" "this" {
+  for_each = { for k, v in var.subnets : v.route_table => v }
+}
+
+resource "azurerm_network_security_group" "main" {
+}
+
+moved {
+  from = module.bootstrap
+  to   = module.storage
+}
+
+module "storage" {
+  source = "../bootstrap"
+}
+
+resource "azurerm_nat_gateway" "this" {
+}
+
+output "id" {
+  value = azurerm_virtual_network.this.id
+}
+
+output "vnet_cidr" {
+  value = []
+}
+`
+
+func parse(t *testing.T, files map[string]string) *Module {
+	t.Helper()
+	sources := map[string][]byte{}
+	for name, src := range files {
+		sources[name] = []byte(src)
+	}
+	m, err := Parse(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDiff(t *testing.T) {
+	old := parse(t, map[string]string{"variables.tf": oldVariables, "main.tf": oldMain})
+	new := parse(t, map[string]string{"variables.tf": newVariables, "main.tf": newMain})
+
+	changes := Diff("vnet", old, new)
+	var got []string
+	for _, c := range changes {
+		got = append(got, strings.SplitN(c.String(), ": ", 2)[1])
+	}
+	want := []string{
+		`major: variable became required: variable "password", the default value is removed`,
+		`major: variable removed: variable "removed"`,
+		`major: type narrowed: variable "size", from string to number`,
+		`major: default changed: variable "sku", from "Standard" to "Premium"`,
+		`major: nullable changed: variable "ssh_keys", null is not accepted anymore`,
+		`major: type narrowed: variable "subnets", from map(object({address_prefixes=list(string)})) to map(object({address_prefixes=list(string),route_table=string}))`,
+		`major: required variable added: variable "new_required"`,
+		`major: output removed: output "subnet_ids"`,
+		`major: resource removed: azurerm_network_security_group.this, existing instances are destroyed unless it is moved`,
+		`major: resource mode changed: azurerm_public_ip.this, from count to for_each, instances change their addresses`,
+		`major: resource keys changed: azurerm_route_table.this, the for_each expression changed, instances can change their keys`,
+		`minor: variable became optional: variable "admin"`,
+		`minor: type widened: variable "tags", from map(string) to map(any)`,
+		`minor: variable added: variable "new_optional"`,
+		`minor: output added: output "vnet_cidr"`,
+		`minor: resource added: azurerm_nat_gateway.this`,
+		`minor: resource added: azurerm_network_security_group.main`,
+		`patch: resource moved: module.bootstrap, to module.storage with a moved block`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if bump := Suggest(changes); bump != Major {
+		t.Errorf("suggested %s, want major", bump)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	old := parse(t, map[string]string{"variables.tf": oldVariables, "main.tf": oldMain})
+	same := parse(t, map[string]string{"variables.tf": oldVariables, "main.tf": oldMain})
+	if changes := Diff("vnet", old, same); len(changes) != 0 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	// a change of the code only, i.e. a fix of a resource's argument, is a patch
+	fixed := parse(t, map[string]string{"variables.tf": oldVariables, "main.tf": strings.Replace(oldMain, "var.name", "\"${var.name}-vnet\"", 1)})
+	changes := Diff("vnet", old, fixed)
+	if len(changes) != 1 || changes[0].Kind != SourceChanged || Suggest(changes) != Patch {
+		t.Errorf("expected a patch, got %v", changes)
+	}
+}
+
+func TestDiffAll(t *testing.T) {
+	vnet := parse(t, map[string]string{"variables.tf": oldVariables})
+	changes := DiffAll(
+		map[string]*Module{"vnet": vnet, "natgw": vnet},
+		map[string]*Module{"vnet": vnet, "gwlb": vnet},
+	)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := "major: module removed: module natgw\nminor: module added: module gwlb"
+	if strings.Join(got, "\n") != want {
+		t.Errorf("got changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), want)
+	}
+}
+
+func TestParseBump(t *testing.T) {
+	if b, err := ParseBump("minor"); err != nil || b != Minor {
+		t.Errorf("ParseBump(minor) = %s, %v", b, err)
+	}
+	if _, err := ParseBump("huge"); err == nil {
+		t.Error("expected an error for an unknown bump")
+	}
+}
+
+func TestReadRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("modules/vnet/variables.tf", oldVariables)
+	write("modules/vnet/README.md", "# vnet\n")
+	write("modules/vnet/examples/nested.tf", "variable \"ignored\" {}\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "v1")
+	run("tag", "v1.0.0")
+	write("modules/vnet/variables.tf", newVariables)
+
+	old, err := ReadRevision(repo, "v1.0.0", "modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 1 || old["vnet"] == nil || old["vnet"].Variables["removed"] == nil || old["vnet"].Variables["ignored"] != nil {
+		t.Fatalf("modules not read from the revision: %v", old)
+	}
+	new, err := ReadDir(repo, "modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new["vnet"] == nil || new["vnet"].Variables["removed"] != nil {
+		t.Fatalf("modules not read from the working tree: %v", new)
+	}
+	if bump := Suggest(DiffAll(old, new)); bump != Major {
+		t.Errorf("suggested %s, want major", bump)
+	}
+}
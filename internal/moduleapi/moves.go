@@ -0,0 +1,290 @@
+package moduleapi
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Move is a proposed move of a resource, or of its instances, between two revisions of a module.
+// Without it, Terraform destroys the resource under its old address and creates it under the new one.
+type Move struct {
+	// From and To are addresses within the module, with an instance key when only an instance is moved,
+	// i.e. `azurerm_storage_share.this[0]` and `azurerm_storage_share.this["bootstrap"]`.
+	From, To string
+	// Reason explains why the address changed.
+	Reason string
+	// Manual is set when the instances cannot be matched, it explains why. From and To are resource addresses then.
+	Manual string
+	// Edit is set when To holds PlaceholderKey instead of a key not known without inputs of the module,
+	// it explains what to replace it with.
+	Edit string
+}
+
+// PlaceholderKey stands for a `for_each` key of a moved instance which has to be filled in by hand.
+const PlaceholderKey = "KEY"
+
+// ProposeMoves compares resource addresses of two revisions of a module and proposes moves for resources
+// which are renamed or switched between a single instance, `count` and `for_each`. Renamed resources are paired
+// by their type and the similarity of their arguments, so the result is a proposal to be reviewed.
+// Resources already covered by `moved` blocks of the new revision are skipped.
+func ProposeMoves(old, new *Module) []Move {
+	moved, targets := map[string]bool{}, map[string]bool{}
+	for _, m := range new.Moved {
+		moved[m.From] = true
+		targets[m.To] = true
+	}
+
+	var moves []Move
+	var removed, added []*Resource
+	for _, address := range sortedKeys(old.Resources) {
+		o := old.Resources[address]
+		n, ok := new.Resources[address]
+		switch {
+		case !ok && !moved[address]:
+			removed = append(removed, o)
+		case !ok:
+		case o.Mode != n.Mode:
+			moves = append(moves, instanceMoves(o, n, fmt.Sprintf("%s to %s", modeString(o.Mode), modeString(n.Mode)))...)
+		case n.Mode == "for_each" && o.Keys != n.Keys:
+			moves = append(moves, Move{From: address, To: address, Reason: "for_each changed",
+				Manual: "the for_each expression changed, move instances whose keys changed"})
+		}
+	}
+	for _, address := range sortedKeys(new.Resources) {
+		if _, ok := old.Resources[address]; !ok && !targets[address] {
+			added = append(added, new.Resources[address])
+		}
+	}
+
+	for _, p := range pairRenames(removed, added) {
+		o, n := p[0], p[1]
+		if o.Mode == n.Mode {
+			moves = append(moves, Move{From: o.Address, To: n.Address, Reason: "renamed"})
+			continue
+		}
+		moves = append(moves, instanceMoves(o, n, fmt.Sprintf("renamed, %s to %s", modeString(o.Mode), modeString(n.Mode)))...)
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].From < moves[j].From })
+	return moves
+}
+
+// instanceMoves matches instances of a resource which changed its mode. Only single instances can be matched,
+// there is no way to tell which key of `for_each` an index of `count` corresponds to. A single instance moved
+// to `for_each` keys not known without inputs of the module, like a storage share created with
+// `count = var.storage_share_name != null ? 1 : 0` and then with `for_each = var.storage_shares`, gets
+// PlaceholderKey.
+func instanceMoves(o, n *Resource, reason string) []Move {
+	if o.InstancesKnown && len(o.Instances) == 1 && !n.InstancesKnown && n.Mode == "for_each" {
+		return []Move{{From: o.Address + o.Instances[0], To: fmt.Sprintf("%s[%q]", n.Address, PlaceholderKey), Reason: reason,
+			Edit: fmt.Sprintf("replace %q with the key the instance has in for_each", PlaceholderKey)}}
+	}
+	if !o.InstancesKnown || !n.InstancesKnown {
+		return []Move{{From: o.Address, To: n.Address, Reason: reason,
+			Manual: "instance keys are not known without inputs of the module, move every instance"}}
+	}
+	if len(o.Instances) != 1 || len(n.Instances) != 1 {
+		return []Move{{From: o.Address, To: n.Address, Reason: reason,
+			Manual: fmt.Sprintf("instances %s cannot be matched with %s, move every instance",
+				strings.Join(o.Instances, ", "), strings.Join(n.Instances, ", "))}}
+	}
+	return []Move{{From: o.Address + o.Instances[0], To: n.Address + n.Instances[0], Reason: reason}}
+}
+
+// pairRenames pairs removed and added resources of the same type, the most similar ones first.
+// A pair has to share at least a half of its arguments, unless it is the only removed and added resource of its type.
+func pairRenames(removed, added []*Resource) [][2]*Resource {
+	type candidate struct {
+		o, n  *Resource
+		score float64
+	}
+	perType := map[string][2]int{}
+	for _, r := range removed {
+		c := perType[r.Type]
+		c[0]++
+		perType[r.Type] = c
+	}
+	for _, r := range added {
+		c := perType[r.Type]
+		c[1]++
+		perType[r.Type] = c
+	}
+
+	var candidates []candidate
+	for _, o := range removed {
+		for _, n := range added {
+			if o.Type != n.Type {
+				continue
+			}
+			score := similarity(o.Attributes, n.Attributes)
+			if score >= 0.5 || perType[o.Type] == [2]int{1, 1} {
+				candidates = append(candidates, candidate{o, n, score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	used := map[*Resource]bool{}
+	var pairs [][2]*Resource
+	for _, c := range candidates {
+		if used[c.o] || used[c.n] {
+			continue
+		}
+		used[c.o], used[c.n] = true, true
+		pairs = append(pairs, [2]*Resource{c.o, c.n})
+	}
+	return pairs
+}
+
+// similarity scores arguments of two resources: an argument with the same source counts fully, an argument
+// set in both with a different source counts half.
+func similarity(a, b map[string]string) float64 {
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		return 1
+	}
+	var score float64
+	for name := range names {
+		av, aok := a[name]
+		bv, bok := b[name]
+		switch {
+		case aok && bok && av == bv:
+			score++
+		case aok && bok:
+			score += 0.5
+		}
+	}
+	return score / float64(len(names))
+}
+
+// instances returns keys of instances created with a `count` or `for_each` expression built of literals.
+// Both branches of a conditional are taken, i.e. `var.create ? 1 : 0` creates the instance `[0]`.
+func instances(mode string, expr hclsyntax.Expression) ([]string, bool) {
+	values, ok := branches(expr)
+	if !ok {
+		return nil, false
+	}
+	seen := map[string]bool{}
+	var keys []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, val := range values {
+		if val.IsNull() {
+			return nil, false
+		}
+		ty := val.Type()
+		switch {
+		case mode == "count" && ty == cty.Number:
+			n, accuracy := val.AsBigFloat().Int64()
+			if accuracy != big.Exact {
+				return nil, false
+			}
+			for i := int64(0); i < n; i++ {
+				add(fmt.Sprintf("[%d]", i))
+			}
+		case mode == "for_each" && (ty.IsObjectType() || ty.IsMapType()):
+			for it := val.ElementIterator(); it.Next(); {
+				k, _ := it.Element()
+				add(fmt.Sprintf("[%q]", k.AsString()))
+			}
+		case mode == "for_each" && (ty.IsTupleType() || ty.IsListType() || ty.IsSetType()):
+			for it := val.ElementIterator(); it.Next(); {
+				_, v := it.Element()
+				if v.Type() != cty.String || v.IsNull() {
+					return nil, false
+				}
+				add(fmt.Sprintf("[%q]", v.AsString()))
+			}
+		default:
+			return nil, false
+		}
+	}
+	sort.Strings(keys)
+	return keys, true
+}
+
+// branches returns values of a literal expression, or of every branch of a conditional one.
+// Type conversions like `toset([...])` are unwrapped, the keys they produce are the same.
+func branches(expr hclsyntax.Expression) ([]cty.Value, bool) {
+	switch e := expr.(type) {
+	case *hclsyntax.ConditionalExpr:
+		t, ok := branches(e.TrueResult)
+		if !ok {
+			return nil, false
+		}
+		f, ok := branches(e.FalseResult)
+		if !ok {
+			return nil, false
+		}
+		return append(t, f...), true
+	case *hclsyntax.FunctionCallExpr:
+		if (e.Name == "toset" || e.Name == "tomap" || e.Name == "tolist") && len(e.Args) == 1 {
+			return branches(e.Args[0])
+		}
+		return nil, false
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || !val.IsWhollyKnown() {
+		return nil, false
+	}
+	return []cty.Value{val}, true
+}
+
+// FormatMovedBlocks renders moves as `moved` blocks to be added to the module. Moves which have to be done manually
+// are rendered as comments, blocks which have to be edited are preceded by one.
+func FormatMovedBlocks(moves []Move) string {
+	var blocks []string
+	for _, m := range moves {
+		if m.Manual != "" {
+			blocks = append(blocks, fmt.Sprintf("# TODO: %s -> %s (%s): %s\n", m.From, m.To, m.Reason, m.Manual))
+			continue
+		}
+		var todo string
+		if m.Edit != "" {
+			todo = fmt.Sprintf("# TODO: %s\n", m.Edit)
+		}
+		blocks = append(blocks, fmt.Sprintf("# %s\n%smoved {\n  from = %s\n  to   = %s\n}\n", m.Reason, todo, m.From, m.To))
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// FormatStateMv renders moves as a shell script moving resources in the state of a root module calling the module,
+// for users who cannot upgrade to a release with `moved` blocks. The address of the module call is read from
+// the MODULE environment variable.
+func FormatStateMv(moves []Move) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString("# Run in the root module calling the module, with MODULE set to the address of the module call,\n")
+	sb.WriteString("# i.e. MODULE='module.bootstrap[\"sa\"]'.\n")
+	sb.WriteString("set -e\n")
+	sb.WriteString(": \"${MODULE:?set MODULE to the address of the module call}\"\n")
+	for _, m := range moves {
+		if m.Manual != "" {
+			fmt.Fprintf(&sb, "# TODO: %s -> %s (%s): %s\n", m.From, m.To, m.Reason, m.Manual)
+			continue
+		}
+		if m.Edit != "" {
+			fmt.Fprintf(&sb, "# TODO: %s\n", m.Edit)
+		}
+		fmt.Fprintf(&sb, "terraform state mv \"$MODULE\"%s \"$MODULE\"%s\n", shellQuote("."+m.From), shellQuote("."+m.To))
+	}
+	return sb.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
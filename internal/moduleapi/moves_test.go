@@ -0,0 +1,252 @@
+package moduleapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const oldResources = `
+resource "azurerm_storage_account" "this" {
+  name                     = var.name
+  resource_group_name      = var.resource_group_name
+  account_replication_type = "LRS"
+}
+
+resource "azurerm_storage_share" "this" {
+  count                = var.create_share ? 1 : 0
+  name                 = var.share_name
+  storage_account_name = azurerm_storage_account.this.name
+  quota                = 50
+}
+
+resource "azurerm_public_ip" "mgmt" {
+  name              = "${var.name}-mgmt"
+  allocation_method = "Static"
+  sku               = "Standard"
+}
+
+resource "azurerm_public_ip" "data" {
+  name              = "${var.name}-data"
+  allocation_method = "Static"
+  sku               = "Standard"
+  zones             = var.zones
+}
+
+resource "azurerm_network_interface" "this" {
+  name = var.name
+}
+
+resource "azurerm_lb_rule" "this" {
+  for_each = var.rules
+  name     = each.key
+}
+
+resource "azurerm_lb_probe" "this" {
+  count = 2
+  name  = "probe-${count.index}"
+}
+
+resource "azurerm_subnet" "this" {
+  name = var.name
+}
+
+resource "azurerm_application_insights" "this" {
+  name = var.name
+}
+
+module "bootstrap" {
+  source = "../bootstrap"
+  name   = var.name
+}
+`
+
+const newResources = `
+resource "azurerm_storage_account" "this" {
+  name                     = var.name
+  resource_group_name      = var.resource_group_name
+  account_replication_type = "LRS"
+}
+
+resource "azurerm_storage_share" "this" {
+  for_each             = toset(["bootstrap"])
+  name                 = var.share_name
+  storage_account_name = azurerm_storage_account.this.name
+  quota                = 50
+}
+
+resource "azurerm_public_ip" "management" {
+  name              = "${var.name}-mgmt"
+  allocation_method = "Static"
+  sku               = "Standard"
+}
+
+resource "azurerm_public_ip" "dataplane" {
+  name              = "${var.name}-data"
+  allocation_method = "Static"
+  sku               = "Standard"
+  zones             = var.zones
+}
+
+resource "azurerm_network_interface" "primary" {
+  count = 1
+  name  = var.name
+}
+
+resource "azurerm_lb_rule" "this" {
+  for_each = { for r in var.rules : r.name => r }
+  name     = each.key
+}
+
+resource "azurerm_lb_probe" "this" {
+  for_each = { http = 80, https = 443 }
+  name     = each.key
+}
+
+moved {
+  from = azurerm_subnet.this
+  to   = azurerm_subnet.main
+}
+
+resource "azurerm_subnet" "main" {
+  name = var.name
+}
+
+resource "azurerm_log_analytics_workspace" "this" {
+  name = var.name
+}
+
+module "storage" {
+  source = "../bootstrap"
+  name   = var.name
+}
+`
+
+func TestProposeMoves(t *testing.T) {
+	old := parse(t, map[string]string{"main.tf": oldResources})
+	new := parse(t, map[string]string{"main.tf": newResources})
+
+	var got []string
+	for _, m := range ProposeMoves(old, new) {
+		s := m.From + " -> " + m.To + ": " + m.Reason
+		if m.Manual != "" {
+			s += ", manual: " + m.Manual
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		`azurerm_lb_probe.this -> azurerm_lb_probe.this: count to for_each, manual: instances [0], [1] cannot be matched with ["http"], ["https"], move every instance`,
+		`azurerm_lb_rule.this -> azurerm_lb_rule.this: for_each changed, manual: the for_each expression changed, move instances whose keys changed`,
+		`azurerm_network_interface.this -> azurerm_network_interface.primary[0]: renamed, a single instance to count`,
+		// two Public IPs are renamed, they are paired by their arguments
+		`azurerm_public_ip.data -> azurerm_public_ip.dataplane: renamed`,
+		`azurerm_public_ip.mgmt -> azurerm_public_ip.management: renamed`,
+		`azurerm_storage_share.this[0] -> azurerm_storage_share.this["bootstrap"]: count to for_each`,
+		`module.bootstrap -> module.storage: renamed`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got moves:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestProposeMovesUnknownInstances(t *testing.T) {
+	for _, tc := range []struct {
+		old, new string
+		want     Move
+	}{
+		{
+			// the storage share of the bootstrap module
+			old: `count = var.storage_share_name != null ? 1 : 0`,
+			new: `for_each = var.storage_shares`,
+			want: Move{From: "azurerm_storage_share.this[0]", To: `azurerm_storage_share.this["KEY"]`, Reason: "count to for_each",
+				Edit: `replace "KEY" with the key the instance has in for_each`},
+		},
+		{
+			old: `count = var.storage_share_count`,
+			new: `for_each = var.storage_shares`,
+			want: Move{From: "azurerm_storage_share.this", To: "azurerm_storage_share.this", Reason: "count to for_each",
+				Manual: "instance keys are not known without inputs of the module, move every instance"},
+		},
+		{
+			old: `count = 2`,
+			new: `for_each = var.storage_shares`,
+			want: Move{From: "azurerm_storage_share.this", To: "azurerm_storage_share.this", Reason: "count to for_each",
+				Manual: "instance keys are not known without inputs of the module, move every instance"},
+		},
+	} {
+		old := parse(t, map[string]string{"main.tf": "resource \"azurerm_storage_share\" \"this\" {\n  " + tc.old + "\n}\n"})
+		new := parse(t, map[string]string{"main.tf": "resource \"azurerm_storage_share\" \"this\" {\n  " + tc.new + "\n}\n"})
+		moves := ProposeMoves(old, new)
+		if len(moves) != 1 || moves[0] != tc.want {
+			t.Errorf("%s -> %s: got moves %+v, want %+v", tc.old, tc.new, moves, tc.want)
+		}
+	}
+}
+
+func TestProposeMovesDifferentResources(t *testing.T) {
+	// the only removed and added resources of a type are paired even when their arguments differ,
+	// resources of different types never are
+	old := parse(t, map[string]string{"main.tf": `
+resource "azurerm_public_ip" "a" {
+  name = "a"
+  sku  = "Basic"
+}
+
+resource "azurerm_route_table" "a" {
+  name = "a"
+}
+`})
+	new := parse(t, map[string]string{"main.tf": `
+resource "azurerm_public_ip" "b" {
+  name  = "b"
+  zones = ["1"]
+}
+
+resource "azurerm_network_security_group" "a" {
+  name = "a"
+}
+`})
+	moves := ProposeMoves(old, new)
+	if len(moves) != 1 || moves[0].From != "azurerm_public_ip.a" || moves[0].To != "azurerm_public_ip.b" {
+		t.Errorf("unexpected moves: %v", moves)
+	}
+}
+
+func TestFormatMoves(t *testing.T) {
+	moves := []Move{
+		{From: `azurerm_storage_share.this[0]`, To: `azurerm_storage_share.this["bootstrap"]`, Reason: "count to for_each"},
+		{From: "azurerm_lb_rule.this", To: "azurerm_lb_rule.this", Reason: "for_each changed", Manual: "move instances"},
+		{From: "azurerm_public_ip.this", To: `azurerm_public_ip.this["KEY"]`, Reason: "a single instance to for_each",
+			Edit: `replace "KEY" with the key the instance has in for_each`},
+	}
+
+	blocks := `# count to for_each
+moved {
+  from = azurerm_storage_share.this[0]
+  to   = azurerm_storage_share.this["bootstrap"]
+}
+
+# TODO: azurerm_lb_rule.this -> azurerm_lb_rule.this (for_each changed): move instances
+
+# a single instance to for_each
+# TODO: replace "KEY" with the key the instance has in for_each
+moved {
+  from = azurerm_public_ip.this
+  to   = azurerm_public_ip.this["KEY"]
+}
+`
+	if got := FormatMovedBlocks(moves); got != blocks {
+		t.Errorf("got moved blocks:\n%s\nwant:\n%s", got, blocks)
+	}
+
+	script := FormatStateMv(moves)
+	for _, line := range []string{
+		`terraform state mv "$MODULE"'.azurerm_storage_share.this[0]' "$MODULE"'.azurerm_storage_share.this["bootstrap"]'`,
+		`# TODO: azurerm_lb_rule.this -> azurerm_lb_rule.this (for_each changed): move instances`,
+		`# TODO: replace "KEY" with the key the instance has in for_each` + "\n" +
+			`terraform state mv "$MODULE"'.azurerm_public_ip.this' "$MODULE"'.azurerm_public_ip.this["KEY"]'`,
+	} {
+		if !strings.Contains(script, line+"\n") {
+			t.Errorf("script does not contain %s:\n%s", line, script)
+		}
+	}
+}
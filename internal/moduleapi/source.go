@@ -0,0 +1,96 @@
+package moduleapi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ReadRevision parses modules kept in dir (i.e. `modules`) of the git repository in repo at the given revision.
+// Files are named by their path in the repository, so that the same file has the same name in every revision.
+func ReadRevision(repo, rev, dir string) (map[string]*Module, error) {
+	out, err := git(repo, "ls-tree", "-r", "--name-only", rev, "--", dir+"/")
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]map[string][]byte{}
+	for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		module, ok := moduleOf(dir, name)
+		if !ok {
+			continue
+		}
+		src, err := git(repo, "show", rev+":"+name)
+		if err != nil {
+			return nil, err
+		}
+		if sources[module] == nil {
+			sources[module] = map[string][]byte{}
+		}
+		sources[module][name] = src
+	}
+	return parseAll(sources)
+}
+
+// ReadDir parses modules kept in dir of the working tree in root.
+func ReadDir(root, dir string) (map[string]*Module, error) {
+	paths, err := filepath.Glob(filepath.Join(root, dir, "*", "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]map[string][]byte{}
+	for _, p := range paths {
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil, err
+		}
+		name = filepath.ToSlash(name)
+		module, _ := moduleOf(dir, name)
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if sources[module] == nil {
+			sources[module] = map[string][]byte{}
+		}
+		sources[module][name] = src
+	}
+	return parseAll(sources)
+}
+
+// moduleOf returns the module of a file when the file is a `.tf` file directly in a module's directory.
+func moduleOf(dir, name string) (string, bool) {
+	rel := strings.TrimPrefix(name, dir+"/")
+	module, file := path.Split(rel)
+	module = strings.TrimSuffix(module, "/")
+	if module == "" || strings.Contains(module, "/") || path.Ext(file) != ".tf" {
+		return "", false
+	}
+	return module, true
+}
+
+func parseAll(sources map[string]map[string][]byte) (map[string]*Module, error) {
+	modules := map[string]*Module{}
+	for name, files := range sources {
+		m, err := Parse(files)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", name, err)
+		}
+		modules[name] = m
+	}
+	return modules, nil
+}
+
+func git(repo string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
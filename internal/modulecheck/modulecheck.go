@@ -0,0 +1,286 @@
+// Package modulecheck verifies calls of local modules against the modules' variables and outputs, without Terraform.
+//
+// Examples call modules with `source = "../../modules/<name>"`. When a variable or an output of a module is renamed
+// or removed, examples drift silently until they are planned. The checker parses the root module and every local
+// module it calls, and reports arguments that are not variables of the called module, required variables that are
+// not set and `module.<name>.<output>` references to outputs the module does not declare.
+package modulecheck
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/varcheck"
+)
+
+// metaArguments are arguments of a module call handled by Terraform, not passed to the module.
+var metaArguments = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"providers":  true,
+	"depends_on": true,
+}
+
+// Module is the interface of a module: its variables and outputs.
+type Module struct {
+	Variables map[string]*varcheck.Variable
+	Outputs   map[string]bool
+}
+
+// LoadModule reads variables and outputs declared in `.tf` files of the module in dir.
+func LoadModule(dir string) (*Module, error) {
+	vars, err := varcheck.LoadModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, diags := parseDir(dir)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	m := &Module{Variables: vars, Outputs: map[string]bool{}}
+	for _, body := range files {
+		for _, block := range body.Blocks {
+			if block.Type == "output" && len(block.Labels) == 1 {
+				m.Outputs[block.Labels[0]] = true
+			}
+		}
+	}
+	return m, nil
+}
+
+// call is a module block calling a local module.
+type call struct {
+	block  *hclsyntax.Block
+	source string
+	module *Module
+}
+
+// Check verifies calls of local modules in the root module in dir. Calls of modules from a registry are skipped.
+func Check(dir string) ([]varcheck.Problem, error) {
+	files, diags := parseDir(dir)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	c := &checker{calls: map[string]*call{}}
+	loaded := map[string]*Module{}
+	for _, body := range files {
+		for _, block := range body.Blocks {
+			if block.Type != "module" || len(block.Labels) != 1 {
+				continue
+			}
+			source, ok := localSource(block)
+			if !ok {
+				c.calls[block.Labels[0]] = &call{block: block}
+				continue
+			}
+			path := filepath.Join(dir, source)
+			m, ok := loaded[path]
+			if !ok {
+				var err error
+				if m, err = LoadModule(path); err != nil {
+					return nil, fmt.Errorf("module %s: %w", block.Labels[0], err)
+				}
+				loaded[path] = m
+			}
+			c.calls[block.Labels[0]] = &call{block: block, source: source, module: m}
+		}
+	}
+
+	for _, name := range sortedCalls(c.calls) {
+		if cl := c.calls[name]; cl.module != nil {
+			c.arguments(name, cl)
+		}
+	}
+	for _, body := range files {
+		hclsyntax.VisitAll(body, func(n hclsyntax.Node) hcl.Diagnostics {
+			c.visit(n)
+			return nil
+		})
+	}
+
+	varcheck.SortProblems(c.problems)
+	return c.problems, nil
+}
+
+// parseDir parses all `.tf` files in dir.
+func parseDir(dir string) ([]*hclsyntax.Body, hcl.Diagnostics) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil || len(paths) == 0 {
+		return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: fmt.Sprintf("no Terraform files found in %s", dir)}}
+	}
+	parser := hclparse.NewParser()
+	var bodies []*hclsyntax.Body
+	var diags hcl.Diagnostics
+	for _, path := range paths {
+		file, fileDiags := parser.ParseHCLFile(path)
+		diags = append(diags, fileDiags...)
+		if !fileDiags.HasErrors() {
+			bodies = append(bodies, file.Body.(*hclsyntax.Body))
+		}
+	}
+	return bodies, diags
+}
+
+// localSource returns the source of a module call when it is a local path.
+func localSource(block *hclsyntax.Block) (string, bool) {
+	attr, ok := block.Body.Attributes["source"]
+	if !ok {
+		return "", false
+	}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || val.IsNull() {
+		return "", false
+	}
+	source := val.AsString()
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return "", false
+	}
+	return source, true
+}
+
+type checker struct {
+	calls    map[string]*call
+	problems []varcheck.Problem
+}
+
+func (c *checker) report(rng hcl.Range, path, format string, args ...interface{}) {
+	c.problems = append(c.problems, varcheck.Problem{Range: rng, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// arguments verifies arguments of a module call against the variables of the called module.
+func (c *checker) arguments(name string, cl *call) {
+	path := "module." + name
+	for _, attrName := range sortedAttributes(cl.block.Body.Attributes) {
+		attr := cl.block.Body.Attributes[attrName]
+		if metaArguments[attrName] {
+			continue
+		}
+		if _, ok := cl.module.Variables[attrName]; !ok {
+			c.report(attr.NameRange, path, "unsupported argument %q, %s declares no such variable", attrName, cl.source)
+		}
+	}
+	for _, block := range cl.block.Body.Blocks {
+		c.report(block.TypeRange, path, "unsupported block %q in a module call", block.Type)
+	}
+	for _, varName := range sortedVariables(cl.module.Variables) {
+		if _, ok := cl.block.Body.Attributes[varName]; !ok && cl.module.Variables[varName].Required {
+			c.report(cl.block.DefRange(), path, "required variable %q of %s is not set", varName, cl.source)
+		}
+	}
+}
+
+// visit verifies `module.<name>.<output>` references. Outputs of a module called with `count` or `for_each`
+// are referenced through an index, i.e. `module.vnet[each.value.vnet_key].subnet_ids`, or a splat.
+func (c *checker) visit(n hclsyntax.Node) {
+	switch e := n.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		if name, ok := moduleName(e.Traversal); ok {
+			if _, ok := c.calls[name]; !ok {
+				c.report(e.Traversal[1].SourceRange(), "module."+name, "no module %q is called", name)
+				return
+			}
+			c.reference(name, e.Traversal[2:])
+		}
+	case *hclsyntax.RelativeTraversalExpr:
+		if name, ok := moduleSource(e.Source); ok {
+			c.reference(name, e.Traversal)
+		}
+	case *hclsyntax.SplatExpr:
+		if each, ok := e.Each.(*hclsyntax.RelativeTraversalExpr); ok {
+			if name, ok := moduleSource(e.Source); ok {
+				c.reference(name, each.Traversal)
+			}
+		}
+	}
+}
+
+// reference verifies that the first attribute of the traversal is an output of the called module.
+// Traversals without an attribute refer to the whole module and are verified where they are used.
+func (c *checker) reference(name string, traversal hcl.Traversal) {
+	cl, ok := c.calls[name]
+	if !ok || cl.module == nil {
+		// a missing module is reported for the traversal starting with `module.<name>`
+		return
+	}
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseIndex, hcl.TraverseSplat:
+			continue
+		case hcl.TraverseAttr:
+			if !cl.module.Outputs[s.Name] {
+				c.report(s.SrcRange, "module."+name+"."+s.Name, "%s declares no output %q", cl.source, s.Name)
+			}
+		}
+		return
+	}
+}
+
+// moduleName returns the name of a module when the traversal starts with `module.<name>`.
+func moduleName(traversal hcl.Traversal) (string, bool) {
+	if len(traversal) < 2 || traversal.RootName() != "module" {
+		return "", false
+	}
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	return attr.Name, ok
+}
+
+// moduleSource returns the name of a module when the expression is `module.<name>`, possibly indexed.
+// Expressions referring to an output, i.e. `module.vnet.subnet_ids`, are verified on their own.
+func moduleSource(expr hclsyntax.Expression) (string, bool) {
+	for {
+		switch e := expr.(type) {
+		case *hclsyntax.IndexExpr:
+			expr = e.Collection
+			continue
+		case *hclsyntax.ScopeTraversalExpr:
+			name, ok := moduleName(e.Traversal)
+			if !ok {
+				return "", false
+			}
+			for _, step := range e.Traversal[2:] {
+				if _, ok := step.(hcl.TraverseAttr); ok {
+					return "", false
+				}
+			}
+			return name, true
+		}
+		return "", false
+	}
+}
+
+func sortedCalls(calls map[string]*call) []string {
+	var names []string
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedAttributes(attrs hclsyntax.Attributes) []string {
+	var names []string
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedVariables(vars map[string]*varcheck.Variable) []string {
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,64 @@
+package modulecheck
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadModule(t *testing.T) {
+	m, err := LoadModule("testdata/module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Variables) != 3 || !m.Variables["name"].Required || m.Variables["tags"].Required {
+		t.Errorf("variables not decoded: %+v", m.Variables)
+	}
+	if !m.Outputs["id"] || !m.Outputs["subnet_ids"] || len(m.Outputs) != 2 {
+		t.Errorf("outputs not decoded: %v", m.Outputs)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	problems, err := Check("testdata/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`testdata/root/main.tf:6,3: module.single: unsupported argument "tag", ../module declares no such variable`,
+		`testdata/root/main.tf:9,1: module.each: required variable "location" of ../module is not set`,
+		`testdata/root/main.tf:35,37: module.each.subnet_id: ../module declares no output "subnet_id"`,
+		`testdata/root/main.tf:36,32: module.counted.ids: ../module declares no output "ids"`,
+		`testdata/root/main.tf:37,62: module.single.name: ../module declares no output "name"`,
+		`testdata/root/main.tf:38,21: module.missing: no module "missing" is called`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestExamples(t *testing.T) {
+	examples, err := filepath.Glob("../../examples/*/main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("no examples found")
+	}
+	for _, mainTf := range examples {
+		dir := filepath.Dir(mainTf)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			problems, err := Check(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range problems {
+				t.Error(p)
+			}
+		})
+	}
+}
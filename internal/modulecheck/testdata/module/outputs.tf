@@ -0,0 +1,7 @@
+output "id" {
+  value = "id"
+}
+
+output "subnet_ids" {
+  value = {}
+}
@@ -0,0 +1,15 @@
+variable "name" {
+  description = "Name of the resource."
+  type        = string
+}
+
+variable "location" {
+  description = "Region of the resource."
+  type        = string
+}
+
+variable "tags" {
+  description = "Tags of the resource."
+  type        = map(string)
+  default     = {}
+}
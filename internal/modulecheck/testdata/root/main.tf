@@ -0,0 +1,40 @@
+module "single" {
+  source = "../module"
+
+  name     = "single"
+  location = "North Europe"
+  tag      = {}
+}
+
+module "each" {
+  source   = "../module"
+  for_each = toset(["a", "b"])
+
+  name = each.key
+}
+
+module "counted" {
+  source = "../module"
+  count  = 2
+
+  name     = "counted-${count.index}"
+  location = "North Europe"
+  tags     = { id = module.single.id }
+}
+
+module "registry" {
+  source  = "PaloAltoNetworks/vmseries-modules/azurerm//modules/vnet"
+  version = "1.0.0"
+
+  anything = module.single.subnet_ids
+}
+
+locals {
+  ids       = [for k, v in module.each : v.id]
+  subnet    = module.each["a"].subnet_ids["public"]
+  indexed   = module.each[local.key].subnet_id
+  splat     = module.counted[*].ids
+  nested    = try(module.counted[0].subnet_ids, module.single.name)
+  undefined = module.missing.id
+  registry  = module.registry.whatever
+}
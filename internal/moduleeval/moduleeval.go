@@ -0,0 +1,331 @@
+// Package moduleeval evaluates locals of a Terraform module without Terraform and without providers,
+// so that computations of a module can be covered by table-driven Go tests.
+//
+// Variables are given as cty values, they are converted to the declared types and variables not given take
+// their defaults, the way Terraform does. Attributes of resources, data sources and module calls are unknown,
+// unless their values are given as well. Locals are evaluated with the functions of the Terraform language,
+// see Functions.
+package moduleeval
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Module holds variables and locals of a module.
+type Module struct {
+	dir       string
+	variables map[string]*variable
+	locals    map[string]hcl.Expression
+	// repetitions are `count` and `for_each` expressions of resources and data sources by their addresses.
+	repetitions map[string]*hclsyntax.Attribute
+}
+
+type variable struct {
+	ty       cty.Type
+	def      *cty.Value
+	nullable bool
+}
+
+// Load reads variables and locals declared in `.tf` files of the module in dir.
+func Load(dir string) (*Module, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+
+	m := &Module{
+		dir:         abs,
+		variables:   map[string]*variable{},
+		locals:      map[string]hcl.Expression{},
+		repetitions: map[string]*hclsyntax.Attribute{},
+	}
+	parser := hclparse.NewParser()
+	var diags hcl.Diagnostics
+	for _, path := range files {
+		file, fileDiags := parser.ParseHCLFile(path)
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			switch {
+			case block.Type == "variable" && len(block.Labels) == 1:
+				v, varDiags := decodeVariable(block)
+				diags = append(diags, varDiags...)
+				m.variables[block.Labels[0]] = v
+			case (block.Type == "resource" || block.Type == "data") && len(block.Labels) == 2:
+				address := block.Labels[0] + "." + block.Labels[1]
+				if block.Type == "data" {
+					address = "data." + address
+				}
+				for _, name := range []string{"count", "for_each"} {
+					if attr, ok := block.Body.Attributes[name]; ok {
+						m.repetitions[address] = attr
+					}
+				}
+			case block.Type == "locals":
+				for name, attr := range block.Body.Attributes {
+					m.locals[name] = attr.Expr
+				}
+			}
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return m, nil
+}
+
+func decodeVariable(block *hclsyntax.Block) (*variable, hcl.Diagnostics) {
+	v := &variable{ty: cty.DynamicPseudoType, nullable: true}
+	var diags hcl.Diagnostics
+	if attr, ok := block.Body.Attributes["type"]; ok {
+		ty, tyDiags := typeexpr.TypeConstraint(attr.Expr)
+		diags = append(diags, tyDiags...)
+		if !tyDiags.HasErrors() {
+			v.ty = ty
+		}
+	}
+	if attr, ok := block.Body.Attributes["nullable"]; ok {
+		val, valDiags := attr.Expr.Value(nil)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() && val.Type() == cty.Bool && val.IsKnown() && !val.IsNull() {
+			v.nullable = val.True()
+		}
+	}
+	if attr, ok := block.Body.Attributes["default"]; ok {
+		val, valDiags := attr.Expr.Value(nil)
+		diags = append(diags, valDiags...)
+		if !valDiags.HasErrors() {
+			val, err := convert.Convert(val, v.ty)
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid default value for variable",
+					Detail:   fmt.Sprintf("This default value is not compatible with the variable's type constraint: %s.", err),
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+			v.def = &val
+		}
+	}
+	return v, diags
+}
+
+// Inputs are values a module is evaluated with.
+type Inputs struct {
+	// Variables are values of variables, they are converted to the types of the variables.
+	Variables map[string]cty.Value
+	// Resources are values of resources, data sources and module calls by their addresses, i.e. `random_pet.this`,
+	// `data.azurerm_public_ip.this` or `module.vnet`. A resource with `count` or `for_each` is a tuple or an object
+	// of its instances. Resources not given are unknown.
+	Resources map[string]cty.Value
+}
+
+// Locals evaluates all locals of the module. A local depending on unknown attributes of a resource is unknown.
+func (m *Module) Locals(in Inputs) (map[string]cty.Value, error) {
+	vars, err := m.variableValues(in.Variables)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(vars),
+			"path": cty.ObjectVal(map[string]cty.Value{
+				"module": cty.StringVal(m.dir),
+				"root":   cty.StringVal(m.dir),
+				"cwd":    cty.StringVal(m.dir),
+			}),
+			"terraform": cty.ObjectVal(map[string]cty.Value{"workspace": cty.StringVal("default")}),
+		},
+		Functions: Functions(m.dir),
+	}
+	if err := m.addResources(ctx, in.Resources); err != nil {
+		return nil, err
+	}
+
+	locals := map[string]cty.Value{}
+	pending := sortedKeys(m.locals)
+	for len(pending) > 0 {
+		var next []string
+		for _, name := range pending {
+			if !m.ready(name, locals) {
+				next = append(next, name)
+				continue
+			}
+			ctx.Variables["local"] = cty.ObjectVal(locals)
+			val, diags := m.locals[name].Value(ctx)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("local.%s: %w", name, diags)
+			}
+			locals[name] = val
+		}
+		if len(next) == len(pending) {
+			return nil, fmt.Errorf("locals %s depend on each other or on locals which are not declared", strings.Join(pending, ", "))
+		}
+		pending = next
+	}
+	return locals, nil
+}
+
+// Local evaluates a single local of the module, see Locals.
+func (m *Module) Local(name string, in Inputs) (cty.Value, error) {
+	if _, ok := m.locals[name]; !ok {
+		return cty.NilVal, fmt.Errorf("local.%s is not declared", name)
+	}
+	locals, err := m.Locals(in)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return locals[name], nil
+}
+
+// ready tells whether all locals the local depends on are already evaluated.
+func (m *Module) ready(name string, locals map[string]cty.Value) bool {
+	for _, traversal := range m.locals[name].Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		if _, ok := locals[attr.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Module) variableValues(given map[string]cty.Value) (map[string]cty.Value, error) {
+	vars := map[string]cty.Value{}
+	for _, name := range sortedKeys(given) {
+		if _, ok := m.variables[name]; !ok {
+			return nil, fmt.Errorf("var.%s is not declared", name)
+		}
+	}
+	for _, name := range sortedKeys(m.variables) {
+		v := m.variables[name]
+		val, ok := given[name]
+		if !ok || (val.IsNull() && !v.nullable) {
+			if v.def == nil {
+				return nil, fmt.Errorf("var.%s is required", name)
+			}
+			vars[name] = *v.def
+			continue
+		}
+		val, err := convert.Convert(val, v.ty)
+		if err != nil {
+			return nil, fmt.Errorf("var.%s: %w", name, err)
+		}
+		vars[name] = val
+	}
+	return vars, nil
+}
+
+// addResources adds values of resources, data sources and module calls to ctx, by their addresses.
+// Every resource referenced by a local and not given is unknown.
+func (m *Module) addResources(ctx *hcl.EvalContext, given map[string]cty.Value) error {
+	roots := map[string]map[string]cty.Value{}
+	set := func(root, name string, val cty.Value) {
+		if roots[root] == nil {
+			roots[root] = map[string]cty.Value{}
+		}
+		roots[root][name] = val
+	}
+	for _, expr := range m.locals {
+		for _, traversal := range expr.Variables() {
+			root := traversal.RootName()
+			if _, ok := ctx.Variables[root]; ok || root == "local" || len(traversal) < 2 {
+				continue
+			}
+			attr, ok := traversal[1].(hcl.TraverseAttr)
+			if !ok {
+				continue
+			}
+			if root != "data" {
+				set(root, attr.Name, m.unknownResource(ctx, root+"."+attr.Name))
+				continue
+			}
+			if len(traversal) < 3 {
+				continue
+			}
+			if name, ok := traversal[2].(hcl.TraverseAttr); ok {
+				set("data."+attr.Name, name.Name, m.unknownResource(ctx, "data."+attr.Name+"."+name.Name))
+			}
+		}
+	}
+	for _, address := range sortedKeys(given) {
+		parts := strings.Split(address, ".")
+		switch {
+		case len(parts) == 2 && ctx.Variables[parts[0]] != cty.NilVal:
+			return fmt.Errorf("%s is not an address of a resource, a data source or a module call", address)
+		case len(parts) == 2 && parts[0] != "data":
+			set(parts[0], parts[1], given[address])
+		case len(parts) == 3 && parts[0] == "data":
+			set("data."+parts[1], parts[2], given[address])
+		default:
+			return fmt.Errorf("%s is not an address of a resource, a data source or a module call", address)
+		}
+	}
+
+	data := map[string]cty.Value{}
+	for _, root := range sortedKeys(roots) {
+		if t := strings.TrimPrefix(root, "data."); t != root {
+			data[t] = cty.ObjectVal(roots[root])
+			continue
+		}
+		ctx.Variables[root] = cty.ObjectVal(roots[root])
+	}
+	if len(data) > 0 {
+		ctx.Variables["data"] = cty.ObjectVal(data)
+	}
+	return nil
+}
+
+// unknownResource is the value of a resource which is not given. Its attributes are unknown, but when `count`
+// or `for_each` depends on variables only and creates no instances, it is known to be empty, the way it is
+// during `terraform plan`. Without it, `can(random_pet.this[0].id)` would be unknown instead of false.
+func (m *Module) unknownResource(ctx *hcl.EvalContext, address string) cty.Value {
+	attr, ok := m.repetitions[address]
+	if !ok {
+		return cty.DynamicVal
+	}
+	val, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() || !val.IsWhollyKnown() || val.IsNull() {
+		return cty.DynamicVal
+	}
+	switch {
+	case attr.Name == "count" && val.Type() == cty.Number && val.RawEquals(cty.Zero):
+		return cty.EmptyTupleVal
+	case attr.Name == "for_each" && val.CanIterateElements() && val.LengthInt() == 0:
+		return cty.EmptyObjectVal
+	}
+	return cty.DynamicVal
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,280 @@
+package moduleeval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// asJSON renders a value for comparisons in tests, unknown values are rendered as `<unknown>`.
+func asJSON(t *testing.T, val cty.Value) string {
+	t.Helper()
+	if !val.IsWhollyKnown() {
+		return "<unknown>"
+	}
+	js, err := ctyjson.SimpleJSONValue{Value: val}.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(js)
+}
+
+func load(t *testing.T, dir string) *Module {
+	t.Helper()
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLocals(t *testing.T) {
+	m := load(t, "testdata/module")
+	locals, err := m.Locals(Inputs{
+		Variables: map[string]cty.Value{
+			"name":    cty.StringVal("fw"),
+			"zones":   cty.NullVal(cty.List(cty.String)),
+			"subnets": cty.ObjectVal(map[string]cty.Value{"mgmt": cty.StringVal("10.0.0.0/24")}),
+		},
+		Resources: map[string]cty.Value{
+			"random_pet.this": cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("pet")})}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"full_name": `"pet-fw"`,
+		"prefix":    `"pet-"`,
+		"zone_list": `"1,2,3"`,
+		"hosts":     `{"mgmt":"10.0.0.254"}`,
+		"location":  `<unknown>`,
+		"template":  `"name: FW\n"`,
+	}
+	for name, w := range want {
+		if got := asJSON(t, locals[name]); got != w {
+			t.Errorf("local.%s = %s, want %s", name, got, w)
+		}
+	}
+	if len(locals) != len(want) {
+		t.Errorf("got %d locals, want %d", len(locals), len(want))
+	}
+}
+
+func TestLocalsErrors(t *testing.T) {
+	m := load(t, "testdata/module")
+	for _, tc := range []struct {
+		name string
+		in   Inputs
+		want string
+	}{
+		{"required", Inputs{}, "var.name is required"},
+		{"undeclared", Inputs{Variables: map[string]cty.Value{"name": cty.StringVal("fw"), "nme": cty.StringVal("fw")}}, "var.nme is not declared"},
+		{"type", Inputs{Variables: map[string]cty.Value{"name": cty.StringVal("fw"), "zones": cty.StringVal("1")}}, "var.zones: list of string required"},
+		{"address", Inputs{Variables: map[string]cty.Value{"name": cty.StringVal("fw")}, Resources: map[string]cty.Value{"random_pet": cty.EmptyTupleVal}}, "random_pet is not an address"},
+		{"function", Inputs{Variables: map[string]cty.Value{"name": cty.StringVal("fw"), "subnets": cty.MapVal(map[string]cty.Value{"mgmt": cty.StringVal("10.0.0.0/32")})}}, "local.hosts:"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := m.Locals(tc.in)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	_, err := load(t, "testdata/cycle").Locals(Inputs{})
+	if err == nil || !strings.Contains(err.Error(), "locals a, b depend on each other") {
+		t.Errorf("got error %v, want a cycle of a and b", err)
+	}
+}
+
+func TestNameTemplater(t *testing.T) {
+	m := load(t, "../../modules/name_templater")
+	pet := map[string]cty.Value{
+		"random_pet.this": cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal("happycat")})}),
+	}
+	template := func(delimiter string, parts ...[2]string) cty.Value {
+		var vals []cty.Value
+		for _, p := range parts {
+			vals = append(vals, cty.MapVal(map[string]cty.Value{p[0]: cty.StringVal(p[1])}))
+		}
+		return cty.ObjectVal(map[string]cty.Value{"delimiter": cty.StringVal(delimiter), "parts": cty.ListVal(vals)})
+	}
+	for _, tc := range []struct {
+		name      string
+		vars      map[string]cty.Value
+		resources map[string]cty.Value
+		want      string
+	}{
+		{
+			name: "default abbreviation",
+			vars: map[string]cty.Value{
+				"resource_type": cty.StringVal("vnet"),
+				"name_prefix":   cty.StringVal("ex-"),
+				"name_template": template("-", [2]string{"prefix", ""}, [2]string{"bu", "rnd"}, [2]string{"name", "%s"}, [2]string{"abbreviation", "__default__"}),
+			},
+			want: `"ex--rnd-%s-vnet"`,
+		},
+		{
+			name: "unknown resource type",
+			vars: map[string]cty.Value{
+				"resource_type": cty.StringVal("unknown"),
+				"name_prefix":   cty.StringVal(""),
+				"name_template": template("-", [2]string{"prefix", ""}, [2]string{"name", "%s"}, [2]string{"abbreviation", "__default__"}),
+			},
+			want: `"%s"`,
+		},
+		{
+			name: "random part",
+			vars: map[string]cty.Value{
+				"resource_type": cty.StringVal("storage_account"),
+				"name_prefix":   cty.StringVal("ex"),
+				"name_template": template("", [2]string{"prefix", ""}, [2]string{"name", "%s"}, [2]string{"random", "__random__"}),
+			},
+			resources: pet,
+			want:      `"ex%shappycat"`,
+		},
+		{
+			name: "random part not created yet",
+			vars: map[string]cty.Value{
+				"resource_type": cty.StringVal("storage_account"),
+				"name_prefix":   cty.StringVal("ex"),
+				"name_template": template("", [2]string{"prefix", ""}, [2]string{"name", "%s"}, [2]string{"random", "__random__"}),
+			},
+			want: `<unknown>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := m.Local("template_trimmed", Inputs{Variables: tc.vars, Resources: tc.resources})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := asJSON(t, got); s != tc.want {
+				t.Errorf("got %s, want %s", s, tc.want)
+			}
+		})
+	}
+}
+
+func TestVMSSPeriods(t *testing.T) {
+	m := load(t, "../../modules/vmss")
+	for _, tc := range []struct {
+		minutes int64
+		want    string
+	}{
+		{0, "P"},
+		{7, "PT7M"},
+		{60, "PT1H"},
+		{61, "PT1H1M"},
+		{1440, "P1D"},
+		{1470, "P1DT30M"},
+		{2190, "P1DT12H30M"},
+	} {
+		got, err := m.Locals(Inputs{Variables: map[string]cty.Value{
+			"name":                      cty.StringVal("vmss"),
+			"location":                  cty.StringVal("westeurope"),
+			"resource_group_name":       cty.StringVal("rg"),
+			"interfaces":                cty.EmptyTupleVal,
+			"password":                  cty.StringVal("secret"),
+			"img_version":               cty.StringVal("10.2.3"),
+			"scaleout_cooldown_minutes": cty.NumberIntVal(tc.minutes),
+			"scaleout_window_minutes":   cty.NumberIntVal(tc.minutes),
+			"scalein_cooldown_minutes":  cty.NumberIntVal(tc.minutes),
+			"scalein_window_minutes":    cty.NumberIntVal(tc.minutes),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"scaleout_cooldown", "scaleout_window", "scalein_cooldown", "scalein_window"} {
+			if s := got[name].AsString(); s != tc.want {
+				t.Errorf("%d minutes: local.%s = %q, want %q", tc.minutes, name, s, tc.want)
+			}
+		}
+	}
+}
+
+func TestLoadBalancerRules(t *testing.T) {
+	m := load(t, "../../modules/loadbalancer")
+	rule := func(port int64) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"port": cty.NumberIntVal(port), "protocol": cty.StringVal("Tcp")})
+	}
+	got, err := m.Locals(Inputs{Variables: map[string]cty.Value{
+		"name":                cty.StringVal("lb"),
+		"location":            cty.StringVal("westeurope"),
+		"resource_group_name": cty.StringVal("rg"),
+		"frontend_ips": cty.ObjectVal(map[string]cty.Value{
+			"web": cty.ObjectVal(map[string]cty.Value{
+				"create_public_ip": cty.True,
+				"in_rules":         cty.ObjectVal(map[string]cty.Value{"http": rule(80), "https": rule(443)}),
+			}),
+			"egress": cty.ObjectVal(map[string]cty.Value{
+				"create_public_ip": cty.True,
+				"out_rules":        cty.ObjectVal(map[string]cty.Value{"all": cty.ObjectVal(map[string]cty.Value{"protocol": cty.StringVal("All")})}),
+			}),
+		}),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := sortedKeys(got["in_rules"].AsValueMap()); strings.Join(keys, ",") != "web-http,web-https" {
+		t.Errorf("got in_rules %v", keys)
+	}
+	if port := got["in_rules"].GetAttr("web-https").GetAttr("rule").GetAttr("port"); !port.RawEquals(cty.NumberIntVal(443)) {
+		t.Errorf("got port %#v of web-https", port)
+	}
+	if keys := sortedKeys(got["out_rules"].AsValueMap()); strings.Join(keys, ",") != "egress-all" {
+		t.Errorf("got out_rules %v", keys)
+	}
+	if !got["disable_outbound_snat"].RawEquals(cty.True) {
+		t.Errorf("got disable_outbound_snat %#v, want true with outbound rules", got["disable_outbound_snat"])
+	}
+	if got["rules_hash"].IsWhollyKnown() && got["rules_hash"].LengthInt() != 0 {
+		t.Errorf("got rules_hash %#v without an NSG", got["rules_hash"])
+	}
+}
+
+func TestAppGWRules(t *testing.T) {
+	m := load(t, "../../modules/appgw")
+	app := func(port int64, extra map[string]cty.Value) cty.Value {
+		attrs := map[string]cty.Value{
+			"priority": cty.NumberIntVal(port),
+			"listener": cty.ObjectVal(map[string]cty.Value{"port": cty.NumberIntVal(port)}),
+		}
+		for k, v := range extra {
+			attrs[k] = v
+		}
+		return cty.ObjectVal(attrs)
+	}
+	got, err := m.Locals(Inputs{Variables: map[string]cty.Value{
+		"name":                cty.StringVal("appgw"),
+		"location":            cty.StringVal("westeurope"),
+		"resource_group_name": cty.StringVal("rg"),
+		"subnet_id":           cty.StringVal("id"),
+		"rules": cty.ObjectVal(map[string]cty.Value{
+			"minimum": app(80, nil),
+			"http":    app(80, nil),
+			"tls": app(443, map[string]cty.Value{
+				"backend": cty.ObjectVal(map[string]cty.Value{
+					"root_certs": cty.ObjectVal(map[string]cty.Value{"ca": cty.StringVal("files/ca.crt")}),
+				}),
+				"url_path_maps": cty.ObjectVal(map[string]cty.Value{
+					"menu": cty.ObjectVal(map[string]cty.Value{"path": cty.StringVal("/menu/")}),
+				}),
+			}),
+		}),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"front_ports_map":        `{"443":443,"80":80}`,
+		"root_certs_map":         `{"tls-ca":"files/ca.crt"}`,
+		"url_path_maps_settings": `{"tls-menu":{"app_name":"tls","path":"/menu/","rule_name":"menu"}}`,
+	} {
+		if s := asJSON(t, got[name]); s != want {
+			t.Errorf("local.%s = %s, want %s", name, s, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+variable "name" {
+  type = string
+}
+
+variable "zones" {
+  type     = list(string)
+  default  = ["1", "2", "3"]
+  nullable = false
+}
+
+variable "subnets" {
+  type    = map(string)
+  default = {}
+}
+
+resource "random_pet" "this" {
+  count = 1
+}
+
+data "azurerm_resource_group" "this" {
+  name = var.name
+}
+
+locals {
+  # locals are declared out of order on purpose
+  full_name = "${local.prefix}${var.name}"
+  prefix    = "${random_pet.this[0].id}-"
+  zone_list = join(",", var.zones)
+  hosts     = { for k, v in var.subnets : k => cidrhost(v, -2) }
+  location  = data.azurerm_resource_group.this.location
+  template  = templatefile("${path.module}/template.tftpl", { name = var.name })
+}
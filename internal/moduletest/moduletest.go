@@ -0,0 +1,318 @@
+// Package moduletest runs Plan and Apply tests against a single module.
+//
+// Modules are not root modules, so they cannot be planned directly. For each named scenario
+// a throwaway root module is generated: it creates a Resource Group, optional setup resources
+// the module depends on (a VNET, an existing Public IP, etc.) and calls the module under test
+// with the scenario's inputs. The generated code is then tested with the example harness.
+package moduletest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/exampletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+const (
+	// ModuleName is the name of the module call in the generated root module.
+	ModuleName = "this"
+	// DefaultLocation is the Azure region scenarios are planned in.
+	DefaultLocation = "North Europe"
+
+	// ResourceGroupName is an expression referencing the name of the generated Resource Group.
+	ResourceGroupName = "azurerm_resource_group.this.name"
+	// Location is an expression referencing the location of the generated Resource Group.
+	Location = "azurerm_resource_group.this.location"
+)
+
+// Fixture describes how to call a module under test.
+type Fixture struct {
+	// ModuleDir is the path to the module under test, defaults to the current directory.
+	ModuleDir string
+	// Location is the Azure region, defaults to `North Europe`.
+	Location string
+	// Setup is HCL code shared by all scenarios, creating resources the module depends on.
+	Setup string
+	// Inputs are module arguments shared by all scenarios. Key is the argument name, value is an HCL expression.
+	Inputs map[string]string
+	// Scenarios are named sets of inputs, each one is tested separately.
+	Scenarios []Scenario
+	// Invalid are scenarios the module has to reject, each one with an ExpectedError. They are only planned.
+	Invalid []Scenario
+}
+
+// Scenario is a single, named way of calling a module.
+type Scenario struct {
+	// Name identifies the scenario, it is used as a subtest name.
+	Name string
+	// Setup is HCL code appended to the fixture's Setup.
+	Setup string
+	// Inputs are module arguments added to, or overriding, the fixture's Inputs.
+	Inputs map[string]string
+	// DependsOn are addresses of setup resources the module call depends on. Use it when
+	// the module reads, using data sources, resources created in Setup.
+	DependsOn []string
+	// PlanAssertions verify the planned resources in the Plan test.
+	PlanAssertions []planassert.Assertion
+	// Base is the name of a scenario in the fixture's Scenarios this one extends. Setup, Inputs and DependsOn
+	// of the base scenario are inherited, so that an invalid scenario can differ from a valid one by a single input.
+	Base string
+	// ExpectedError is a part of the error an invalid scenario has to fail with, usually the `error_message`
+	// of a variable validation or a resource precondition.
+	ExpectedError string
+}
+
+// String returns an HCL expression of a string literal.
+func String(s string) string {
+	return strconv.Quote(s)
+}
+
+// Address returns the address of a resource created by the module call, for use in PlanAssertions.
+func Address(resource string) string {
+	return "module." + ModuleName + "." + resource
+}
+
+// NetworkSetup returns HCL code creating a VNET with the `10.0.0.0/16` address space and
+// subnets, where key is a subnet name and value is its CIDR. Subnet IDs can be referenced with SubnetID.
+func NetworkSetup(subnets map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString(`resource "azurerm_virtual_network" "this" {
+  name                = "${var.name_prefix}vnet"
+  resource_group_name = azurerm_resource_group.this.name
+  location            = azurerm_resource_group.this.location
+  address_space       = ["10.0.0.0/16"]
+}
+
+resource "azurerm_subnet" "this" {
+  for_each = {
+`)
+	for _, name := range sortedKeys(subnets) {
+		fmt.Fprintf(&sb, "    %s = %s\n", String(name), String(subnets[name]))
+	}
+	sb.WriteString(`  }
+
+  name                 = each.key
+  resource_group_name  = azurerm_resource_group.this.name
+  virtual_network_name = azurerm_virtual_network.this.name
+  address_prefixes     = [each.value]
+}
+`)
+	return sb.String()
+}
+
+// SubnetID returns an expression referencing an ID of a subnet created with NetworkSetup.
+func SubnetID(name string) string {
+	return fmt.Sprintf("azurerm_subnet.this[%s].id", String(name))
+}
+
+// Generate writes a root module calling the module under test with the scenario's inputs
+// to a temporary directory and returns the path to that directory.
+func Generate(t *testing.T, fixture Fixture, scenario Scenario) string {
+	dir := t.TempDir()
+	moduleDir, err := filepath.Abs(orDefault(fixture.ModuleDir, "."))
+	if err != nil {
+		t.Fatalf("cannot locate module: %v", err)
+	}
+	source, err := moduleSource(dir, moduleDir)
+	if err != nil {
+		t.Fatalf("cannot locate module: %v", err)
+	}
+	scenario, err = fixture.resolve(scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := render(source, fixture, scenario)
+	if err != nil {
+		t.Fatalf("cannot generate root module for scenario %q: %v", scenario.Name, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), code, 0644); err != nil {
+		t.Fatalf("cannot write root module: %v", err)
+	}
+	tfvars := fmt.Sprintf("location = %s\n", String(orDefault(fixture.Location, DefaultLocation)))
+	if err := os.WriteFile(filepath.Join(dir, exampletest.DefaultVarFile), []byte(tfvars), 0644); err != nil {
+		t.Fatalf("cannot write variables file: %v", err)
+	}
+	return dir
+}
+
+// resolve returns the scenario merged with its base scenario.
+func (f Fixture) resolve(scenario Scenario) (Scenario, error) {
+	if scenario.Base == "" {
+		return scenario, nil
+	}
+	for _, base := range f.Scenarios {
+		if base.Name != scenario.Base {
+			continue
+		}
+		if base.Base != "" {
+			return scenario, fmt.Errorf("scenario %q extends %q, which extends another scenario", scenario.Name, base.Name)
+		}
+		inputs := map[string]string{}
+		for k, v := range base.Inputs {
+			inputs[k] = v
+		}
+		for k, v := range scenario.Inputs {
+			inputs[k] = v
+		}
+		scenario.Setup = strings.TrimSpace(base.Setup) + "\n\n" + strings.TrimSpace(scenario.Setup)
+		scenario.Inputs = inputs
+		scenario.DependsOn = append(append([]string{}, base.DependsOn...), scenario.DependsOn...)
+		scenario.Base = ""
+		return scenario, nil
+	}
+	return scenario, fmt.Errorf("scenario %q extends an unknown scenario %q", scenario.Name, scenario.Base)
+}
+
+// moduleSource returns a local module source of the module as seen from the root module's directory.
+// Terraform recognizes local paths only when they start with `./` or `../`.
+func moduleSource(rootDir, moduleDir string) (string, error) {
+	rel, err := filepath.Rel(rootDir, moduleDir)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel, nil
+}
+
+// render returns formatted code of the root module. The code is parsed before it is returned,
+// so that a typo in a scenario is reported before Terraform is run.
+func render(source string, fixture Fixture, scenario Scenario) ([]byte, error) {
+	inputs := map[string]string{}
+	for k, v := range fixture.Inputs {
+		inputs[k] = v
+	}
+	for k, v := range scenario.Inputs {
+		inputs[k] = v
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`# Code generated by internal/moduletest. DO NOT EDIT.
+
+provider "azurerm" {
+  features {}
+}
+
+variable "name_prefix" {
+  type = string
+}
+
+variable "resource_group_name" {
+  type = string
+}
+
+variable "location" {
+  type = string
+}
+
+resource "azurerm_resource_group" "this" {
+  name     = "${var.name_prefix}${var.resource_group_name}"
+  location = var.location
+}
+`)
+	for _, setup := range []string{fixture.Setup, scenario.Setup} {
+		if strings.TrimSpace(setup) != "" {
+			sb.WriteString("\n")
+			sb.WriteString(strings.TrimSpace(setup))
+			sb.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nmodule %s {\n  source = %s\n\n", String(ModuleName), String(source))
+	for _, name := range sortedKeys(inputs) {
+		fmt.Fprintf(&sb, "  %s = %s\n", name, inputs[name])
+	}
+	if len(scenario.DependsOn) > 0 {
+		fmt.Fprintf(&sb, "\n  depends_on = [%s]\n", strings.Join(scenario.DependsOn, ", "))
+	}
+	sb.WriteString("}\n")
+
+	code := []byte(sb.String())
+	if _, diags := hclparse.NewParser().ParseHCL(code, "main.tf"); diags.HasErrors() {
+		return nil, diagnosticsError(diags)
+	}
+	return hclwrite.Format(code), nil
+}
+
+func diagnosticsError(diags hcl.Diagnostics) error {
+	var msgs []string
+	for _, d := range diags {
+		if d.Severity == hcl.DiagError {
+			msgs = append(msgs, d.Error())
+		}
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "; "))
+}
+
+// Plan plans every scenario of the fixture in a separate subtest.
+func Plan(t *testing.T, fixture Fixture) {
+	for _, scenario := range fixture.Scenarios {
+		scenario := scenario
+		t.Run(scenario.Name, func(t *testing.T) {
+			// every scenario has its own root module, they can be planned concurrently
+			t.Parallel()
+			dir := Generate(t, fixture, scenario)
+			exampletest.Plan(t, exampletest.Spec{
+				TerraformDir:   dir,
+				InPlace:        true,
+				PlanAssertions: scenario.PlanAssertions,
+			})
+		})
+	}
+}
+
+// PlanInvalid plans every invalid scenario of the fixture in a separate subtest
+// and verifies that planning fails with the scenario's ExpectedError.
+func PlanInvalid(t *testing.T, fixture Fixture) {
+	for _, scenario := range fixture.Invalid {
+		scenario := scenario
+		t.Run(scenario.Name, func(t *testing.T) {
+			if scenario.ExpectedError == "" {
+				t.Fatalf("invalid scenario %q does not define ExpectedError", scenario.Name)
+			}
+			t.Parallel()
+			dir := Generate(t, fixture, scenario)
+			exampletest.PlanFailure(t, exampletest.Spec{TerraformDir: dir, InPlace: true}, scenario.ExpectedError)
+		})
+	}
+}
+
+// Apply deploys every scenario of the fixture in a separate subtest.
+func Apply(t *testing.T, fixture Fixture) {
+	for _, scenario := range fixture.Scenarios {
+		scenario := scenario
+		t.Run(scenario.Name, func(t *testing.T) {
+			dir := Generate(t, fixture, scenario)
+			exampletest.Apply(t, exampletest.Spec{TerraformDir: dir, InPlace: true})
+		})
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,154 @@
+package moduletest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func TestModuleSource(t *testing.T) {
+	cases := []struct {
+		root, module, want string
+	}{
+		{"/tmp/test/001", "/src/modules/natgw", "../../../src/modules/natgw"},
+		{"/src", "/src/modules/natgw", "./modules/natgw"},
+	}
+	for _, c := range cases {
+		got, err := moduleSource(c.root, c.module)
+		if err != nil || got != c.want {
+			t.Errorf("moduleSource(%q, %q) = %q, %v, want %q", c.root, c.module, got, err, c.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	fixture := Fixture{
+		Setup: NetworkSetup(map[string]string{"natgw": "10.0.1.0/24"}),
+		Inputs: map[string]string{
+			"name":                `"${var.name_prefix}natgw"`,
+			"resource_group_name": ResourceGroupName,
+			"location":            Location,
+			"subnet_ids":          `{ natgw = ` + SubnetID("natgw") + ` }`,
+		},
+	}
+	scenario := Scenario{
+		Name: "existing_natgw",
+		Setup: `resource "azurerm_nat_gateway" "existing" {
+  name = "${var.name_prefix}natgw"
+  resource_group_name = azurerm_resource_group.this.name
+  location = azurerm_resource_group.this.location
+}`,
+		Inputs:    map[string]string{"create_natgw": "false"},
+		DependsOn: []string{"azurerm_nat_gateway.existing"},
+	}
+
+	code, err := render("../modules/natgw", fixture, scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(code, "main.tf")
+	if diags.HasErrors() {
+		t.Fatalf("generated code does not parse: %v", diags)
+	}
+	var types []string
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		types = append(types, block.Type+" "+strings.Join(block.Labels, "."))
+	}
+	want := []string{
+		"provider azurerm",
+		"variable name_prefix",
+		"variable resource_group_name",
+		"variable location",
+		"resource azurerm_resource_group.this",
+		"resource azurerm_virtual_network.this",
+		"resource azurerm_subnet.this",
+		"resource azurerm_nat_gateway.existing",
+		"module this",
+	}
+	if strings.Join(types, ", ") != strings.Join(want, ", ") {
+		t.Errorf("got blocks %v, want %v", types, want)
+	}
+
+	for _, line := range []string{
+		`  source = "../modules/natgw"`,
+		`  create_natgw        = false`,
+		`  subnet_ids          = { natgw = azurerm_subnet.this["natgw"].id }`,
+		`  depends_on = [azurerm_nat_gateway.existing]`,
+		`  name                = "${var.name_prefix}natgw"`,
+	} {
+		if !strings.Contains(string(code), line+"\n") {
+			t.Errorf("generated code does not contain %q:\n%s", line, code)
+		}
+	}
+}
+
+func TestRenderInvalidInput(t *testing.T) {
+	fixture := Fixture{Inputs: map[string]string{"name": `"unterminated`}}
+	if _, err := render("./natgw", fixture, Scenario{Name: "invalid"}); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	fixture := Fixture{Location: "West Europe"}
+	dir := Generate(t, fixture, Scenario{Name: "default"})
+
+	tfvars, err := os.ReadFile(filepath.Join(dir, "example.tfvars"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(tfvars) != "location = \"West Europe\"\n" {
+		t.Errorf("unexpected variables file: %s", tfvars)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.tf")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	fixture := Fixture{
+		Scenarios: []Scenario{
+			{
+				Name:      "active_standby",
+				Setup:     `resource "azurerm_public_ip" "existing" {}`,
+				Inputs:    map[string]string{"sku": `"VpnGw1"`, "generation": `"Generation1"`},
+				DependsOn: []string{"azurerm_public_ip.existing"},
+			},
+			{Name: "nested", Base: "active_standby"},
+		},
+	}
+
+	got, err := fixture.resolve(Scenario{
+		Name:          "generation2_on_small_sku",
+		Base:          "active_standby",
+		Inputs:        map[string]string{"generation": `"Generation2"`},
+		ExpectedError: "Generation2 is only value",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Inputs["sku"] != `"VpnGw1"` || got.Inputs["generation"] != `"Generation2"` {
+		t.Errorf("inputs are not merged with the base scenario: %v", got.Inputs)
+	}
+	if !strings.Contains(got.Setup, "azurerm_public_ip") || len(got.DependsOn) != 1 {
+		t.Errorf("setup is not inherited from the base scenario: %q, %v", got.Setup, got.DependsOn)
+	}
+	if got.Name != "generation2_on_small_sku" || got.ExpectedError == "" {
+		t.Errorf("own fields of the scenario are lost: %+v", got)
+	}
+	if len(fixture.Scenarios[0].Inputs) != 2 {
+		t.Errorf("base scenario is modified: %v", fixture.Scenarios[0].Inputs)
+	}
+
+	if _, err := fixture.resolve(Scenario{Name: "x", Base: "unknown"}); err == nil {
+		t.Error("expected an error for an unknown base scenario")
+	}
+	if _, err := fixture.resolve(Scenario{Name: "x", Base: "nested"}); err == nil {
+		t.Error("expected an error for a base scenario extending another one")
+	}
+}
@@ -0,0 +1,204 @@
+// Package nametemplate is a reference implementation of the `name_templater` module, used to test the module
+// and to lint name templates before they are planned.
+//
+// A template is a list of parts glued with a delimiter. The value of a `prefix` part is replaced with the name
+// prefix, `__default__` with the abbreviation of the resource type and `__random__` with a random pet name.
+// The result is trimmed of the delimiter and is used with `format()`, so it has to contain a single `%s`.
+package nametemplate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+const (
+	// Default is replaced with the abbreviation of the resource type.
+	Default = "__default__"
+	// Random is replaced with a random pet name, the module creates one only when a part is exactly `__random__`.
+	Random = "__random__"
+)
+
+// Template is the `name_template` variable of the module.
+type Template struct {
+	Delimiter string
+	// Parts are maps, usually with a single key naming the part. Values of a part are taken in the order of keys.
+	Parts []map[string]string
+}
+
+// Inputs are the remaining variables of the module, together with the random pet name, which is known only
+// after the module is applied.
+type Inputs struct {
+	NamePrefix   string
+	ResourceType string
+	// Abbreviations default to DefaultAbbreviations when nil.
+	Abbreviations map[string]string
+	// RandomPet is the id of the `random_pet` resource.
+	RandomPet string
+}
+
+// DefaultAbbreviations is the default value of the `abbreviations` variable.
+var DefaultAbbreviations = map[string]string{
+	"vnet":                      "vnet",
+	"subnet":                    "snet",
+	"service_endpoint":          "se",
+	"vnet_peering":              "peer",
+	"virtual_network_gateway":   "vgw",
+	"nsg":                       "nsg",
+	"nsg_rule":                  "nsgsr",
+	"route_table":               "rt",
+	"udr":                       "udr",
+	"public_ip":                 "pip",
+	"public_ip_prefix":          "ippre",
+	"nat_gw":                    "ng",
+	"load_balancer":             "lb",
+	"application_gw":            "agw",
+	"storage_account":           "st",
+	"file_share":                "share",
+	"application_insights":      "appi",
+	"log_analytics_workspace":   "log",
+	"network_interface":         "nic",
+	"availability_set":          "avail",
+	"os_disk":                   "osdisk",
+	"data_disk":                 "disk",
+	"virtual_machine":           "vm",
+	"virtual_machine_scale_set": "vmss",
+	"resource_group":            "rg",
+	"bastion":                   "bas",
+	"managed_identity":          "id",
+}
+
+// values returns values of all parts in order, with the prefix placeholder replaced.
+func (t Template) values(prefix string) []string {
+	var values []string
+	for _, part := range t.Parts {
+		keys := make([]string, 0, len(part))
+		for k := range part {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k == "prefix" {
+				values = append(values, prefix)
+				continue
+			}
+			values = append(values, part[k])
+		}
+	}
+	return values
+}
+
+// usesRandom tells whether the module creates the `random_pet` resource for the template.
+func (t Template) usesRandom() bool {
+	for _, v := range t.values("") {
+		if v == Random {
+			return true
+		}
+	}
+	return false
+}
+
+func (in Inputs) abbreviations() map[string]string {
+	if in.Abbreviations == nil {
+		return DefaultAbbreviations
+	}
+	return in.Abbreviations
+}
+
+// Render returns the template the module outputs.
+func Render(t Template, in Inputs) string {
+	s := strings.Join(t.values(in.NamePrefix), t.Delimiter)
+	s = strings.ReplaceAll(s, Default, in.abbreviations()[in.ResourceType])
+	if t.usesRandom() {
+		s = strings.ReplaceAll(s, Random, in.RandomPet)
+	}
+	// trim() takes the delimiter as a set of characters, not as a string
+	return strings.Trim(s, t.Delimiter)
+}
+
+// Name returns the name of a resource, the way `format(template, name)` does.
+func Name(t Template, in Inputs, name string) string {
+	return strings.Replace(Render(t, in), "%s", name, 1)
+}
+
+// Lint returns problems of a template: no `%s` or more than one, an abbreviation of an unknown resource type,
+// a `__random__` the module does not replace and empty parts producing doubled delimiters.
+func Lint(t Template, in Inputs) []string {
+	var problems []string
+	values := t.values(in.NamePrefix)
+	rendered := Render(t, Inputs{
+		NamePrefix:    in.NamePrefix,
+		ResourceType:  in.ResourceType,
+		Abbreviations: in.Abbreviations,
+		RandomPet:     "pet",
+	})
+
+	switch n := strings.Count(rendered, "%s"); {
+	case n == 0:
+		problems = append(problems, "no part is %s, format() would ignore the name of the resource")
+	case n > 1:
+		problems = append(problems, fmt.Sprintf("%d parts contain %%s, format() requires as many names", n))
+	}
+	if strings.Contains(strings.ReplaceAll(strings.ReplaceAll(rendered, "%%", ""), "%s", ""), "%") {
+		problems = append(problems, "a part contains %, format() treats it as a verb, use %% for a literal %")
+	}
+
+	if strings.Contains(strings.Join(values, ""), Default) {
+		if _, ok := in.abbreviations()[in.ResourceType]; !ok {
+			problems = append(problems, fmt.Sprintf("resource type %q has no abbreviation, %s would be removed", in.ResourceType, Default))
+		}
+	}
+	if !t.usesRandom() && strings.Contains(strings.Join(values, ""), Random) {
+		problems = append(problems, fmt.Sprintf("%s is replaced only when it is a whole part, it would be kept as is", Random))
+	}
+
+	if t.Delimiter != "" {
+		for i, v := range values {
+			switch {
+			case v == "" && i > 0 && i < len(values)-1:
+				problems = append(problems, fmt.Sprintf("part %d is empty, the delimiter %q would be doubled", i+1, t.Delimiter))
+			case v != "" && i < len(values)-1 && strings.HasSuffix(v, t.Delimiter):
+				problems = append(problems, fmt.Sprintf("part %d %q ends with the delimiter %q, it would be doubled", i+1, v, t.Delimiter))
+			}
+		}
+	}
+	return problems
+}
+
+// FromValue decodes a template from a value of the `name_template` variable.
+func FromValue(val cty.Value) (Template, error) {
+	val, err := convert.Convert(val, cty.Object(map[string]cty.Type{
+		"delimiter": cty.String,
+		"parts":     cty.List(cty.Map(cty.String)),
+	}))
+	if err != nil {
+		return Template{}, err
+	}
+	if !val.IsWhollyKnown() || val.IsNull() {
+		return Template{}, fmt.Errorf("template is not known")
+	}
+	var t Template
+	if d := val.GetAttr("delimiter"); !d.IsNull() {
+		t.Delimiter = d.AsString()
+	}
+	if parts := val.GetAttr("parts"); !parts.IsNull() {
+		for it := parts.ElementIterator(); it.Next(); {
+			_, part := it.Element()
+			p := map[string]string{}
+			if !part.IsNull() {
+				for k, v := range part.AsValueMap() {
+					if !v.IsNull() {
+						p[k] = v.AsString()
+					} else {
+						p[k] = ""
+					}
+				}
+			}
+			t.Parts = append(t.Parts, p)
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,258 @@
+package nametemplate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduleeval"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const module = "../../modules/name_templater"
+
+func parts(pairs ...string) []map[string]string {
+	var p []map[string]string
+	for i := 0; i < len(pairs); i += 2 {
+		p = append(p, map[string]string{pairs[i]: pairs[i+1]})
+	}
+	return p
+}
+
+var cases = []struct {
+	name     string
+	template Template
+	in       Inputs
+	want     string
+}{
+	{
+		name:     "readme",
+		template: Template{Delimiter: "-", Parts: parts("prefix", "", "bu", "rnd", "randomize", Random, "env", "prd", "name", "%s", "abbreviation", Default)},
+		in:       Inputs{NamePrefix: "a_prefix", ResourceType: "vnet", RandomPet: "crediblefrog"},
+		want:     "a_prefix-rnd-crediblefrog-prd-%s-vnet",
+	},
+	{
+		name:     "storage without delimiter",
+		template: Template{Delimiter: "", Parts: parts("prefix", "", "org", "palo", "env", "prd", "name", "%s")},
+		in:       Inputs{NamePrefix: "ex", ResourceType: "storage_account"},
+		want:     "expaloprd%s",
+	},
+	{
+		name:     "empty prefix trimmed",
+		template: Template{Delimiter: "-", Parts: parts("prefix", "", "name", "%s", "abbreviation", Default)},
+		in:       Inputs{ResourceType: "nsg"},
+		want:     "%s-nsg",
+	},
+	{
+		name:     "unknown resource type trimmed",
+		template: Template{Delimiter: "-", Parts: parts("name", "%s", "abbreviation", Default)},
+		in:       Inputs{ResourceType: "firewall"},
+		want:     "%s",
+	},
+	{
+		name:     "custom abbreviations",
+		template: Template{Delimiter: "_", Parts: parts("abbreviation", Default, "name", "%s")},
+		in:       Inputs{ResourceType: "firewall", Abbreviations: map[string]string{"firewall": "fw"}},
+		want:     "fw_%s",
+	},
+	{
+		name:     "prefix key replaced whatever its value",
+		template: Template{Delimiter: "-", Parts: parts("prefix", "ignored", "name", "%s")},
+		in:       Inputs{NamePrefix: "ex"},
+		want:     "ex-%s",
+	},
+	{
+		name:     "random inside a part kept",
+		template: Template{Delimiter: "-", Parts: parts("name", "%s", "suffix", "x"+Random)},
+		in:       Inputs{RandomPet: "pet"},
+		want:     "%s-x__random__",
+	},
+	{
+		name:     "random replaced everywhere",
+		template: Template{Delimiter: "-", Parts: parts("random", Random, "name", "%s", "suffix", "x"+Random)},
+		in:       Inputs{RandomPet: "pet"},
+		want:     "pet-%s-xpet",
+	},
+	{
+		name:     "delimiter trimmed as a set of characters",
+		template: Template{Delimiter: "-_", Parts: parts("prefix", "", "name", "%s_", "env", "_prd")},
+		in:       Inputs{NamePrefix: "_ex"},
+		want:     "ex-_%s_-__prd",
+	},
+	{
+		name:     "multiple keys in a part in the order of keys",
+		template: Template{Delimiter: "-", Parts: []map[string]string{{"z": "b", "a": "a"}, {"name": "%s"}}},
+		in:       Inputs{},
+		want:     "a-b-%s",
+	},
+}
+
+func TestRender(t *testing.T) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Render(tc.template, tc.in); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestModule cross-checks Render with the module, every case is evaluated by both.
+func TestModule(t *testing.T) {
+	m, err := moduleeval.Load(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ps []cty.Value
+			// like the module, random_pet is created only when a part is __random__
+			var resources map[string]cty.Value
+			for _, p := range tc.template.Parts {
+				vals := map[string]cty.Value{}
+				for k, v := range p {
+					vals[k] = cty.StringVal(v)
+					if v == Random {
+						resources = map[string]cty.Value{
+							"random_pet.this": cty.TupleVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"id": cty.StringVal(tc.in.RandomPet)})}),
+						}
+					}
+				}
+				ps = append(ps, cty.MapVal(vals))
+			}
+			vars := map[string]cty.Value{
+				"resource_type": cty.StringVal(tc.in.ResourceType),
+				"name_prefix":   cty.StringVal(tc.in.NamePrefix),
+				"name_template": cty.ObjectVal(map[string]cty.Value{
+					"delimiter": cty.StringVal(tc.template.Delimiter),
+					"parts":     cty.ListVal(ps),
+				}),
+			}
+			if tc.in.Abbreviations != nil {
+				abbreviations := map[string]cty.Value{}
+				for k, v := range tc.in.Abbreviations {
+					abbreviations[k] = cty.StringVal(v)
+				}
+				vars["abbreviations"] = cty.MapVal(abbreviations)
+			}
+			got, err := m.Local("template_trimmed", moduleeval.Inputs{
+				Variables: vars,
+				Resources: resources,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AsString() != Render(tc.template, tc.in) {
+				t.Errorf("module renders %q, Render %q", got.AsString(), Render(tc.template, tc.in))
+			}
+		})
+	}
+}
+
+func TestDefaultAbbreviations(t *testing.T) {
+	file, diags := hclparse.NewParser().ParseHCLFile(module + "/variables.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "variable" || block.Labels[0] != "abbreviations" {
+			continue
+		}
+		val, diags := block.Body.Attributes["default"].Expr.Value(nil)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		got := map[string]string{}
+		for k, v := range val.AsValueMap() {
+			got[k] = v.AsString()
+		}
+		if !reflect.DeepEqual(got, DefaultAbbreviations) {
+			t.Errorf("default of var.abbreviations:\n%v\ndiffers from DefaultAbbreviations:\n%v", got, DefaultAbbreviations)
+		}
+		return
+	}
+	t.Fatal("var.abbreviations not found")
+}
+
+func TestLint(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		template Template
+		in       Inputs
+		want     []string
+	}{
+		{
+			name:     "valid",
+			template: Template{Delimiter: "-", Parts: parts("prefix", "", "env", "prd", "name", "%s", "abbreviation", Default)},
+			in:       Inputs{NamePrefix: "ex", ResourceType: "vnet"},
+		},
+		{
+			name:     "empty prefix first",
+			template: Template{Delimiter: "-", Parts: parts("prefix", "", "name", "%s")},
+		},
+		{
+			name:     "no name",
+			template: Template{Delimiter: "-", Parts: parts("prefix", "", "env", "prd")},
+			in:       Inputs{NamePrefix: "ex"},
+			want:     []string{"no part is %s, format() would ignore the name of the resource"},
+		},
+		{
+			name:     "two names",
+			template: Template{Delimiter: "-", Parts: parts("name", "%s", "again", "%s")},
+			want:     []string{"2 parts contain %s, format() requires as many names"},
+		},
+		{
+			name:     "verb",
+			template: Template{Delimiter: "-", Parts: parts("name", "%s", "pct", "100%", "literal", "100%%")},
+			want:     []string{"a part contains %, format() treats it as a verb, use %% for a literal %"},
+		},
+		{
+			name:     "unknown resource type",
+			template: Template{Delimiter: "-", Parts: parts("name", "%s", "abbreviation", Default)},
+			in:       Inputs{ResourceType: "firewall"},
+			want:     []string{`resource type "firewall" has no abbreviation, __default__ would be removed`},
+		},
+		{
+			name:     "random inside a part",
+			template: Template{Delimiter: "-", Parts: parts("name", "%s", "suffix", "x"+Random)},
+			want:     []string{"__random__ is replaced only when it is a whole part, it would be kept as is"},
+		},
+		{
+			name:     "double delimiters",
+			template: Template{Delimiter: "-", Parts: parts("prefix", "", "env", "", "name", "%s")},
+			in:       Inputs{NamePrefix: "ex-"},
+			want: []string{
+				`part 1 "ex-" ends with the delimiter "-", it would be doubled`,
+				`part 2 is empty, the delimiter "-" would be doubled`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Lint(tc.template, tc.in)
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestFromValue(t *testing.T) {
+	tmpl, err := FromValue(cty.ObjectVal(map[string]cty.Value{
+		"delimiter": cty.StringVal("-"),
+		"parts": cty.TupleVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"prefix": cty.NullVal(cty.String)}),
+			cty.ObjectVal(map[string]cty.Value{"name": cty.StringVal("%s")}),
+		}),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Name(tmpl, Inputs{NamePrefix: "ex"}, "transit"); got != "ex-transit" {
+		t.Errorf("got name %q", got)
+	}
+	if _, err := FromValue(cty.ObjectVal(map[string]cty.Value{"parts": cty.EmptyTupleVal})); err == nil {
+		t.Error("template without a delimiter decoded")
+	}
+}
@@ -0,0 +1,254 @@
+package panosconfig
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// Problem is a broken reference, or a name defined twice.
+type Problem struct {
+	// Path names the element with the problem, i.e. `virtual-router "private" static-route "internet"`.
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// iface is a defined interface.
+type iface struct {
+	// mode is layer3, layer2, virtual-wire, tap, or empty for an ethernet interface without a mode.
+	mode    string
+	profile string
+}
+
+type checker struct {
+	problems []Problem
+}
+
+func (c *checker) report(path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// unique reports names defined more than once.
+func (c *checker) unique(path, kind string, names []string) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			c.report(path, "%s %q is defined more than once", kind, name)
+		}
+		seen[name] = true
+	}
+}
+
+// Check returns broken references of the configuration: interfaces, zones, profiles and virtual routers referenced
+// but not defined, static routes through interfaces of other virtual routers, interfaces of a zone of a different
+// mode or not imported into the vsys, and interfaces assigned to several virtual routers or zones.
+func Check(config *Config) []Problem {
+	c := &checker{}
+	for _, d := range config.Devices {
+		c.device(d)
+	}
+	return c.problems
+}
+
+func (c *checker) device(d Device) {
+	interfaces := map[string]iface{}
+	var names []string
+	add := func(name string, i iface) {
+		names = append(names, name)
+		interfaces[name] = i
+	}
+	for _, e := range d.Network.Ethernet {
+		switch {
+		case e.Layer3 != nil:
+			add(e.Name, iface{mode: "layer3", profile: e.Layer3.ManagementProfile})
+			for _, u := range e.Layer3.Units {
+				add(u.Name, iface{mode: "layer3", profile: u.ManagementProfile})
+			}
+		case e.Layer2 != nil:
+			add(e.Name, iface{mode: "layer2"})
+			for _, u := range e.Layer2.Units {
+				add(u.Name, iface{mode: "layer2"})
+			}
+		case e.VirtualWire != nil:
+			add(e.Name, iface{mode: "virtual-wire"})
+		case e.Tap != nil:
+			add(e.Name, iface{mode: "tap"})
+		default:
+			add(e.Name, iface{})
+		}
+	}
+	for _, u := range append(append([]Unit{}, d.Network.Loopback...), d.Network.Tunnel...) {
+		add(u.Name, iface{mode: "layer3", profile: u.ManagementProfile})
+	}
+	c.unique("network interface", "interface", names)
+
+	profiles := map[string]bool{}
+	var profileNames []string
+	for _, p := range d.Network.ManagementProfiles {
+		profiles[p.Name] = true
+		profileNames = append(profileNames, p.Name)
+	}
+	c.unique("network profiles interface-management-profile", "profile", profileNames)
+	for _, name := range sortedKeys(interfaces) {
+		if p := interfaces[name].profile; p != "" && !profiles[p] {
+			c.report(fmt.Sprintf("interface %q", name), "interface management profile %q is not defined", p)
+		}
+	}
+
+	c.virtualRouters(d.Network.VirtualRouters, interfaces)
+	for _, v := range d.Vsys {
+		c.vsys(v, interfaces)
+	}
+}
+
+func (c *checker) virtualRouters(routers []VirtualRouter, interfaces map[string]iface) {
+	names := map[string]bool{}
+	var routerNames []string
+	for _, vr := range routers {
+		names[vr.Name] = true
+		routerNames = append(routerNames, vr.Name)
+	}
+	c.unique("network virtual-router", "virtual router", routerNames)
+
+	routerOf := map[string]string{}
+	for _, vr := range routers {
+		path := fmt.Sprintf("virtual-router %q", vr.Name)
+		members := map[string]bool{}
+		for _, name := range vr.Interfaces {
+			members[name] = true
+			i, ok := interfaces[name]
+			switch {
+			case !ok:
+				c.report(path, "interface %q is not defined", name)
+				continue
+			case i.mode != "layer3":
+				c.report(path, "interface %q is not a layer3 interface", name)
+			}
+			if other, ok := routerOf[name]; ok {
+				c.report(path, "interface %q is already in virtual router %q", name, other)
+				continue
+			}
+			routerOf[name] = vr.Name
+		}
+
+		var routeNames []string
+		for _, r := range vr.StaticRoutes {
+			routeNames = append(routeNames, r.Name)
+			routePath := fmt.Sprintf("%s static-route %q", path, r.Name)
+			if _, _, err := net.ParseCIDR(r.Destination); err != nil {
+				c.report(routePath, "destination %q is not a CIDR", r.Destination)
+			}
+			if r.Interface != "" {
+				if _, ok := interfaces[r.Interface]; !ok {
+					c.report(routePath, "interface %q is not defined", r.Interface)
+				} else if !members[r.Interface] {
+					c.report(routePath, "interface %q is not in the virtual router", r.Interface)
+				}
+			}
+			if r.NextHopIP != "" && net.ParseIP(r.NextHopIP) == nil {
+				c.report(routePath, "next hop %q is not an IP address", r.NextHopIP)
+			}
+			switch {
+			case r.NextVR == vr.Name:
+				c.report(routePath, "next virtual router is the virtual router itself")
+			case r.NextVR != "" && !names[r.NextVR]:
+				c.report(routePath, "next virtual router %q is not defined", r.NextVR)
+			}
+		}
+		c.unique(path, "static route", routeNames)
+	}
+}
+
+func (c *checker) vsys(v Vsys, interfaces map[string]iface) {
+	imported := map[string]bool{}
+	for _, name := range v.Import {
+		imported[name] = true
+		if _, ok := interfaces[name]; !ok {
+			c.report(fmt.Sprintf("%s import", v.Name), "interface %q is not defined", name)
+		}
+	}
+
+	zones := map[string]Zone{}
+	zoneOf := map[string]string{}
+	var zoneNames []string
+	for _, z := range v.Zones {
+		zones[z.Name] = z
+		zoneNames = append(zoneNames, z.Name)
+		path := fmt.Sprintf("%s zone %q", v.Name, z.Name)
+		for _, m := range []struct {
+			mode    string
+			members []string
+		}{{"layer3", z.Layer3}, {"layer2", z.Layer2}, {"virtual-wire", z.VirtualWire}, {"tap", z.Tap}} {
+			for _, name := range m.members {
+				i, ok := interfaces[name]
+				switch {
+				case !ok:
+					c.report(path, "interface %q is not defined", name)
+					continue
+				case i.mode != m.mode:
+					c.report(path, "interface %q is not a %s interface", name, m.mode)
+				}
+				if len(v.Import) > 0 && !imported[name] {
+					c.report(path, "interface %q is not imported into %s", name, v.Name)
+				}
+				if other, ok := zoneOf[name]; ok {
+					c.report(path, "interface %q is already in zone %q", name, other)
+					continue
+				}
+				zoneOf[name] = z.Name
+			}
+		}
+	}
+	c.unique(v.Name+" zone", "zone", zoneNames)
+
+	zoneRefs := func(path, field string, refs []string) {
+		for _, z := range refs {
+			if _, ok := zones[z]; !ok && z != "any" {
+				c.report(path, "%s zone %q is not defined", field, z)
+			}
+		}
+	}
+
+	var ruleNames []string
+	for _, r := range v.SecurityRules {
+		ruleNames = append(ruleNames, r.Name)
+		path := fmt.Sprintf("%s rulebase security %q", v.Name, r.Name)
+		zoneRefs(path, "from", r.From)
+		zoneRefs(path, "to", r.To)
+	}
+	c.unique(v.Name+" rulebase security", "rule", ruleNames)
+
+	ruleNames = nil
+	for _, r := range v.NATRules {
+		ruleNames = append(ruleNames, r.Name)
+		path := fmt.Sprintf("%s rulebase nat %q", v.Name, r.Name)
+		zoneRefs(path, "from", r.From)
+		zoneRefs(path, "to", r.To)
+		if r.ToInterface != "" && r.ToInterface != "any" {
+			if _, ok := interfaces[r.ToInterface]; !ok {
+				c.report(path, "destination interface %q is not defined", r.ToInterface)
+			}
+		}
+		if r.SourceInterface != "" {
+			if _, ok := interfaces[r.SourceInterface]; !ok {
+				c.report(path, "source translation interface %q is not defined", r.SourceInterface)
+			} else if len(r.To) == 1 && zones[r.To[0]].Name != "" && zoneOf[r.SourceInterface] != r.To[0] {
+				c.report(path, "source translation interface %q is not in the destination zone %q", r.SourceInterface, r.To[0])
+			}
+		}
+	}
+	c.unique(v.Name+" rulebase nat", "rule", ruleNames)
+}
+
+func sortedKeys(m map[string]iface) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+// Package panosconfig reads a PAN-OS configuration, like the `bootstrap.xml` of a bootstrap package, and verifies
+// references between its parts.
+//
+// A well-formed configuration can still be rejected by the firewall, or accepted and not pass traffic: a static
+// route through an interface of another virtual router, a zone with an interface which is not defined, a missing
+// interface management profile or a NAT rule to a zone which does not exist. Only the subtrees holding such
+// references are read: interfaces, interface management profiles, virtual routers, zones and rulebases.
+package panosconfig
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Config is the `config` element.
+type Config struct {
+	XMLName xml.Name `xml:"config"`
+	Devices []Device `xml:"devices>entry"`
+}
+
+// Device is an entry of `devices`, `localhost.localdomain` on a firewall.
+type Device struct {
+	Name    string  `xml:"name,attr"`
+	Network Network `xml:"network"`
+	Vsys    []Vsys  `xml:"vsys>entry"`
+}
+
+// Network is the `network` element of a device.
+type Network struct {
+	Ethernet           []Ethernet          `xml:"interface>ethernet>entry"`
+	Loopback           []Unit              `xml:"interface>loopback>units>entry"`
+	Tunnel             []Unit              `xml:"interface>tunnel>units>entry"`
+	ManagementProfiles []ManagementProfile `xml:"profiles>interface-management-profile>entry"`
+	VirtualRouters     []VirtualRouter     `xml:"virtual-router>entry"`
+}
+
+// Ethernet is an ethernet interface, in one of the modes.
+type Ethernet struct {
+	Name        string    `xml:"name,attr"`
+	Layer3      *Layer3   `xml:"layer3"`
+	Layer2      *Layer2   `xml:"layer2"`
+	VirtualWire *struct{} `xml:"virtual-wire"`
+	Tap         *struct{} `xml:"tap"`
+}
+
+// Layer3 is the layer 3 mode of an ethernet interface.
+type Layer3 struct {
+	ManagementProfile string `xml:"interface-management-profile"`
+	Units             []Unit `xml:"units>entry"`
+}
+
+// Layer2 is the layer 2 mode of an ethernet interface.
+type Layer2 struct {
+	Units []Unit `xml:"units>entry"`
+}
+
+// Unit is a subinterface, of an ethernet, loopback or tunnel interface.
+type Unit struct {
+	Name              string `xml:"name,attr"`
+	ManagementProfile string `xml:"interface-management-profile"`
+	Tag               string `xml:"tag"`
+}
+
+// ManagementProfile is an interface management profile, the services an interface responds to.
+type ManagementProfile struct {
+	Name        string  `xml:"name,attr"`
+	HTTP        string  `xml:"http"`
+	HTTPS       string  `xml:"https"`
+	Ping        string  `xml:"ping"`
+	SSH         string  `xml:"ssh"`
+	PermittedIP []Entry `xml:"permitted-ip>entry"`
+}
+
+// Entry is an element with a name only.
+type Entry struct {
+	Name string `xml:"name,attr"`
+}
+
+// VirtualRouter is an entry of `virtual-router`.
+type VirtualRouter struct {
+	Name         string        `xml:"name,attr"`
+	Interfaces   []string      `xml:"interface>member"`
+	StaticRoutes []StaticRoute `xml:"routing-table>ip>static-route>entry"`
+}
+
+// StaticRoute is an IPv4 static route of a virtual router.
+type StaticRoute struct {
+	Name        string `xml:"name,attr"`
+	Destination string `xml:"destination"`
+	Interface   string `xml:"interface"`
+	NextHopIP   string `xml:"nexthop>ip-address"`
+	NextVR      string `xml:"nexthop>next-vr"`
+}
+
+// Vsys is a virtual system.
+type Vsys struct {
+	Name          string         `xml:"name,attr"`
+	Zones         []Zone         `xml:"zone>entry"`
+	Import        []string       `xml:"import>network>interface>member"`
+	SecurityRules []SecurityRule `xml:"rulebase>security>rules>entry"`
+	NATRules      []NATRule      `xml:"rulebase>nat>rules>entry"`
+}
+
+// Zone is a security zone, with the interfaces of one of the modes.
+type Zone struct {
+	Name        string   `xml:"name,attr"`
+	Layer3      []string `xml:"network>layer3>member"`
+	Layer2      []string `xml:"network>layer2>member"`
+	VirtualWire []string `xml:"network>virtual-wire>member"`
+	Tap         []string `xml:"network>tap>member"`
+}
+
+// SecurityRule is a rule of the security rulebase.
+type SecurityRule struct {
+	Name   string   `xml:"name,attr"`
+	From   []string `xml:"from>member"`
+	To     []string `xml:"to>member"`
+	Action string   `xml:"action"`
+}
+
+// NATRule is a rule of the NAT rulebase.
+type NATRule struct {
+	Name        string   `xml:"name,attr"`
+	From        []string `xml:"from>member"`
+	To          []string `xml:"to>member"`
+	ToInterface string   `xml:"to-interface"`
+	// SourceInterface is the interface whose address source addresses are translated to.
+	SourceInterface string `xml:"source-translation>dynamic-ip-and-port>interface-address>interface"`
+}
+
+// Parse reads a configuration.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	if err := xml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cannot read the configuration: %w", err)
+	}
+	return &c, nil
+}
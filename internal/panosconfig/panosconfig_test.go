@@ -0,0 +1,130 @@
+package panosconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/tftemplate"
+)
+
+func load(t *testing.T, path string) *Config {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestParse(t *testing.T) {
+	config := load(t, "testdata/broken.xml")
+	if len(config.Devices) != 1 {
+		t.Fatalf("got %d devices", len(config.Devices))
+	}
+	d := config.Devices[0]
+	if len(d.Network.Ethernet) != 3 || d.Network.Ethernet[0].Layer3.Units[0].Name != "ethernet1/1.1" || d.Network.Ethernet[2].VirtualWire == nil {
+		t.Errorf("got interfaces %+v", d.Network.Ethernet)
+	}
+	if p := d.Network.ManagementProfiles; len(p) != 1 || p[0].HTTP != "yes" || p[0].PermittedIP[0].Name != "168.63.129.16" {
+		t.Errorf("got profiles %+v", p)
+	}
+	if r := d.Network.VirtualRouters[0].StaticRoutes[1]; r.NextVR != "publik" || r.Destination != "0.0.0.0/0" {
+		t.Errorf("got static route %+v", r)
+	}
+	if r := d.Vsys[0].NATRules[0]; r.SourceInterface != "ethernet1/1" || r.To[0] != "public" {
+		t.Errorf("got NAT rule %+v", r)
+	}
+	if _, err := Parse([]byte("<config><devices>")); err == nil {
+		t.Error("truncated configuration read")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var got []string
+	for _, p := range Check(load(t, "testdata/broken.xml")) {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`interface "ethernet1/1": interface management profile "lb-health-check" is not defined`,
+		`virtual-router "private": interface "ethernet1/3" is not a layer3 interface`,
+		`virtual-router "private": interface "ethernet1/4" is not defined`,
+		`virtual-router "private" static-route "lb_health_check": interface "ethernet1/2" is not in the virtual router`,
+		`virtual-router "private" static-route "internet": next virtual router "publik" is not defined`,
+		`virtual-router "private" static-route "loop": destination "10.0.0.0" is not a CIDR`,
+		`virtual-router "private" static-route "loop": next virtual router is the virtual router itself`,
+		`virtual-router "public": interface "ethernet1/1" is already in virtual router "private"`,
+		`virtual-router "public" static-route "internet": next hop "10.0.1.256" is not an IP address`,
+		`vsys1 zone "private": interface "ethernet1/5" is not defined`,
+		`vsys1 zone "public": interface "ethernet1/1" is already in zone "private"`,
+		`vsys1 zone "public": interface "ethernet1/3" is not a layer3 interface`,
+		`vsys1 zone "public": interface "ethernet1/1.1" is not imported into vsys1`,
+		`vsys1 rulebase security "any-any": to zone "untrust" is not defined`,
+		`vsys1 rulebase nat "internet": destination interface "ethernet1/9" is not defined`,
+		`vsys1 rulebase nat "internet": source translation interface "ethernet1/1" is not in the destination zone "public"`,
+		`vsys1 rulebase nat "outbound": to zone "internet" is not defined`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// templateVars are values of the variables the templates of the examples reference.
+var templateVars = map[string]cty.Value{
+	"private_azure_router_ip": cty.StringVal("10.0.0.33"),
+	"public_azure_router_ip":  cty.StringVal("10.0.0.65"),
+	"data_gateway_ip":         cty.StringVal("10.0.1.1"),
+	"private_network_cidr":    cty.StringVal("10.0.0.0/8"),
+	"mgmt_profile_appgw_cidr": cty.TupleVal([]cty.Value{cty.StringVal("10.0.0.128/26")}),
+	"ai_instr_key":            cty.StringVal("00000000-0000-0000-0000-000000000000"),
+	"ai_update_interval":      cty.NumberIntVal(5),
+}
+
+// TestExamples renders every template of the examples and checks the configuration.
+func TestExamples(t *testing.T) {
+	templates, err := filepath.Glob("../../examples/*/templates/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("no templates found")
+	}
+	for _, path := range templates {
+		t.Run(path, func(t *testing.T) {
+			dir := filepath.Dir(filepath.Dir(path))
+			names, err := tftemplate.Variables(dir, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vars := map[string]cty.Value{}
+			for _, name := range names {
+				v, ok := templateVars[name]
+				if !ok {
+					t.Fatalf("the template references %s, add its value to templateVars", name)
+				}
+				vars[name] = v
+			}
+			out, err := tftemplate.Render(dir, path, vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := Parse([]byte(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(config.Devices) == 0 || len(config.Devices[0].Network.Ethernet) == 0 {
+				t.Fatal("no interfaces read")
+			}
+			for _, p := range Check(config) {
+				t.Error(p)
+			}
+		})
+	}
+}
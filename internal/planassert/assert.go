@@ -0,0 +1,235 @@
+package planassert
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Assertion is a single verification of a plan.
+type Assertion struct {
+	// Description is a human readable statement that is expected to be true.
+	Description string
+	// Check returns an error explaining why the statement is false.
+	Check func(plan *Plan) error
+}
+
+// Check runs all assertions against the plan and reports each failed one as a test error.
+func Check(t *testing.T, plan *Plan, assertions []Assertion) {
+	t.Helper()
+	for _, a := range assertions {
+		if err := a.Check(plan); err != nil {
+			t.Errorf("%s: %v", a.Description, err)
+		}
+	}
+}
+
+// matchCreated returns all resources matching the pattern that are going to be created,
+// or an error when there are none.
+func matchCreated(plan *Plan, pattern string) ([]*ResourceChange, error) {
+	var created []*ResourceChange
+	for _, rc := range plan.Match(pattern) {
+		if rc.Created() {
+			created = append(created, rc)
+		}
+	}
+	if len(created) == 0 {
+		return nil, fmt.Errorf("no resource matching %s is planned for creation", pattern)
+	}
+	return created, nil
+}
+
+// IsCreated verifies that at least one resource matching the address pattern is going to be created.
+func IsCreated(pattern string) Assertion {
+	return Assertion{
+		Description: fmt.Sprintf("%s is created", pattern),
+		Check: func(plan *Plan) error {
+			_, err := matchCreated(plan, pattern)
+			return err
+		},
+	}
+}
+
+// IsNotPlanned verifies that no resource matching the address pattern is in the plan.
+func IsNotPlanned(pattern string) Assertion {
+	return Assertion{
+		Description: fmt.Sprintf("%s is not planned", pattern),
+		Check: func(plan *Plan) error {
+			if changes := plan.Match(pattern); len(changes) > 0 {
+				return fmt.Errorf("found %s", addresses(changes))
+			}
+			return nil
+		},
+	}
+}
+
+// CreatedCount verifies the exact number of created resources matching the address pattern.
+func CreatedCount(pattern string, count int) Assertion {
+	return Assertion{
+		Description: fmt.Sprintf("%d resources matching %s are created", count, pattern),
+		Check: func(plan *Plan) error {
+			var created []*ResourceChange
+			for _, rc := range plan.Match(pattern) {
+				if rc.Created() {
+					created = append(created, rc)
+				}
+			}
+			if len(created) != count {
+				return fmt.Errorf("got %d: %s", len(created), addresses(created))
+			}
+			return nil
+		},
+	}
+}
+
+// CreatedWithLength verifies that resources matching the address pattern are created
+// and that the attribute under the path has the given number of elements.
+func CreatedWithLength(pattern, path string, length int) Assertion {
+	return Assertion{
+		Description: fmt.Sprintf("%s is created with %d %s", pattern, length, path),
+		Check: func(plan *Plan) error {
+			created, err := matchCreated(plan, pattern)
+			if err != nil {
+				return err
+			}
+			for _, rc := range created {
+				got, known := rc.Length(path)
+				if !known {
+					return fmt.Errorf("%s: length of %s is not known until apply", rc.Address, path)
+				}
+				if got != length {
+					return fmt.Errorf("%s: got %d elements", rc.Address, got)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// AttributeEquals verifies that resources matching the address pattern are created
+// and that all values under the path are known and equal to the expected one.
+// Numbers in a plan are decoded as float64.
+func AttributeEquals(pattern, path string, want interface{}) Assertion {
+	return Assertion{
+		Description: fmt.Sprintf("%s is created with %s = %v", pattern, path, want),
+		Check: func(plan *Plan) error {
+			created, err := matchCreated(plan, pattern)
+			if err != nil {
+				return err
+			}
+			for _, rc := range created {
+				values := rc.Attribute(path)
+				if len(values) == 0 {
+					return fmt.Errorf("%s: attribute %s not found", rc.Address, path)
+				}
+				for _, v := range values {
+					if v.Unknown {
+						return fmt.Errorf("%s: %s is not known until apply", rc.Address, path)
+					}
+					if !reflect.DeepEqual(v.Value, want) {
+						return fmt.Errorf("%s: got %v", rc.Address, v.Value)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// AttributeNull verifies that resources matching the address pattern are created and that all values
+// under the path are known to be null (or empty, for nested blocks) after apply. A path matching no value
+// fails, so that a mistyped path does not pass unnoticed.
+func AttributeNull(pattern, path string) Assertion {
+	return Assertion{
+		Description: fmt.Sprintf("%s is created without %s", pattern, path),
+		Check: func(plan *Plan) error {
+			created, err := matchCreated(plan, pattern)
+			if err != nil {
+				return err
+			}
+			for _, rc := range created {
+				values := rc.Attribute(path)
+				if len(values) == 0 {
+					return fmt.Errorf("%s: %s is not found", rc.Address, path)
+				}
+				for _, v := range values {
+					if v.Unknown || !isEmpty(v.Value) {
+						return fmt.Errorf("%s: %s is set", rc.Address, path)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// NoPublicIPOnInterfaces verifies that no Public IP is attached to a created `azurerm_network_interface`
+// with a planned name ending with the given suffix, for example `-mgmt-nic`.
+func NoPublicIPOnInterfaces(nameSuffix string) Assertion {
+	return publicIPOnInterfaces(nameSuffix, false)
+}
+
+// PublicIPOnInterfaces verifies that a Public IP is attached to every created `azurerm_network_interface`
+// with a planned name ending with the given suffix.
+func PublicIPOnInterfaces(nameSuffix string) Assertion {
+	return publicIPOnInterfaces(nameSuffix, true)
+}
+
+func publicIPOnInterfaces(nameSuffix string, attached bool) Assertion {
+	description := fmt.Sprintf("no azurerm_public_ip is attached to NICs named *%s", nameSuffix)
+	if attached {
+		description = fmt.Sprintf("an azurerm_public_ip is attached to every NIC named *%s", nameSuffix)
+	}
+	return Assertion{
+		Description: description,
+		Check: func(plan *Plan) error {
+			found := 0
+			for _, rc := range plan.OfType("azurerm_network_interface") {
+				name, _ := rc.StringAttribute("name")
+				if !rc.Created() || !strings.HasSuffix(name, nameSuffix) {
+					continue
+				}
+				found++
+				hasPublicIP := false
+				for _, v := range rc.Attribute("ip_configuration.*.public_ip_address_id") {
+					if v.Unknown || !isEmpty(v.Value) {
+						hasPublicIP = true
+					}
+				}
+				if hasPublicIP != attached {
+					return fmt.Errorf("%s (%s) does not meet the expectation", rc.Address, name)
+				}
+			}
+			if found == 0 {
+				return fmt.Errorf("no network interface named *%s is planned for creation", nameSuffix)
+			}
+			return nil
+		},
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func addresses(changes []*ResourceChange) string {
+	var out []string
+	for _, rc := range changes {
+		out = append(out, rc.Address)
+	}
+	if len(out) == 0 {
+		return "none"
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,325 @@
+// Package planassert decodes saved Terraform plans and runs typed assertions against the planned resource changes.
+//
+// It lets example tests verify the planned topology (what is created, with which attributes and how many of them)
+// before any resources are deployed.
+package planassert
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Plan is a decoded `terraform show -json` output of a saved plan.
+type Plan struct {
+	// Raw is the plan as decoded by terraform-json.
+	Raw *tfjson.Plan
+	// Changes are all managed resource changes, sorted by address.
+	Changes []*ResourceChange
+}
+
+// ResourceChange is a single planned change of a managed resource.
+type ResourceChange struct {
+	Address       string
+	ModuleAddress string
+	Type          string
+	Name          string
+	Index         interface{}
+	Actions       tfjson.Actions
+
+	after          interface{}
+	afterUnknown   interface{}
+	afterSensitive interface{}
+}
+
+// Value is a single planned attribute value. Unknown values are only known after apply.
+type Value struct {
+	Value   interface{}
+	Unknown bool
+}
+
+// Parse decodes the JSON representation of a plan.
+func Parse(data []byte) (*Plan, error) {
+	raw := &tfjson.Plan{}
+	if err := json.Unmarshal(data, raw); err != nil {
+		return nil, fmt.Errorf("cannot decode plan: %w", err)
+	}
+
+	plan := &Plan{Raw: raw}
+	for _, rc := range raw.ResourceChanges {
+		if rc.Mode != tfjson.ManagedResourceMode || rc.Change == nil {
+			continue
+		}
+		plan.Changes = append(plan.Changes, &ResourceChange{
+			Address:        rc.Address,
+			ModuleAddress:  rc.ModuleAddress,
+			Type:           rc.Type,
+			Name:           rc.Name,
+			Index:          rc.Index,
+			Actions:        rc.Change.Actions,
+			after:          rc.Change.After,
+			afterUnknown:   rc.Change.AfterUnknown,
+			afterSensitive: rc.Change.AfterSensitive,
+		})
+	}
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].Address < plan.Changes[j].Address })
+
+	return plan, nil
+}
+
+// Load runs `terraform init` and `terraform plan` with a temporary plan file, then decodes
+// the `terraform show -json` output of that file. It fails the test on any error.
+func Load(t *testing.T, options *terraform.Options) *Plan {
+	plan, err := LoadE(t, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+// LoadE is like Load, but returns an error instead of failing the test.
+func LoadE(t *testing.T, options *terraform.Options) (*Plan, error) {
+	planOptions, err := options.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("cannot copy Terraform options: %w", err)
+	}
+	planOptions.PlanFilePath = filepath.Join(t.TempDir(), "plan.tfplan")
+
+	out, err := terraform.InitAndPlanAndShowE(t, planOptions)
+	if err != nil {
+		return nil, fmt.Errorf("cannot plan infrastructure: %w", err)
+	}
+	return Parse([]byte(out))
+}
+
+// Resource returns a change of a resource with the given address, or nil when it is not in the plan.
+func (p *Plan) Resource(address string) *ResourceChange {
+	for _, rc := range p.Changes {
+		if rc.Address == address {
+			return rc
+		}
+	}
+	return nil
+}
+
+// Match returns all changes with addresses matching the pattern, see MatchAddress.
+func (p *Plan) Match(pattern string) []*ResourceChange {
+	var changes []*ResourceChange
+	for _, rc := range p.Changes {
+		if MatchAddress(pattern, rc.Address) {
+			changes = append(changes, rc)
+		}
+	}
+	return changes
+}
+
+// OfType returns all changes of resources of the given type.
+func (p *Plan) OfType(resourceType string) []*ResourceChange {
+	var changes []*ResourceChange
+	for _, rc := range p.Changes {
+		if rc.Type == resourceType {
+			changes = append(changes, rc)
+		}
+	}
+	return changes
+}
+
+// Created reports whether the resource is going to be created, including replacements.
+func (rc *ResourceChange) Created() bool {
+	return rc.Actions.Create() || rc.Actions.Replace()
+}
+
+// Attribute returns planned values found under a dot separated path, for example `ip_configuration.0.subnet_id`.
+// A `*` path element expands to all elements of a list or all values of a map.
+func (rc *ResourceChange) Attribute(path string) []Value {
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, ".")
+	}
+	return lookup(rc.after, rc.afterUnknown, parts)
+}
+
+// AttributeNames returns sorted names of all top-level attributes of the planned value, known or not.
+func (rc *ResourceChange) AttributeNames() []string {
+	names := map[string]bool{}
+	for _, m := range []interface{}{rc.after, rc.afterUnknown} {
+		if m, ok := m.(map[string]interface{}); ok {
+			for k := range m {
+				names[k] = true
+			}
+		}
+	}
+	var out []string
+	for k := range names {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Sensitive reports whether a top-level attribute, or any part of it, is marked as sensitive.
+func (rc *ResourceChange) Sensitive(name string) bool {
+	m, ok := rc.afterSensitive.(map[string]interface{})
+	if !ok {
+		return isUnknown(rc.afterSensitive)
+	}
+	return containsTrue(m[name])
+}
+
+func containsTrue(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case []interface{}:
+		for _, e := range v {
+			if containsTrue(e) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			if containsTrue(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Length returns the number of elements of a list, set or map attribute and whether that number is known.
+func (rc *ResourceChange) Length(path string) (int, bool) {
+	values := rc.Attribute(path)
+	if len(values) != 1 || values[0].Unknown {
+		return 0, false
+	}
+	switch v := values[0].Value.(type) {
+	case []interface{}:
+		return len(v), true
+	case map[string]interface{}:
+		return len(v), true
+	case nil:
+		return 0, true
+	}
+	return 0, false
+}
+
+// StringAttribute returns a known string value of an attribute.
+func (rc *ResourceChange) StringAttribute(path string) (string, bool) {
+	values := rc.Attribute(path)
+	if len(values) != 1 || values[0].Unknown {
+		return "", false
+	}
+	s, ok := values[0].Value.(string)
+	return s, ok
+}
+
+func lookup(after, unknown interface{}, path []string) []Value {
+	if isUnknown(unknown) {
+		return []Value{{Unknown: true}}
+	}
+	if len(path) == 0 {
+		return []Value{{Value: merge(after, unknown)}}
+	}
+
+	head, tail := path[0], path[1:]
+	switch v := after.(type) {
+	case []interface{}:
+		unknowns, _ := unknown.([]interface{})
+		var indexes []int
+		if head == "*" {
+			for i := range v {
+				indexes = append(indexes, i)
+			}
+		} else if i, err := strconv.Atoi(head); err == nil && i >= 0 && i < len(v) {
+			indexes = append(indexes, i)
+		}
+		var values []Value
+		for _, i := range indexes {
+			var u interface{}
+			if i < len(unknowns) {
+				u = unknowns[i]
+			}
+			values = append(values, lookup(v[i], u, tail)...)
+		}
+		return values
+	case map[string]interface{}:
+		unknowns, _ := unknown.(map[string]interface{})
+		var keys []string
+		if head == "*" {
+			for k := range v {
+				keys = append(keys, k)
+			}
+			for k := range unknowns {
+				if _, ok := v[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+		} else {
+			keys = []string{head}
+		}
+		var values []Value
+		for _, k := range keys {
+			if _, ok := v[k]; !ok && unknowns[k] == nil {
+				continue
+			}
+			values = append(values, lookup(v[k], unknowns[k], tail)...)
+		}
+		return values
+	case nil:
+		// a whole object can be absent from `after` when it is unknown, this is handled above
+		if m, ok := unknown.(map[string]interface{}); ok {
+			return lookup(map[string]interface{}{}, m, path)
+		}
+	}
+	return nil
+}
+
+// merge fills in elements of lists that are known in length but have unknown elements,
+// so that lengths of such lists can still be verified.
+func merge(after, unknown interface{}) interface{} {
+	if after == nil {
+		if u, ok := unknown.([]interface{}); ok {
+			return make([]interface{}, len(u))
+		}
+	}
+	return after
+}
+
+func isUnknown(unknown interface{}) bool {
+	b, ok := unknown.(bool)
+	return ok && b
+}
+
+// MatchAddress reports whether a resource address matches a pattern. The only special character
+// in a pattern is `*`, matching any sequence of characters, including an empty one.
+// This way instance keys can be matched without escaping, for example
+// `module.vmseries["fw-1"].azurerm_network_interface.this[*]`.
+func MatchAddress(pattern, address string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == address
+	}
+	if !strings.HasPrefix(address, parts[0]) {
+		return false
+	}
+	address = address[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(address, part)
+		}
+		idx := strings.Index(address, part)
+		if idx < 0 {
+			return false
+		}
+		address = address[idx+len(part):]
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package planassert
+
+import (
+	"os"
+	"testing"
+)
+
+func loadTestPlan(t *testing.T) *Plan {
+	data, err := os.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestParseSkipsDataSources(t *testing.T) {
+	plan := loadTestPlan(t)
+
+	if got := len(plan.Changes); got != 5 {
+		t.Fatalf("got %d changes, want 5", got)
+	}
+	if plan.Resource("data.azurerm_resource_group.this[0]") != nil {
+		t.Error("data sources should not be listed as resource changes")
+	}
+	if rc := plan.Resource("azurerm_resource_group.this[0]"); rc == nil || rc.Created() {
+		t.Error("azurerm_resource_group.this[0] should be present and not created")
+	}
+}
+
+func TestMatchAddress(t *testing.T) {
+	cases := []struct {
+		pattern, address string
+		want             bool
+	}{
+		{`module.vmseries["fw-1"].azurerm_virtual_machine.this`, `module.vmseries["fw-1"].azurerm_virtual_machine.this`, true},
+		{`module.vmseries["fw-1"].azurerm_virtual_machine.this`, `module.vmseries["fw-2"].azurerm_virtual_machine.this`, false},
+		{`module.vmseries[*].azurerm_virtual_machine.this`, `module.vmseries["fw-2"].azurerm_virtual_machine.this`, true},
+		{`module.vmseries["fw-1"].azurerm_network_interface.this[*]`, `module.vmseries["fw-1"].azurerm_network_interface.this["a-mgmt"]`, true},
+		{`*azurerm_public_ip*`, `module.vmseries["fw-1"].azurerm_public_ip.this["a"]`, true},
+		{`*.azurerm_public_ip.*`, `azurerm_public_ip.this`, false},
+		{`module.*.this[*-mgmt"]`, `module.vmseries["fw-1"].azurerm_network_interface.this["a-mgmt"]`, true},
+		{`module.*.this[*-mgmt"]`, `module.vmseries["fw-1"].azurerm_network_interface.this["a-private"]`, false},
+	}
+	for _, c := range cases {
+		if got := MatchAddress(c.pattern, c.address); got != c.want {
+			t.Errorf("MatchAddress(%q, %q) = %v, want %v", c.pattern, c.address, got, c.want)
+		}
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	plan := loadTestPlan(t)
+	vm := plan.Resource(`module.vmseries["fw-1"].azurerm_virtual_machine.this`)
+
+	if n, known := vm.Length("network_interface_ids"); !known || n != 3 {
+		t.Errorf("Length(network_interface_ids) = %d, %v, want 3, true", n, known)
+	}
+	if _, known := vm.Length("id"); known {
+		t.Error("id should not be known until apply")
+	}
+	if s, ok := vm.StringAttribute("vm_size"); !ok || s != "Standard_DS3_v2" {
+		t.Errorf("StringAttribute(vm_size) = %q, %v", s, ok)
+	}
+
+	nic := plan.Resource(`module.vmseries["fw-1"].azurerm_network_interface.this["abcd-fw-1-mgmt"]`)
+	values := nic.Attribute("ip_configuration.*.public_ip_address_id")
+	if len(values) != 1 || !values[0].Unknown {
+		t.Errorf("public_ip_address_id = %+v, want a single unknown value", values)
+	}
+	values = nic.Attribute("ip_configuration.0.name")
+	if len(values) != 1 || values[0].Value != "primary" {
+		t.Errorf("ip_configuration.0.name = %+v, want primary", values)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	plan := loadTestPlan(t)
+
+	cases := []struct {
+		assertion Assertion
+		pass      bool
+	}{
+		{IsCreated(`module.vmseries["fw-1"].azurerm_virtual_machine.this`), true},
+		{IsCreated(`module.vmseries["fw-2"].azurerm_virtual_machine.this`), false},
+		{IsCreated(`azurerm_resource_group.this[0]`), false},
+		{IsNotPlanned(`module.natgw*`), true},
+		{IsNotPlanned(`module.vmseries*`), false},
+		{CreatedCount(`module.vmseries["fw-1"].azurerm_network_interface.this[*]`, 2), true},
+		{CreatedCount(`module.vmseries[*].azurerm_public_ip.this[*]`, 2), false},
+		{CreatedWithLength(`module.vmseries["fw-1"].azurerm_virtual_machine.this`, "network_interface_ids", 3), true},
+		{CreatedWithLength(`module.vmseries["fw-1"].azurerm_virtual_machine.this`, "network_interface_ids", 2), false},
+		{CreatedWithLength(`module.vmseries["fw-1"].azurerm_virtual_machine.this`, "id", 2), false},
+		{AttributeEquals(`module.vmseries[*].azurerm_virtual_machine.this`, "vm_size", "Standard_DS3_v2"), true},
+		{AttributeEquals(`module.vmseries[*].azurerm_virtual_machine.this`, "zones.0", "2"), false},
+		{AttributeEquals(`module.vmseries[*].azurerm_virtual_machine.this`, "missing", "x"), false},
+		{AttributeNull(`module.vmseries["fw-1"].azurerm_network_interface.this["abcd-fw-1-private"]`, "ip_configuration.*.public_ip_address_id"), true},
+		{AttributeNull(`module.vmseries["fw-1"].azurerm_network_interface.this["abcd-fw-1-mgmt"]`, "ip_configuration.*.public_ip_address_id"), false},
+		{AttributeNull(`module.vmseries["fw-1"].azurerm_network_interface.this["abcd-fw-1-private"]`, "ip_configuration.*.missing"), false},
+		{AttributeNull(`module.vmseries["fw-1"].azurerm_network_interface.this["abcd-fw-1-private"]`, "ip_configuration.5.public_ip_address_id"), false},
+		{NoPublicIPOnInterfaces("-private-nic"), true},
+		{NoPublicIPOnInterfaces("-mgmt-nic"), false},
+		{NoPublicIPOnInterfaces("-public-nic"), false},
+		{PublicIPOnInterfaces("-mgmt-nic"), true},
+		{PublicIPOnInterfaces("-private-nic"), false},
+	}
+	for _, c := range cases {
+		err := c.assertion.Check(plan)
+		if (err == nil) != c.pass {
+			t.Errorf("%s: got error %v, want pass = %v", c.assertion.Description, err, c.pass)
+		}
+	}
+}
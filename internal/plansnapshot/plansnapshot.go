@@ -0,0 +1,166 @@
+// Package plansnapshot renders a planned resource set into a stable, human readable form and compares it
+// with a committed golden file, so that reviewers can see how a change in a module affects what examples create.
+package plansnapshot
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+// maxValueLength is the length above which a value is replaced with its hash to keep snapshots readable.
+const maxValueLength = 120
+
+// skippedAttributes are attributes that carry no information about the infrastructure shape.
+var skippedAttributes = map[string]bool{
+	"tags":     true,
+	"timeouts": true,
+}
+
+// Mask replaces a random, per run value with a stable placeholder.
+type Mask struct {
+	Value       string
+	Placeholder string
+}
+
+// Render returns a normalised representation of all planned resource changes.
+//
+// For each resource its address, planned actions and known top-level attributes are listed.
+// Nested blocks, unknown and sensitive values are omitted. All masked values are replaced with their placeholders,
+// in addresses as well as in attribute values.
+func Render(plan *planassert.Plan, masks []Mask) string {
+	// replace longer values first, so that a value containing another one is masked as a whole
+	masks = append([]Mask(nil), masks...)
+	sort.SliceStable(masks, func(i, j int) bool { return len(masks[i].Value) > len(masks[j].Value) })
+	mask := func(s string) string {
+		for _, m := range masks {
+			if m.Value != "" {
+				s = strings.ReplaceAll(s, m.Value, m.Placeholder)
+			}
+		}
+		return s
+	}
+
+	type block struct {
+		address string
+		lines   []string
+	}
+	var blocks []block
+	for _, rc := range plan.Changes {
+		b := block{address: mask(rc.Address)}
+		for _, name := range rc.AttributeNames() {
+			if skippedAttributes[name] || rc.Sensitive(name) {
+				continue
+			}
+			values := rc.Attribute(name)
+			if len(values) != 1 || values[0].Unknown || !isFlat(values[0].Value) {
+				continue
+			}
+			b.lines = append(b.lines, fmt.Sprintf("  %s = %s", name, formatValue(values[0].Value, mask)))
+		}
+		actions := make([]string, 0, len(rc.Actions))
+		for _, a := range rc.Actions {
+			actions = append(actions, string(a))
+		}
+		b.lines = append([]string{fmt.Sprintf("%s (%s)", b.address, strings.Join(actions, ", "))}, b.lines...)
+		blocks = append(blocks, b)
+	}
+	// sort again, masking changes the order of addresses
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].address < blocks[j].address })
+
+	var sb strings.Builder
+	for i, b := range blocks {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		for _, l := range b.lines {
+			sb.WriteString(l)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// isFlat reports whether a value is a scalar or a collection of scalars.
+func isFlat(v interface{}) bool {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			if !isScalar(e) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		for _, e := range v {
+			if !isScalar(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return isScalar(v)
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, float64:
+		return true
+	}
+	return false
+}
+
+func formatValue(v interface{}, mask func(string) string) string {
+	// encoding/json sorts map keys, so the output is stable
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	s := mask(string(out))
+	if len(s) > maxValueLength {
+		return fmt.Sprintf("<%d bytes, sha256 %x>", len(s), sha256.Sum256([]byte(s)))
+	}
+	return s
+}
+
+// Diff returns a unified diff between the golden and the actual snapshot, or an empty string when they are equal.
+func Diff(golden, actual, goldenName string) string {
+	if golden == actual {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(golden),
+		B:        difflib.SplitLines(actual),
+		FromFile: goldenName,
+		ToFile:   "planned",
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Sprintf("cannot compute diff: %v", err)
+	}
+	return diff
+}
+
+// Compare compares the snapshot with the golden file. When update is set, the golden file is (re)written instead.
+// A missing golden file is reported with os.ErrNotExist, so that callers can decide whether it is an error.
+func Compare(path, actual string, update bool) (string, error) {
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		return "", os.WriteFile(path, []byte(actual), 0644)
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return Diff(string(golden), actual, path), nil
+}
@@ -0,0 +1,74 @@
+package plansnapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
+)
+
+var testMasks = []Mask{
+	{Value: "abcd-", Placeholder: "<name_prefix>"},
+	{Value: "rgxyz", Placeholder: "<resource_group_name>"},
+	{Value: "abcdstorage123", Placeholder: "<storage_account_name>"},
+}
+
+func renderTestPlan(t *testing.T) string {
+	data, err := os.ReadFile("testdata/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planassert.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Render(plan, testMasks)
+}
+
+func TestRender(t *testing.T) {
+	actual := renderTestPlan(t)
+
+	golden, err := os.ReadFile("testdata/plan.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(string(golden), actual, "testdata/plan.golden"); diff != "" {
+		t.Errorf("snapshot mismatch:\n%s", diff)
+	}
+	if strings.Contains(actual, "secret") {
+		t.Error("sensitive values must not be rendered")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if diff := Diff("a\nb\n", "a\nb\n", "golden"); diff != "" {
+		t.Errorf("expected no diff, got:\n%s", diff)
+	}
+
+	diff := Diff("a\nb\nc\n", "a\nB\nc\n", "golden")
+	for _, want := range []string{"--- golden", "+++ planned", "-b", "+B"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, diff)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "plan.golden")
+
+	if _, err := Compare(path, "x\n", false); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing golden file: got %v, want os.ErrNotExist", err)
+	}
+	if _, err := Compare(path, "x\n", true); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if diff, err := Compare(path, "x\n", false); err != nil || diff != "" {
+		t.Errorf("after update: diff %q, error %v", diff, err)
+	}
+	if diff, err := Compare(path, "y\n", false); err != nil || diff == "" {
+		t.Errorf("changed snapshot: diff %q, error %v", diff, err)
+	}
+}
@@ -0,0 +1,59 @@
+package readme
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// paragraphLine matches a line break followed by a line starting with a word, Markdown joins such lines.
+	paragraphLine = regexp.MustCompile(`(\S*)(\r?\n)(\s*)(\w+)`)
+	url           = regexp.MustCompile(`https?://[^\s)\]]+`)
+)
+
+// description renders a description for a table cell: line breaks become `<br>`, code blocks become preformatted
+// blocks and characters with a meaning in Markdown are escaped outside of code spans.
+func description(s string) string {
+	if s == "" {
+		return "n/a"
+	}
+	var sb strings.Builder
+	for i, segment := range strings.Split(s, "```") {
+		if i%2 == 1 {
+			sb.WriteString("<pre>" + strings.ReplaceAll(strings.TrimSpace(segment), "\n", "<br>") + "</pre>")
+			continue
+		}
+		segment = escape(segment)
+		segment = multiLine(segment)
+		segment = url.ReplaceAllStringFunc(segment, func(u string) string {
+			return strings.ReplaceAll(u, `\_`, "_")
+		})
+		sb.WriteString(segment)
+	}
+	return sb.String()
+}
+
+// escape escapes underscores, asterisks and pipes outside of code spans, and pipes in code spans. Like terraform-docs,
+// an unterminated code span is closed at the end of the text.
+func escape(s string) string {
+	segments := strings.Split(s, "`")
+	for i, segment := range segments {
+		if i%2 == 0 {
+			segment = strings.ReplaceAll(segment, "_", `\_`)
+		}
+		segments[i] = strings.ReplaceAll(segment, "|", `\|`)
+	}
+	if len(segments)%2 == 0 {
+		segments = append(segments, "")
+	}
+	return strings.Join(segments, "`")
+}
+
+func multiLine(s string) string {
+	s = strings.TrimSpace(s)
+	s = paragraphLine.ReplaceAllString(s, "$1  $2$3$4")
+	s = strings.ReplaceAll(s, "    \n", "  \n")
+	s = strings.ReplaceAll(s, "<br>  \n", "\n\n")
+	s = strings.ReplaceAll(s, "  \n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,522 @@
+// Package readme renders the reference documentation of a Terraform module, its requirements, providers, modules,
+// resources, inputs and outputs, into the module's README.md.
+//
+// The reference is kept between the marker comments used by the pre-commit hook, everything outside of them is
+// written by hand. The output is the same as of the terraform_docs hook with the repository's settings, so that
+// either of them can update a README. With Options.Sensitive, a `Sensitive` column is added to the inputs and outputs
+// of a module which has sensitive ones, terraform-docs cannot render it. Check reports a README which is not up to
+// date, so that documentation can be enforced in tests.
+package readme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// BeginMarker and EndMarker enclose the generated reference in a README.
+	BeginMarker = "<!-- BEGINNING OF PRE-COMMIT-TERRAFORM DOCS HOOK -->"
+	EndMarker   = "<!-- END OF PRE-COMMIT-TERRAFORM DOCS HOOK -->"
+)
+
+// Module is the part of a Terraform module which is documented.
+type Module struct {
+	// TerraformVersion is the `required_version` constraint.
+	TerraformVersion string
+	Requirements     []Provider
+	// Providers are providers used by resources, data sources and provider blocks, in order of appearance.
+	Providers []Provider
+	Modules   []Call
+	Resources []Resource
+	Variables []Variable
+	Outputs   []Output
+}
+
+// Provider is a provider required or used by the module.
+type Provider struct {
+	Name    string
+	Source  string
+	Version string
+}
+
+// Call is a call of another module.
+type Call struct {
+	Name    string
+	Source  string
+	Version string
+}
+
+// Resource is a managed resource or a data source.
+type Resource struct {
+	Type string
+	Name string
+	Data bool
+}
+
+// Variable is an input of the module.
+type Variable struct {
+	Name        string
+	Description string
+	// Type is the source of the type constraint, `any` when there is none.
+	Type string
+	// Default is the default value rendered as JSON, or the source of the default when it is not a literal.
+	Default   string
+	Required  bool
+	Sensitive bool
+}
+
+// Output is an output of the module.
+type Output struct {
+	Name        string
+	Description string
+	Sensitive   bool
+}
+
+// Options change the rendered reference.
+type Options struct {
+	// Sensitive adds a `Sensitive` column to the inputs and to the outputs when any of them is sensitive.
+	// The reference differs from the one of terraform-docs then, do not use it for READMEs of the pre-commit hook.
+	Sensitive bool
+}
+
+// Load parses `.tf` files of the module in dir. Blocks are kept in order of appearance, files in alphabetical order.
+func Load(dir string) (*Module, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	m := &Module{}
+	parser := hclparse.NewParser()
+	var diags hcl.Diagnostics
+	used := map[string]bool{}
+	useProvider := func(name string) {
+		if !used[name] {
+			used[name] = true
+			m.Providers = append(m.Providers, Provider{Name: name})
+		}
+	}
+	for _, path := range paths {
+		file, fileDiags := parser.ParseHCLFile(path)
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+		src := file.Bytes
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			switch {
+			case block.Type == "terraform":
+				m.decodeTerraform(block)
+			case block.Type == "provider" && len(block.Labels) == 1:
+				useProvider(block.Labels[0])
+			case (block.Type == "resource" || block.Type == "data") && len(block.Labels) == 2:
+				m.Resources = append(m.Resources, Resource{Type: block.Labels[0], Name: block.Labels[1], Data: block.Type == "data"})
+				if attr, ok := block.Body.Attributes["provider"]; ok {
+					if traversal, diags := hcl.AbsTraversalForExpr(attr.Expr); !diags.HasErrors() {
+						useProvider(traversal.RootName())
+						continue
+					}
+				}
+				useProvider(strings.SplitN(block.Labels[0], "_", 2)[0])
+			case block.Type == "module" && len(block.Labels) == 1:
+				m.Modules = append(m.Modules, Call{
+					Name:    block.Labels[0],
+					Source:  stringAttribute(block.Body, "source"),
+					Version: stringAttribute(block.Body, "version"),
+				})
+			case block.Type == "variable" && len(block.Labels) == 1:
+				m.Variables = append(m.Variables, decodeVariable(block, src))
+			case block.Type == "output" && len(block.Labels) == 1:
+				m.Outputs = append(m.Outputs, Output{
+					Name:        block.Labels[0],
+					Description: stringAttribute(block.Body, "description"),
+					Sensitive:   boolAttribute(block.Body, "sensitive"),
+				})
+			}
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	for i, p := range m.Providers {
+		for _, r := range m.Requirements {
+			if r.Name == p.Name {
+				m.Providers[i] = r
+			}
+		}
+	}
+	sort.SliceStable(m.Resources, func(i, j int) bool {
+		a, b := m.Resources[i], m.Resources[j]
+		if a.Data != b.Data {
+			return !a.Data
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Name < b.Name
+	})
+	return m, nil
+}
+
+func (m *Module) decodeTerraform(block *hclsyntax.Block) {
+	if v := stringAttribute(block.Body, "required_version"); v != "" {
+		m.TerraformVersion = v
+	}
+	for _, b := range block.Body.Blocks {
+		if b.Type != "required_providers" {
+			continue
+		}
+		attrs := make([]*hclsyntax.Attribute, 0, len(b.Body.Attributes))
+		for _, attr := range b.Body.Attributes {
+			attrs = append(attrs, attr)
+		}
+		sort.Slice(attrs, func(i, j int) bool { return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte })
+		for _, attr := range attrs {
+			p := Provider{Name: attr.Name}
+			val, diags := attr.Expr.Value(nil)
+			switch {
+			case diags.HasErrors() || val.IsNull():
+			case val.Type() == cty.String:
+				// the legacy syntax, `azurerm = "~> 3.25"`
+				p.Version = val.AsString()
+			case val.Type().IsObjectType():
+				if val.Type().HasAttribute("source") && val.GetAttr("source").Type() == cty.String {
+					p.Source = val.GetAttr("source").AsString()
+				}
+				if val.Type().HasAttribute("version") && val.GetAttr("version").Type() == cty.String {
+					p.Version = val.GetAttr("version").AsString()
+				}
+			}
+			m.Requirements = append(m.Requirements, p)
+		}
+	}
+}
+
+func decodeVariable(block *hclsyntax.Block, src []byte) Variable {
+	v := Variable{
+		Name:        block.Labels[0],
+		Description: stringAttribute(block.Body, "description"),
+		Type:        "any",
+		Required:    true,
+		Sensitive:   boolAttribute(block.Body, "sensitive"),
+	}
+	if attr, ok := block.Body.Attributes["type"]; ok {
+		v.Type = string(attr.Expr.Range().SliceBytes(src))
+	}
+	if attr, ok := block.Body.Attributes["default"]; ok {
+		v.Required = false
+		v.Default = formatDefault(attr.Expr, src)
+		if _, ok := block.Body.Attributes["type"]; !ok {
+			v.Type = defaultType(attr.Expr)
+		}
+	}
+	return v
+}
+
+// defaultType is the type of a variable without a type constraint, guessed from its default value.
+func defaultType(expr hclsyntax.Expression) string {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || val.IsNull() {
+		return "any"
+	}
+	switch ty := val.Type(); {
+	case ty == cty.String:
+		return "string"
+	case ty == cty.Number:
+		return "number"
+	case ty == cty.Bool:
+		return "bool"
+	case ty.IsObjectType() || ty.IsMapType():
+		return "map"
+	case ty.IsTupleType() || ty.IsListType() || ty.IsSetType():
+		return "list"
+	}
+	return "any"
+}
+
+// formatDefault renders a default value as indented JSON, the way Terraform shows values of variables.
+func formatDefault(expr hclsyntax.Expression, src []byte) string {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return string(expr.Range().SliceBytes(src))
+	}
+	if val.IsNull() {
+		return "null"
+	}
+	js, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return string(expr.Range().SliceBytes(src))
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, js, "", "  "); err != nil {
+		return string(js)
+	}
+	return buf.String()
+}
+
+func stringAttribute(body *hclsyntax.Body, name string) string {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || val.IsNull() {
+		return ""
+	}
+	return val.AsString()
+}
+
+func boolAttribute(body *hclsyntax.Body, name string) bool {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return false
+	}
+	val, diags := attr.Expr.Value(nil)
+	return !diags.HasErrors() && val.Type() == cty.Bool && !val.IsNull() && val.True()
+}
+
+// versionedRequirements tells whether any required provider has a version constraint,
+// providers without one are not listed as requirements.
+func (m *Module) versionedRequirements() bool {
+	for _, p := range m.Requirements {
+		if p.Version != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Render renders the reference of the module, without the markers.
+func Render(m *Module, opts Options) string {
+	var sb strings.Builder
+	w := func(format string, args ...interface{}) {
+		fmt.Fprintf(&sb, format, args...)
+	}
+
+	w("### Requirements\n\n")
+	if m.TerraformVersion == "" && !m.versionedRequirements() {
+		w("No requirements.\n")
+	} else {
+		w("| Name | Version |\n|------|---------|\n")
+		if m.TerraformVersion != "" {
+			w("| %s | %s |\n", anchor("requirement", "terraform"), m.TerraformVersion)
+		}
+		for _, p := range m.Requirements {
+			if p.Version != "" {
+				w("| %s | %s |\n", anchor("requirement", p.Name), p.Version)
+			}
+		}
+	}
+
+	w("\n### Providers\n\n")
+	if len(m.Providers) == 0 {
+		w("No providers.\n")
+	} else {
+		w("| Name | Version |\n|------|---------|\n")
+		for _, p := range m.Providers {
+			w("| %s | %s |\n", anchor("provider", p.Name), orNA(p.Version))
+		}
+	}
+
+	w("\n### Modules\n\n")
+	if len(m.Modules) == 0 {
+		w("No modules.\n")
+	} else {
+		w("| Name | Source | Version |\n|------|--------|---------|\n")
+		for _, c := range m.Modules {
+			w("| %s | %s | %s |\n", anchor("module", c.Name), c.Source, orNA(c.Version))
+		}
+	}
+
+	w("\n### Resources\n\n")
+	if len(m.Resources) == 0 {
+		w("No resources.\n")
+	} else {
+		w("| Name | Type |\n|------|------|\n")
+		for _, r := range m.Resources {
+			w("| [%s.%s](%s) | %s |\n", resourceType(r), r.Name, m.resourceURL(r), map[bool]string{false: "resource", true: "data source"}[r.Data])
+		}
+	}
+
+	w("\n### Inputs\n\n")
+	if len(m.Variables) == 0 {
+		w("No inputs.\n")
+	} else {
+		sensitive := false
+		for _, v := range m.Variables {
+			sensitive = sensitive || (opts.Sensitive && v.Sensitive)
+		}
+		if sensitive {
+			w("| Name | Description | Type | Default | Required | Sensitive |\n|------|-------------|------|---------|:--------:|:---------:|\n")
+		} else {
+			w("| Name | Description | Type | Default | Required |\n|------|-------------|------|---------|:--------:|\n")
+		}
+		for _, v := range m.Variables {
+			def := "n/a"
+			if !v.Required {
+				def = code(v.Default)
+			}
+			w("| %s | %s | %s | %s | %s |", anchor("input", v.Name), description(v.Description), code(v.Type), def, yesNo(v.Required))
+			if sensitive {
+				w(" %s |", yesNo(v.Sensitive))
+			}
+			w("\n")
+		}
+	}
+
+	w("\n### Outputs\n\n")
+	if len(m.Outputs) == 0 {
+		w("No outputs.\n")
+	} else {
+		sensitive := false
+		for _, o := range m.Outputs {
+			sensitive = sensitive || (opts.Sensitive && o.Sensitive)
+		}
+		if sensitive {
+			w("| Name | Description | Sensitive |\n|------|-------------|:---------:|\n")
+		} else {
+			w("| Name | Description |\n|------|-------------|\n")
+		}
+		for _, o := range m.Outputs {
+			w("| %s | %s |", anchor("output", o.Name), description(o.Description))
+			if sensitive {
+				w(" %s |", yesNo(o.Sensitive))
+			}
+			w("\n")
+		}
+	}
+	return sb.String()
+}
+
+// resourceURL links the documentation of a resource in the Terraform Registry.
+func (m *Module) resourceURL(r Resource) string {
+	provider, kind := strings.SplitN(r.Type, "_", 2), "resources"
+	if r.Data {
+		kind = "data-sources"
+	}
+	source := "hashicorp/" + provider[0]
+	for _, p := range m.Requirements {
+		if p.Name == provider[0] && p.Source != "" {
+			source = p.Source
+		}
+	}
+	name := r.Type
+	if len(provider) == 2 {
+		name = provider[1]
+	}
+	return fmt.Sprintf("https://registry.terraform.io/providers/%s/latest/docs/%s/%s", source, kind, name)
+}
+
+// resourceType prefixes the type of a resource with its provider, also when the type is the name of the provider,
+// i.e. `http_http` for the `http` data source.
+func resourceType(r Resource) string {
+	provider := strings.SplitN(r.Type, "_", 2)[0]
+	return provider + "_" + strings.TrimPrefix(r.Type, provider+"_")
+}
+
+func anchor(kind, name string) string {
+	escaped := strings.ReplaceAll(name, "_", "\\_")
+	return fmt.Sprintf(`<a name="%s_%s"></a> [%s](#%s\_%s)`, kind, name, escaped, kind, escaped)
+}
+
+// code renders a type or a default value, multi-line values as a preformatted block.
+func code(s string) string {
+	if strings.Contains(s, "\n") {
+		return "<pre>" + strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", "<br>") + "</pre>"
+	}
+	return "`" + s + "`"
+}
+
+func orNA(s string) string {
+	if s == "" {
+		return "n/a"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// Update replaces the reference between the markers of the README in dir. It returns false when the README
+// is up to date.
+func Update(dir string, opts Options) (bool, error) {
+	path, current, updated, err := update(dir, opts)
+	if err != nil || current == updated {
+		return false, err
+	}
+	return true, os.WriteFile(path, []byte(updated), 0644)
+}
+
+// Check returns an error when the reference in the README in dir is not up to date.
+func Check(dir string, opts Options) error {
+	path, current, updated, err := update(dir, opts)
+	if err != nil {
+		return err
+	}
+	if current != updated {
+		command := "go run ./cmd/readme"
+		if opts.Sensitive {
+			command += " -sensitive"
+		}
+		return fmt.Errorf("%s is not up to date, run: %s %s\n%s", path, command, dir, firstDifference(current, updated))
+	}
+	return nil
+}
+
+func update(dir string, opts Options) (path, current, updated string, err error) {
+	m, err := Load(dir)
+	if err != nil {
+		return "", "", "", err
+	}
+	path = filepath.Join(dir, "README.md")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", "", err
+	}
+	current = string(content)
+	begin := strings.Index(current, BeginMarker)
+	end := strings.Index(current, EndMarker)
+	if begin < 0 || end < begin {
+		return "", "", "", fmt.Errorf("%s has no %s and %s markers", path, BeginMarker, EndMarker)
+	}
+	updated = current[:begin+len(BeginMarker)] + "\n" + Render(m, opts) + current[end:]
+	return path, current, updated, nil
+}
+
+// firstDifference shows the first line which differs, a README is usually stale in a single table row.
+func firstDifference(current, updated string) string {
+	a, b := strings.Split(current, "\n"), strings.Split(updated, "\n")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, x, y)
+		}
+	}
+	return ""
+}
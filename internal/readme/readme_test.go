@@ -0,0 +1,120 @@
+package readme
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	if err := Check("testdata/module", Options{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := filepath.Glob("testdata/module/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := strings.Replace(string(golden), "A password of the administrator", "A password", 1)
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Check(dir, Options{})
+	if err == nil || !strings.Contains(err.Error(), "+ | <a name=\"input_password\">") {
+		t.Errorf("got error %v, want the stale row of var.password", err)
+	}
+	updated, err := Update(dir, Options{})
+	if err != nil || !updated {
+		t.Fatalf("got %v, %v, want the README updated", updated, err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(golden) {
+		t.Errorf("got README:\n%s\nwant:\n%s", content, golden)
+	}
+	if updated, err := Update(dir, Options{}); err != nil || updated {
+		t.Errorf("got %v, %v, want an up to date README left intact", updated, err)
+	}
+}
+
+func TestRenderSensitive(t *testing.T) {
+	m, err := Load("testdata/module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Render(m, Options{Sensitive: true})
+	for _, want := range []string{
+		"| Name | Description | Type | Default | Required | Sensitive |\n",
+		"| `string` | `null` | no | yes |\n",
+		"| `{}` | no | no |\n",
+		"| Name | Description | Sensitive |\n",
+		"[password](#output\\_password) | n/a | yes |\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got reference:\n%s\nwant it to contain %q", got, want)
+		}
+	}
+
+	// the column is added only to tables with a sensitive row
+	m.Variables = m.Variables[:1]
+	m.Outputs = m.Outputs[:1]
+	if got := Render(m, Options{Sensitive: true}); got != Render(m, Options{}) {
+		t.Errorf("got a Sensitive column for a module without sensitive inputs and outputs:\n%s", got)
+	}
+}
+
+func TestDescription(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"", "n/a"},
+		{"Name of the `resource_group_name`.", "Name of the `resource_group_name`."},
+		{"A resource_group | a **bold** word.", `A resource\_group \| a **bold** word.`},
+		{"First line\n  indented.\n\nNext paragraph.", "First line<br>  indented.<br><br>Next paragraph."},
+		{"See https://example.com/a_b.", "See https://example.com/a_b."},
+		{"Example:\n```\n  a = {\n    b = 1\n  }\n```\n", "Example:<pre>a = {<br>    b = 1<br>  }</pre>"},
+		{"- `a : b.\n- `c_d` : e.\n\nExample:\n```\na = 1\n```\n", "- `a : b.<br>- `c\\_d` : e.<br><br>Example:<br>`<pre>a = 1</pre>"},
+	} {
+		if got := description(tc.in); got != tc.want {
+			t.Errorf("description(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestModules fails when a README of a module or an example is not up to date, run `go run ./cmd/readme` to fix it.
+func TestModules(t *testing.T) {
+	modules, err := filepath.Glob("../../modules/*/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples, err := filepath.Glob("../../examples/*/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(modules, examples...) {
+		dir := filepath.Dir(path)
+		t.Run(filepath.Base(filepath.Dir(dir))+"/"+filepath.Base(dir), func(t *testing.T) {
+			if err := Check(dir, Options{}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
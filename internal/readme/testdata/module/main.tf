@@ -0,0 +1,18 @@
+data "azurerm_resource_group" "this" {
+  name = var.resource_group_name
+}
+
+resource "random_password" "this" {
+  length = 16
+}
+
+resource "azurerm_public_ip" "this" {
+  for_each = var.public_ips
+
+  name                = each.key
+  location            = data.azurerm_resource_group.this.location
+  resource_group_name = data.azurerm_resource_group.this.name
+  allocation_method   = "Static"
+  sku                 = each.value.sku
+  tags                = var.tags
+}
@@ -0,0 +1,9 @@
+output "public_ips" {
+  description = "IP addresses of the created Public IPs."
+  value       = { for k, v in azurerm_public_ip.this : k => v.ip_address }
+}
+
+output "password" {
+  value     = random_password.this.result
+  sensitive = true
+}
@@ -0,0 +1,35 @@
+variable "resource_group_name" {
+  description = "Name of the existing Resource Group."
+  type        = string
+}
+
+variable "public_ips" {
+  description = <<-EOF
+  A map of Public IPs to create, keyed by their names:
+
+  - `sku` - (`string`, optional, defaults to `Standard`) the SKU, `Basic` or `Standard`.
+
+  Example:
+  ```
+  public_ips = {
+    "mgmt" = { sku = "Standard" }
+  }
+  ```
+  EOF
+  type = map(object({
+    sku = optional(string, "Standard")
+  }))
+  default = {}
+}
+
+variable "tags" {
+  description = "Tags assigned to created resources, see https://docs.microsoft.com/en-us/azure/azure_resource_manager."
+  default     = { "owner" = "me" }
+}
+
+variable "password" {
+  description = "A password of the administrator | the `admin_password` argument."
+  type        = string
+  sensitive   = true
+  default     = null
+}
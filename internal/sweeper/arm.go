@@ -0,0 +1,213 @@
+package sweeper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultEndpoint is the Azure Resource Manager endpoint of the public cloud.
+	DefaultEndpoint = "https://management.azure.com"
+
+	resourcesAPIVersion = "2021-04-01"
+	storageAPIVersion   = "2023-01-01"
+)
+
+// TokenSource returns a bearer token for the Resource Manager API.
+type TokenSource func(ctx context.Context) (string, error)
+
+// ARMClient is a Client calling the Azure Resource Manager REST API.
+type ARMClient struct {
+	// Endpoint defaults to DefaultEndpoint.
+	Endpoint       string
+	SubscriptionID string
+	Token          TokenSource
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// armError is the error body returned by the Resource Manager.
+type armError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ARMClient) endpoint() string {
+	if c.Endpoint == "" {
+		return DefaultEndpoint
+	}
+	return strings.TrimSuffix(c.Endpoint, "/")
+}
+
+// do sends a request to the path relative to the subscription and decodes a JSON response into out, when given.
+func (c *ARMClient) do(ctx context.Context, method, pathOrURL string, out interface{}) error {
+	u := pathOrURL
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		u = c.endpoint() + "/subscriptions/" + url.PathEscape(c.SubscriptionID) + pathOrURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	token, err := c.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get an access token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e armError
+		if json.Unmarshal(body, &e) == nil && e.Error.Code != "" {
+			return fmt.Errorf("%s %s: %s (%s): %s", method, req.URL.Path, resp.Status, e.Error.Code, e.Error.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("cannot decode response of %s %s: %w", method, req.URL.Path, err)
+		}
+	}
+	return nil
+}
+
+// list follows next links of a paged list and calls add for every page. A next link to another host than
+// the endpoint is rejected, the access token is not sent anywhere else.
+func (c *ARMClient) list(ctx context.Context, path string, add func(raw json.RawMessage) error) error {
+	endpoint, err := url.Parse(c.endpoint())
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	for next := path; next != ""; {
+		var page struct {
+			Value    []json.RawMessage `json:"value"`
+			NextLink string            `json:"nextLink"`
+		}
+		if err := c.do(ctx, http.MethodGet, next, &page); err != nil {
+			return err
+		}
+		for _, v := range page.Value {
+			if err := add(v); err != nil {
+				return err
+			}
+		}
+		if page.NextLink != "" {
+			u, err := url.Parse(page.NextLink)
+			if err != nil {
+				return fmt.Errorf("invalid nextLink %q: %w", page.NextLink, err)
+			}
+			if u.Scheme != endpoint.Scheme || !strings.EqualFold(u.Host, endpoint.Host) {
+				return fmt.Errorf("nextLink %s is not on the endpoint %s, refusing to follow it", u.Redacted(), endpoint.Redacted())
+			}
+		}
+		next = page.NextLink
+	}
+	return nil
+}
+
+// ListResourceGroups implements Client.
+func (c *ARMClient) ListResourceGroups(ctx context.Context) ([]ResourceGroup, error) {
+	var groups []ResourceGroup
+	err := c.list(ctx, "/resourcegroups?api-version="+resourcesAPIVersion, func(raw json.RawMessage) error {
+		var rg struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &rg); err != nil {
+			return err
+		}
+		groups = append(groups, ResourceGroup{Name: rg.Name})
+		return nil
+	})
+	return groups, err
+}
+
+// ResourceGroupCreated implements Client. The Resource Manager does not report when a Resource Group was created,
+// the creation time of the oldest resource in the group is used instead.
+func (c *ARMClient) ResourceGroupCreated(ctx context.Context, name string) (time.Time, error) {
+	var oldest time.Time
+	path := fmt.Sprintf("/resourceGroups/%s/resources?api-version=%s&$expand=createdTime", url.PathEscape(name), resourcesAPIVersion)
+	err := c.list(ctx, path, func(raw json.RawMessage) error {
+		var r struct {
+			CreatedTime time.Time `json:"createdTime"`
+		}
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		if !r.CreatedTime.IsZero() && (oldest.IsZero() || r.CreatedTime.Before(oldest)) {
+			oldest = r.CreatedTime
+		}
+		return nil
+	})
+	return oldest, err
+}
+
+// ListStorageAccounts implements Client.
+func (c *ARMClient) ListStorageAccounts(ctx context.Context) ([]StorageAccount, error) {
+	var accounts []StorageAccount
+	err := c.list(ctx, "/providers/Microsoft.Storage/storageAccounts?api-version="+storageAPIVersion, func(raw json.RawMessage) error {
+		var sa struct {
+			ID         string `json:"id"`
+			Name       string `json:"name"`
+			Properties struct {
+				CreationTime time.Time `json:"creationTime"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(raw, &sa); err != nil {
+			return err
+		}
+		accounts = append(accounts, StorageAccount{
+			Name:          sa.Name,
+			ResourceGroup: resourceGroupFromID(sa.ID),
+			CreatedTime:   sa.Properties.CreationTime,
+		})
+		return nil
+	})
+	return accounts, err
+}
+
+// resourceGroupFromID returns the Resource Group name from a resource ID.
+func resourceGroupFromID(id string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// DeleteResourceGroup implements Client. The deletion is asynchronous, it is only started.
+func (c *ARMClient) DeleteResourceGroup(ctx context.Context, name string) error {
+	path := fmt.Sprintf("/resourcegroups/%s?api-version=%s", url.PathEscape(name), resourcesAPIVersion)
+	return c.do(ctx, http.MethodDelete, path, nil)
+}
+
+// DeleteStorageAccount implements Client.
+func (c *ARMClient) DeleteStorageAccount(ctx context.Context, resourceGroup, name string) error {
+	path := fmt.Sprintf("/resourceGroups/%s/providers/Microsoft.Storage/storageAccounts/%s?api-version=%s",
+		url.PathEscape(resourceGroup), url.PathEscape(name), storageAPIVersion)
+	return c.do(ctx, http.MethodDelete, path, nil)
+}
@@ -0,0 +1,273 @@
+// Package sweeper finds and deletes Azure resources leaked by killed or failed test runs.
+//
+// Tests name their Resource Groups and Storage Accounts with random names. The sweeper recognizes
+// such names with a Scheme, and deletes only the resources older than a given age, so that resources
+// of tests that are still running are left intact.
+package sweeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResourceGroup is a Resource Group as listed by the Resource Manager.
+type ResourceGroup struct {
+	Name string
+}
+
+// StorageAccount is a Storage Account as listed by the Resource Manager.
+type StorageAccount struct {
+	Name          string
+	ResourceGroup string
+	CreatedTime   time.Time
+}
+
+// Client is the subset of the Azure Resource Manager API used by the sweeper.
+type Client interface {
+	ListResourceGroups(ctx context.Context) ([]ResourceGroup, error)
+	// ResourceGroupCreated returns the creation time of the oldest resource in a Resource Group,
+	// or a zero time when the Resource Group is empty.
+	ResourceGroupCreated(ctx context.Context, name string) (time.Time, error)
+	ListStorageAccounts(ctx context.Context) ([]StorageAccount, error)
+	DeleteResourceGroup(ctx context.Context, name string) error
+	DeleteStorageAccount(ctx context.Context, resourceGroup, name string) error
+}
+
+// Scheme matches names generated for test runs.
+type Scheme struct {
+	// ResourceGroup and StorageAccount are nil when resources of the kind are not to be swept.
+	ResourceGroup  *regexp.Regexp
+	StorageAccount *regexp.Regexp
+}
+
+// MinAnchor is the minimum length of the parts common to all generated names. A pattern matching
+// only random characters would match any resource of the same length, not just the ones of tests.
+const MinAnchor = 4
+
+// ErrNoAnchor is returned for samples with too short a common part to recognize names of tests.
+var ErrNoAnchor = errors.New("names do not have a common part long enough to be recognized")
+
+// InferScheme builds a Scheme from names generated for a number of test runs. Resource Group names
+// are matched case-insensitively, like Azure does.
+func InferScheme(resourceGroups, storageAccounts []string) (Scheme, error) {
+	rg, err := InferPattern(resourceGroups, true)
+	if err != nil {
+		return Scheme{}, fmt.Errorf("resource group names: %w", err)
+	}
+	sa, err := InferPattern(storageAccounts, false)
+	if err != nil {
+		return Scheme{}, fmt.Errorf("storage account names: %w", err)
+	}
+	return Scheme{ResourceGroup: rg, StorageAccount: sa}, nil
+}
+
+// InferPattern builds a pattern from names generated for a number of test runs. Parts of the names common
+// to all samples are matched literally, the random part in between is matched with the characters and lengths
+// seen in the samples. ErrNoAnchor is returned when the common parts are shorter than MinAnchor.
+func InferPattern(samples []string, ignoreCase bool) (*regexp.Regexp, error) {
+	if len(samples) < 2 {
+		return nil, errors.New("at least 2 samples are required")
+	}
+
+	prefix := samples[0]
+	for _, s := range samples[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// the suffix is searched for only after the prefix, so that they do not overlap
+	rests := make([]string, len(samples))
+	for i, s := range samples {
+		rests[i] = s[len(prefix):]
+	}
+	suffix := rests[0]
+	for _, s := range rests[1:] {
+		for !strings.HasSuffix(s, suffix) {
+			suffix = suffix[1:]
+		}
+	}
+
+	minLen, maxLen := -1, 0
+	chars := map[rune]bool{}
+	for _, s := range rests {
+		random := s[:len(s)-len(suffix)]
+		if minLen < 0 || len(random) < minLen {
+			minLen = len(random)
+		}
+		if len(random) > maxLen {
+			maxLen = len(random)
+		}
+		for _, r := range random {
+			chars[r] = true
+		}
+	}
+	if maxLen == 0 {
+		return nil, fmt.Errorf("samples do not contain a random part: %q", samples)
+	}
+	if len(prefix)+len(suffix) < MinAnchor {
+		return nil, fmt.Errorf("%w, common prefix %q and suffix %q: %q", ErrNoAnchor, prefix, suffix, samples)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if ignoreCase {
+		sb.WriteString("(?i)")
+	}
+	sb.WriteString(regexp.QuoteMeta(prefix))
+	sb.WriteString(charClass(chars))
+	if minLen == maxLen {
+		fmt.Fprintf(&sb, "{%d}", minLen)
+	} else {
+		fmt.Fprintf(&sb, "{%d,%d}", minLen, maxLen)
+	}
+	sb.WriteString(regexp.QuoteMeta(suffix))
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// charClass returns a character class covering whole ranges of letters and digits seen in the samples,
+// a random value is not expected to contain all of them. Other characters are listed as they are.
+func charClass(chars map[rune]bool) string {
+	var ranges, others []string
+	seen := map[string]bool{}
+	for r := range chars {
+		var class string
+		switch {
+		case r >= 'a' && r <= 'z':
+			class = "a-z"
+		case r >= 'A' && r <= 'Z':
+			class = "A-Z"
+		case r >= '0' && r <= '9':
+			class = "0-9"
+		default:
+			others = append(others, regexp.QuoteMeta(string(r)))
+			continue
+		}
+		if !seen[class] {
+			seen[class] = true
+			ranges = append(ranges, class)
+		}
+	}
+	// a hyphen is a literal only at the end of a class
+	sort.Slice(others, func(i, j int) bool { return others[j] == "-" || (others[i] != "-" && others[i] < others[j]) })
+	sort.Strings(ranges)
+	return "[" + strings.Join(ranges, "") + strings.Join(others, "") + "]"
+}
+
+// Options control which of the matching resources are reported as leaked.
+type Options struct {
+	// MinAge is the age a resource has to reach to be considered leaked.
+	MinAge time.Duration
+	// IncludeEmpty reports empty Resource Groups, their age is not known.
+	IncludeEmpty bool
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+}
+
+// Kinds of leaked resources.
+const (
+	KindResourceGroup  = "resource group"
+	KindStorageAccount = "storage account"
+)
+
+// Leak is a resource left behind by a test run.
+type Leak struct {
+	Kind          string
+	Name          string
+	ResourceGroup string
+	// Age is zero when it is not known.
+	Age time.Duration
+}
+
+func (l Leak) String() string {
+	age := "unknown age"
+	if l.Age > 0 {
+		age = l.Age.Truncate(time.Minute).String()
+	}
+	if l.Kind == KindStorageAccount {
+		return fmt.Sprintf("%s %s in %s (%s)", l.Kind, l.Name, l.ResourceGroup, age)
+	}
+	return fmt.Sprintf("%s %s (%s)", l.Kind, l.Name, age)
+}
+
+// Find lists Resource Groups and Storage Accounts matching the scheme and older than the minimum age, a kind
+// without a pattern is not listed.
+// Storage Accounts placed in leaked Resource Groups are not listed, they are deleted together with the group.
+func Find(ctx context.Context, client Client, scheme Scheme, opts Options) ([]Leak, error) {
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+
+	var leaks []Leak
+	leakedGroups := map[string]bool{}
+
+	groups, err := client.ListResourceGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list resource groups: %w", err)
+	}
+	for _, rg := range groups {
+		if scheme.ResourceGroup == nil || !scheme.ResourceGroup.MatchString(rg.Name) {
+			continue
+		}
+		created, err := client.ResourceGroupCreated(ctx, rg.Name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read resources of %s: %w", rg.Name, err)
+		}
+		leak := Leak{Kind: KindResourceGroup, Name: rg.Name}
+		if created.IsZero() {
+			if !opts.IncludeEmpty {
+				continue
+			}
+		} else {
+			leak.Age = now().Sub(created)
+			if leak.Age < opts.MinAge {
+				continue
+			}
+		}
+		leaks = append(leaks, leak)
+		leakedGroups[strings.ToLower(rg.Name)] = true
+	}
+
+	accounts, err := client.ListStorageAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list storage accounts: %w", err)
+	}
+	for _, sa := range accounts {
+		if scheme.StorageAccount == nil || !scheme.StorageAccount.MatchString(sa.Name) || leakedGroups[strings.ToLower(sa.ResourceGroup)] {
+			continue
+		}
+		age := now().Sub(sa.CreatedTime)
+		if sa.CreatedTime.IsZero() || age < opts.MinAge {
+			continue
+		}
+		leaks = append(leaks, Leak{Kind: KindStorageAccount, Name: sa.Name, ResourceGroup: sa.ResourceGroup, Age: age})
+	}
+
+	return leaks, nil
+}
+
+// Sweep deletes the leaked resources. It tries to delete all of them, errors are returned together.
+func Sweep(ctx context.Context, client Client, leaks []Leak) error {
+	var errs []error
+	for _, l := range leaks {
+		var err error
+		switch l.Kind {
+		case KindResourceGroup:
+			err = client.DeleteResourceGroup(ctx, l.Name)
+		case KindStorageAccount:
+			err = client.DeleteStorageAccount(ctx, l.ResourceGroup, l.Name)
+		default:
+			err = fmt.Errorf("unknown kind %q", l.Kind)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot delete %s: %w", l, err))
+		}
+	}
+	return errors.Join(errs...)
+}
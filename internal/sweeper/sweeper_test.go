@@ -0,0 +1,292 @@
+package sweeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeARM is a minimal, in memory implementation of the Resource Manager API endpoints used by ARMClient.
+type fakeARM struct {
+	mu sync.Mutex
+	// groups maps a Resource Group name to creation times of its resources
+	groups   map[string][]time.Time
+	accounts []map[string]interface{}
+	deleted  []string
+}
+
+func (f *fakeARM) handler(t *testing.T) http.Handler {
+	const sub = "/subscriptions/00000000-0000-0000-0000-000000000000"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"code": "InvalidAuthenticationToken", "message": "missing token"}}`)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, sub)
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		switch {
+		case r.Method == http.MethodGet && path == "/resourcegroups":
+			// every group on a separate page, to exercise paging
+			var names []string
+			for name := range f.groups {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			page := 0
+			fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+			resp := map[string]interface{}{"value": []interface{}{}}
+			if page < len(names) {
+				resp["value"] = []interface{}{map[string]string{"name": names[page]}}
+				if page+1 < len(names) {
+					resp["nextLink"] = fmt.Sprintf("http://%s%s/resourcegroups?api-version=2021-04-01&page=%d", r.Host, sub, page+1)
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "resourceGroups" && parts[2] == "resources":
+			if r.URL.Query().Get("$expand") != "createdTime" {
+				t.Errorf("resources listed without createdTime")
+			}
+			var value []interface{}
+			for _, created := range f.groups[parts[1]] {
+				value = append(value, map[string]string{"createdTime": created.Format(time.RFC3339)})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+		case r.Method == http.MethodGet && path == "/providers/Microsoft.Storage/storageAccounts":
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": f.accounts})
+		case r.Method == http.MethodDelete && len(parts) == 2 && parts[0] == "resourcegroups":
+			if _, ok := f.groups[parts[1]]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprintf(w, `{"error": {"code": "ResourceGroupNotFound", "message": "Resource group '%s' could not be found."}}`, parts[1])
+				return
+			}
+			f.deleted = append(f.deleted, "rg/"+parts[1])
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete && len(parts) == 6 && parts[4] == "storageAccounts":
+			f.deleted = append(f.deleted, "sa/"+parts[1]+"/"+parts[5])
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	})
+}
+
+func newTestClient(t *testing.T, fake *fakeARM) *ARMClient {
+	server := httptest.NewServer(fake.handler(t))
+	t.Cleanup(server.Close)
+	return &ARMClient{
+		Endpoint:       server.URL,
+		SubscriptionID: "00000000-0000-0000-0000-000000000000",
+		Token:          func(context.Context) (string, error) { return "test-token", nil },
+		HTTPClient:     server.Client(),
+	}
+}
+
+func testScheme(t *testing.T) Scheme {
+	scheme, err := InferScheme(
+		[]string{"ghci8k2pa9-rg", "ghcix01bbz-rg", "ghci7fzz3q-rg"},
+		[]string{"ghci8k2pa9sa", "ghcix01bbzsa", "ghci7fzz3qsa"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestInferScheme(t *testing.T) {
+	scheme := testScheme(t)
+
+	if got, want := scheme.ResourceGroup.String(), `^(?i)ghci[0-9a-z]{6}-rg$`; got != want {
+		t.Errorf("resource group pattern = %s, want %s", got, want)
+	}
+	if got, want := scheme.StorageAccount.String(), `^ghci[0-9a-z]{6}sa$`; got != want {
+		t.Errorf("storage account pattern = %s, want %s", got, want)
+	}
+	for name, want := range map[string]bool{
+		"ghciabc123-rg":   true,
+		"GHCIABC123-RG":   true,
+		"ghciabc12-rg":    false,
+		"ghci-prod-rg":    false,
+		"example-rg":      false,
+		"ghciabc123-rg-2": false,
+	} {
+		if got := scheme.ResourceGroup.MatchString(name); got != want {
+			t.Errorf("match %s = %v, want %v", name, got, want)
+		}
+	}
+
+	if _, err := InferScheme([]string{"a-rg", "a-rg"}, []string{"a", "b"}); err == nil {
+		t.Error("expected an error for samples without a random part")
+	}
+	if _, err := InferScheme([]string{"a-rg"}, []string{"a", "b"}); err == nil {
+		t.Error("expected an error for a single sample")
+	}
+}
+
+// TestInferSchemeNoAnchor uses names shaped like the skeleton's Storage Account names, random characters only.
+func TestInferSchemeNoAnchor(t *testing.T) {
+	accounts := []string{"k3x9qa1mz0b7t", "p0d8s2lw5nq4e", "a7c1v9ty3mu6r"}
+	if _, err := InferPattern(accounts, false); !errors.Is(err, ErrNoAnchor) {
+		t.Errorf("got error %v, want ErrNoAnchor", err)
+	}
+	if _, err := InferScheme([]string{"ghci8k2pa9-rg", "ghcix01bbz-rg"}, accounts); !errors.Is(err, ErrNoAnchor) {
+		t.Errorf("got error %v, want ErrNoAnchor", err)
+	}
+	// a short suffix alone would match Resource Groups of anyone
+	if _, err := InferPattern([]string{"k3x9qa1-rg", "p0d8s2l-rg"}, true); !errors.Is(err, ErrNoAnchor) {
+		t.Errorf("got error %v, want ErrNoAnchor", err)
+	}
+
+	// without a pattern, nothing of the kind is found, even when old enough
+	fake := &fakeARM{
+		groups: map[string][]time.Time{"ghciold001-rg": {now.Add(-5 * time.Hour)}},
+		accounts: []map[string]interface{}{
+			{"name": "k3x9qa1mz0b7t", "id": "/subscriptions/x/resourceGroups/shared-rg/providers/Microsoft.Storage/storageAccounts/k3x9qa1mz0b7t",
+				"properties": map[string]string{"creationTime": now.Add(-300 * time.Hour).Format(time.RFC3339)}},
+		},
+	}
+	scheme := testScheme(t)
+	scheme.StorageAccount = nil
+	leaks, err := Find(context.Background(), newTestClient(t, fake), scheme, Options{MinAge: 2 * time.Hour, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaks) != 1 || leaks[0].Kind != KindResourceGroup {
+		t.Errorf("got leaks %v, want the resource group only", leaks)
+	}
+}
+
+func TestInferSchemeHyphen(t *testing.T) {
+	re, err := InferPattern([]string{"x-ab-cd-y", "x-q1-zz-y", "x-bb-0a-y"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := re.String(), `^x-[0-9a-z-]{5}-y$`; got != want {
+		t.Errorf("pattern = %s, want %s", got, want)
+	}
+}
+
+func TestFindAndSweep(t *testing.T) {
+	fake := &fakeARM{
+		groups: map[string][]time.Time{
+			"ghciold001-rg": {now.Add(-5 * time.Hour), now.Add(-4 * time.Hour)},
+			"ghcinew001-rg": {now.Add(-10 * time.Minute)},
+			"ghciempty1-rg": nil,
+			"production-rg": {now.Add(-100 * time.Hour)},
+		},
+		accounts: []map[string]interface{}{
+			// in a leaked group, deleted together with it
+			{"name": "ghciold001sa", "id": "/subscriptions/x/resourceGroups/ghciold001-rg/providers/Microsoft.Storage/storageAccounts/ghciold001sa",
+				"properties": map[string]string{"creationTime": now.Add(-5 * time.Hour).Format(time.RFC3339)}},
+			// leaked in a shared group
+			{"name": "ghcishrd01sa", "id": "/subscriptions/x/resourceGroups/shared-rg/providers/Microsoft.Storage/storageAccounts/ghcishrd01sa",
+				"properties": map[string]string{"creationTime": now.Add(-3 * time.Hour).Format(time.RFC3339)}},
+			// too young
+			{"name": "ghcishrd02sa", "id": "/subscriptions/x/resourceGroups/shared-rg/providers/Microsoft.Storage/storageAccounts/ghcishrd02sa",
+				"properties": map[string]string{"creationTime": now.Add(-time.Minute).Format(time.RFC3339)}},
+			// not created by tests
+			{"name": "prodstorage", "id": "/subscriptions/x/resourceGroups/shared-rg/providers/Microsoft.Storage/storageAccounts/prodstorage",
+				"properties": map[string]string{"creationTime": now.Add(-300 * time.Hour).Format(time.RFC3339)}},
+		},
+	}
+	client := newTestClient(t, fake)
+	ctx := context.Background()
+
+	leaks, err := Find(ctx, client, testScheme(t), Options{MinAge: 2 * time.Hour, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range leaks {
+		got = append(got, l.String())
+	}
+	want := []string{
+		"resource group ghciold001-rg (5h0m0s)",
+		"storage account ghcishrd01sa in shared-rg (3h0m0s)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got leaks:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if err := Sweep(ctx, client, leaks); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(fake.deleted, ","), "rg/ghciold001-rg,sa/shared-rg/ghcishrd01sa"; got != want {
+		t.Errorf("deleted %s, want %s", got, want)
+	}
+
+	// empty groups have no known age, they are reported only on request
+	leaks, err = Find(ctx, client, testScheme(t), Options{MinAge: 2 * time.Hour, IncludeEmpty: true, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaks) != 3 || leaks[0].Name != "ghciempty1-rg" || leaks[0].Age != 0 {
+		t.Errorf("unexpected leaks with empty groups: %v", leaks)
+	}
+}
+
+func TestSweepErrors(t *testing.T) {
+	client := newTestClient(t, &fakeARM{groups: map[string][]time.Time{}})
+
+	err := Sweep(context.Background(), client, []Leak{
+		{Kind: KindResourceGroup, Name: "ghcigone01-rg"},
+		{Kind: KindStorageAccount, Name: "ghcishrd01sa", ResourceGroup: "shared-rg"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "ResourceGroupNotFound") {
+		t.Errorf("expected the Resource Manager error code to be reported, got %v", err)
+	}
+}
+
+func TestARMClientUnauthorized(t *testing.T) {
+	client := newTestClient(t, &fakeARM{})
+	client.Token = func(context.Context) (string, error) { return "wrong", nil }
+
+	_, err := client.ListResourceGroups(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "InvalidAuthenticationToken") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
+
+func TestARMClientForeignNextLink(t *testing.T) {
+	var foreignRequests int
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		foreignRequests++
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": []interface{}{}})
+	}))
+	t.Cleanup(foreign.Close)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"value":    []interface{}{map[string]string{"name": "ghci8k2pa9-rg"}},
+			"nextLink": foreign.URL + "/resourcegroups?page=1",
+		})
+	}))
+	t.Cleanup(server.Close)
+	client := &ARMClient{
+		Endpoint:       server.URL,
+		SubscriptionID: "00000000-0000-0000-0000-000000000000",
+		Token:          func(context.Context) (string, error) { return "test-token", nil },
+		HTTPClient:     server.Client(),
+	}
+
+	_, err := client.ListResourceGroups(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "refusing to follow it") {
+		t.Errorf("expected the nextLink to be rejected, got %v", err)
+	}
+	if foreignRequests != 0 {
+		t.Errorf("the nextLink was followed")
+	}
+}
@@ -0,0 +1,107 @@
+// Package tftemplate renders template files of the Terraform template language without Terraform, the way
+// `templatefile()` does: interpolations, `%{ for }` and `%{ if }` directives, strip markers and the functions
+// of the Terraform language.
+//
+// The examples render their `bootstrap.xml` from templates in a `local_file` resource, with values known only
+// during a plan. Tests render the templates with chosen values instead and inspect the result.
+package tftemplate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduleeval"
+)
+
+// Render renders the template at path, relative to the directory of the calling module dir, like
+// `templatefile(path, vars)` in that module. Every variable the template references has to be given,
+// and so does every value it interpolates, a null value is an error.
+func Render(dir, path string, vars map[string]cty.Value) (string, error) {
+	src, err := os.ReadFile(resolve(dir, path))
+	if err != nil {
+		return "", err
+	}
+	return RenderBytes(dir, path, src, vars)
+}
+
+// RenderBytes renders a template given as src, path is used in errors only.
+func RenderBytes(dir, path string, src []byte, vars map[string]cty.Value) (string, error) {
+	funcs := moduleeval.Functions(dir)
+	// like in Terraform, a template cannot render another template
+	delete(funcs, "templatefile")
+	val, diags := moduleeval.RenderTemplate(path, src, vars, funcs)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	return val.AsString(), nil
+}
+
+// Variables returns names of the variables the template at path references, sorted.
+func Variables(dir, path string) ([]string, error) {
+	src, err := os.ReadFile(resolve(dir, path))
+	if err != nil {
+		return nil, err
+	}
+	expr, diags := hclsyntax.ParseTemplate(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, traversal := range expr.Variables() {
+		if name := traversal.RootName(); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ParseVar parses a variable given as `name=expression`, like the `-var` flag of the commands. The expression is
+// of the Terraform language and cannot reference anything, i.e. a string is quoted: `name="value"`.
+func ParseVar(s string) (string, cty.Value, error) {
+	name, src, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", cty.NilVal, fmt.Errorf("%q is not name=expression", s)
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(src), name, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return "", cty.NilVal, diags
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return "", cty.NilVal, diags
+	}
+	return name, val, nil
+}
+
+// Vars collects variables of repeated `-var name=expression` flags, see ParseVar.
+type Vars map[string]cty.Value
+
+func (v Vars) String() string {
+	return fmt.Sprint(map[string]cty.Value(v))
+}
+
+func (v Vars) Set(s string) error {
+	name, val, err := ParseVar(s)
+	if err != nil {
+		return err
+	}
+	v[name] = val
+	return nil
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,297 @@
+package tftemplate
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRenderBytes(t *testing.T) {
+	for _, tc := range []struct {
+		name, src string
+		vars      map[string]cty.Value
+		want      string
+	}{
+		{
+			name: "interpolation and functions",
+			src:  "gw=${cidrhost(cidr, 1)} ${upper(name)}",
+			vars: map[string]cty.Value{"cidr": cty.StringVal("10.0.1.0/24"), "name": cty.StringVal("fw")},
+			want: "gw=10.0.1.1 FW",
+		},
+		{
+			name: "for with strip markers",
+			src:  "<ip>\n%{ for c in cidrs ~}\n  <entry name=\"${c}\"/>\n%{ endfor ~}\n</ip>\n",
+			vars: map[string]cty.Value{"cidrs": cty.ListVal([]cty.Value{cty.StringVal("10.0.0.0/24"), cty.StringVal("10.0.1.0/24")})},
+			want: "<ip>\n  <entry name=\"10.0.0.0/24\"/>\n  <entry name=\"10.0.1.0/24\"/>\n</ip>\n",
+		},
+		{
+			name: "for over an empty list",
+			src:  "<ip>\n%{ for c in cidrs ~}\n  <entry name=\"${c}\"/>\n%{ endfor ~}\n</ip>\n",
+			vars: map[string]cty.Value{"cidrs": cty.EmptyTupleVal},
+			want: "<ip>\n</ip>\n",
+		},
+		{
+			name: "if null",
+			src:  "a\n%{ if key != null ~}\nkey=${key}\n%{ endif ~}\nb\n",
+			vars: map[string]cty.Value{"key": cty.NullVal(cty.String)},
+			want: "a\nb\n",
+		},
+		{
+			name: "if else",
+			src:  "%{ if n > 1 }many%{ else }one%{ endif }",
+			vars: map[string]cty.Value{"n": cty.NumberIntVal(2)},
+			want: "many",
+		},
+		{
+			name: "literal markers",
+			src:  "$${not} %%{not}",
+			want: "${not} %{not}",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RenderBytes(".", "test.tftpl", []byte(tc.src), tc.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, src, want string
+		vars            map[string]cty.Value
+	}{
+		{
+			name: "missing variable",
+			src:  "${a}${b}",
+			vars: map[string]cty.Value{"a": cty.StringVal("a")},
+			want: `Variable "b" is not given to the template`,
+		},
+		{
+			name: "null interpolated",
+			src:  "key=${key}",
+			vars: map[string]cty.Value{"key": cty.NullVal(cty.String)},
+			want: "null value",
+		},
+		{
+			name: "templatefile",
+			src:  `${templatefile("other.tftpl", {})}`,
+			want: "templatefile",
+		},
+		{
+			name: "unclosed directive",
+			src:  "%{ if true }",
+			want: "test.tftpl:1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := RenderBytes(".", "test.tftpl", []byte(tc.src), tc.vars)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// templateVariables returns names of the variables the `local_file.bootstrap_xml` resource of an example
+// gives to `templatefile()`.
+func templateVariables(t *testing.T, dir string) []string {
+	t.Helper()
+	file, diags := hclparse.NewParser().ParseHCLFile(dir + "/main.tf")
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "resource" || block.Labels[0] != "local_file" || block.Labels[1] != "bootstrap_xml" {
+			continue
+		}
+		call, ok := block.Body.Attributes["content"].Expr.(*hclsyntax.FunctionCallExpr)
+		if !ok || call.Name != "templatefile" || len(call.Args) != 2 {
+			t.Fatal("content of local_file.bootstrap_xml is not a call of templatefile()")
+		}
+		obj, ok := call.Args[1].(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			t.Fatal("variables of templatefile() are not an object")
+		}
+		var names []string
+		for _, item := range obj.Items {
+			names = append(names, objectKey(t, item.KeyExpr))
+		}
+		return names
+	}
+	t.Fatalf("%s has no local_file.bootstrap_xml", dir)
+	return nil
+}
+
+func objectKey(t *testing.T, expr hclsyntax.Expression) string {
+	t.Helper()
+	if key, ok := expr.(*hclsyntax.ObjectConsKeyExpr); ok {
+		expr = key.Wrapped
+	}
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok {
+		t.Fatal("a key of templatefile() variables is not a name")
+	}
+	return traversal.Traversal.RootName()
+}
+
+var aiKey = cty.StringVal("00000000-0000-0000-0000-000000000000")
+
+// examples are the templates of the examples with values like the ones the examples compute during a plan.
+var examples = []struct {
+	dir, template string
+	vars          map[string]cty.Value
+	contains      []string
+}{
+	{
+		dir:      "../../examples/dedicated_vmseries",
+		template: "templates/bootstrap_inbound.tmpl",
+		vars: map[string]cty.Value{
+			"private_azure_router_ip": cty.StringVal("10.0.0.33"),
+			"public_azure_router_ip":  cty.StringVal("10.0.0.65"),
+			"ai_instr_key":            aiKey,
+			"ai_update_interval":      cty.NumberIntVal(5),
+			"private_network_cidr":    cty.StringVal("10.0.0.0/8"),
+			"mgmt_profile_appgw_cidr": cty.TupleVal([]cty.Value{cty.StringVal("10.0.0.128/26")}),
+		},
+		contains: []string{
+			"<ip-address>10.0.0.65</ip-address>",
+			"<ip-address>10.0.0.33</ip-address>",
+			`<entry name="10.0.0.128/26"/>`,
+			"<instrumentation-key>00000000-0000-0000-0000-000000000000</instrumentation-key>",
+		},
+	},
+	{
+		dir:      "../../examples/dedicated_vmseries",
+		template: "templates/bootstrap_obew.tmpl",
+		vars: map[string]cty.Value{
+			"private_azure_router_ip": cty.StringVal("10.0.0.33"),
+			"public_azure_router_ip":  cty.StringVal("10.0.0.65"),
+			"ai_instr_key":            cty.NullVal(cty.String),
+			"ai_update_interval":      cty.NumberIntVal(5),
+			"private_network_cidr":    cty.StringVal("10.0.0.0/8"),
+			"mgmt_profile_appgw_cidr": cty.EmptyTupleVal,
+		},
+		contains: []string{"<destination>10.0.0.0/8</destination>", "<member>10.0.0.0/8</member>"},
+	},
+	{
+		dir:      "../../examples/dedicated_vmseries",
+		template: "templates/bootstrap_common.tmpl",
+		vars: map[string]cty.Value{
+			"private_azure_router_ip": cty.StringVal("10.0.0.33"),
+			"public_azure_router_ip":  cty.StringVal("10.0.0.65"),
+			"ai_instr_key":            aiKey,
+			"ai_update_interval":      cty.NumberIntVal(1),
+			"private_network_cidr":    cty.StringVal("10.0.0.0/8"),
+			"mgmt_profile_appgw_cidr": cty.TupleVal([]cty.Value{cty.StringVal("10.0.0.128/26"), cty.StringVal("10.0.0.192/26")}),
+		},
+		contains: []string{`<entry name="10.0.0.128/26"/>`, `<entry name="10.0.0.192/26"/>`, "<update-interval>1</update-interval>"},
+	},
+	{
+		dir:      "../../examples/gwlb_with_vmseries",
+		template: "templates/bootstrap-gwlb.tftpl",
+		vars: map[string]cty.Value{
+			"data_gateway_ip":    cty.StringVal("10.0.1.1"),
+			"ai_instr_key":       cty.NullVal(cty.String),
+			"ai_update_interval": cty.NumberIntVal(5),
+		},
+		contains: []string{"<ip-address>10.0.1.1</ip-address>"},
+	},
+}
+
+// TestExamples renders templates of the examples, verifies the result is well-formed XML and that the example
+// gives every variable the template references.
+func TestExamples(t *testing.T) {
+	for _, tc := range examples {
+		t.Run(tc.template, func(t *testing.T) {
+			given := templateVariables(t, tc.dir)
+			vars := make([]string, 0, len(tc.vars))
+			for name := range tc.vars {
+				vars = append(vars, name)
+			}
+			if !sameNames(given, vars) {
+				t.Fatalf("the example gives variables %v, the test %v", given, vars)
+			}
+			referenced, err := Variables(tc.dir, tc.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range referenced {
+				if _, ok := tc.vars[name]; !ok {
+					t.Errorf("the template references %s, the example does not give it", name)
+				}
+			}
+
+			got, err := Render(tc.dir, tc.template, tc.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoder := xml.NewDecoder(bytes.NewBufferString(got))
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("rendered template is not well-formed: %v", err)
+				}
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("rendered template does not contain %s", s)
+				}
+			}
+			if ai := strings.Contains(got, "<azure-advanced-metrics>"); ai != !tc.vars["ai_instr_key"].IsNull() {
+				t.Errorf("Application Insights configured: %t, with the instrumentation key %#v", ai, tc.vars["ai_instr_key"])
+			}
+		})
+	}
+}
+
+func sameNames(a, b []string) bool {
+	set := func(names []string) map[string]bool {
+		m := map[string]bool{}
+		for _, n := range names {
+			m[n] = true
+		}
+		return m
+	}
+	return reflect.DeepEqual(set(a), set(b))
+}
+
+func TestVars(t *testing.T) {
+	vars := Vars{}
+	for _, s := range []string{`ip="10.0.1.1"`, "key=null", "interval=5", `cidrs=["10.0.0.0/24"]`} {
+		if err := vars.Set(s); err != nil {
+			t.Errorf("Set(%q): %v", s, err)
+		}
+	}
+	want := map[string]cty.Value{
+		"ip":       cty.StringVal("10.0.1.1"),
+		"key":      cty.NullVal(cty.DynamicPseudoType),
+		"interval": cty.NumberIntVal(5),
+		"cidrs":    cty.TupleVal([]cty.Value{cty.StringVal("10.0.0.0/24")}),
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("got %v, want %v", vars, want)
+	}
+	for name, val := range want {
+		if !vars[name].RawEquals(val) {
+			t.Errorf("got %s = %#v, want %#v", name, vars[name], val)
+		}
+	}
+	for _, s := range []string{"ip", `ip="a`, "ip=name"} {
+		if _, _, err := ParseVar(s); err == nil {
+			t.Errorf("ParseVar(%q) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package varcheck
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Reference describes an attribute of variable files holding a key of a map defined elsewhere in variable files,
+// like `subnet_key` of a VM-Series interface pointing to a subnet of a VNET.
+type Reference struct {
+	// From is a pattern of the referencing attribute, i.e. `vmseries.*.interfaces.*.subnet_key`.
+	// A `*` matches every key of a map and every element of a list.
+	From string
+	// To is the path to the map the key has to be defined in. Segments can be substituted:
+	//   - `$1` is the key matched by the first `*` of From, `$2` by the second one, and so on,
+	//   - `{vnet_key}` is the value of the closest `vnet_key` attribute of objects enclosing the referencing attribute,
+	//   - `{vmseries_common.vnet_key}` is the value at that path,
+	//   - `*` matches every map, the key has to be defined in one of them.
+	To string
+}
+
+// Rules are the references between variables of a single example.
+type Rules struct {
+	References []Reference
+	// Unused are patterns of maps whose keys have to be referenced, i.e. route tables not assigned to any subnet.
+	Unused []string
+}
+
+var (
+	networkRules = Rules{
+		References: []Reference{
+			{From: "vnets.*.subnets.*.network_security_group", To: "vnets.$1.network_security_groups"},
+			{From: "vnets.*.subnets.*.route_table", To: "vnets.$1.route_tables"},
+		},
+		Unused: []string{"vnets.*.network_security_groups", "vnets.*.route_tables"},
+	}
+
+	// unassignedRouteTablesRules is networkRules for an example defining Route Tables it leaves to the user to assign,
+	// like `gwlb_with_vmseries`, assigning them would change the deployed topology.
+	unassignedRouteTablesRules = Rules{
+		References: networkRules.References,
+		Unused:     []string{"vnets.*.network_security_groups"},
+	}
+
+	loadBalancerRules = Rules{
+		References: []Reference{
+			{From: "load_balancers.*.nsg_vnet_key", To: "vnets"},
+			{From: "load_balancers.*.nsg_key", To: "vnets.{nsg_vnet_key}.network_security_groups"},
+			{From: "load_balancers.*.frontend_ips.*.vnet_key", To: "vnets"},
+			{From: "load_balancers.*.frontend_ips.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+		},
+	}
+
+	natgwRules = Rules{
+		References: []Reference{
+			{From: "natgws.*.vnet_key", To: "vnets"},
+			{From: "natgws.*.subnet_keys.*", To: "vnets.{vnet_key}.subnets"},
+		},
+	}
+
+	appgwRules = Rules{
+		References: []Reference{
+			{From: "appgws.*.vnet_key", To: "vnets"},
+			{From: "appgws.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+		},
+	}
+
+	vmseriesRules = Rules{
+		References: []Reference{
+			{From: "vmseries.*.vnet_key", To: "vnets"},
+			{From: "vmseries.*.interfaces.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "vmseries.*.interfaces.*.load_balancer_key", To: "load_balancers"},
+			{From: "vmseries.*.availability_set_key", To: "availability_sets"},
+			{From: "vmseries.*.bootstrap_storage.name", To: "bootstrap_storage"},
+			{From: "vmseries.*.bootstrap_storage.public_snet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "vmseries.*.bootstrap_storage.private_snet_key", To: "vnets.{vnet_key}.subnets"},
+			// a Storage Account is shared by VMs of any VNET
+			{From: "bootstrap_storage.*.public_snet_key", To: "vnets.*.subnets"},
+			{From: "bootstrap_storage.*.private_snet_key", To: "vnets.*.subnets"},
+			{From: "bootstrap_storage.*.storage_allow_vnet_subnets.*.vnet_key", To: "vnets"},
+			{From: "bootstrap_storage.*.storage_allow_vnet_subnets.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+		},
+		Unused: []string{"availability_sets", "bootstrap_storage"},
+	}
+
+	vmssRules = Rules{
+		References: []Reference{
+			{From: "vmss.*.vnet_key", To: "vnets"},
+			{From: "vmss.*.interfaces.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "vmss.*.interfaces.*.load_balancer_key", To: "load_balancers"},
+			{From: "vmss.*.interfaces.*.application_gateway_key", To: "appgws"},
+		},
+	}
+
+	gwlbRules = Rules{
+		References: []Reference{
+			{From: "gateway_load_balancers.*.vnet_key", To: "vnets"},
+			{From: "gateway_load_balancers.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "bootstrap_storages.*.storage_allow_vnet_subnets.*.vnet_key", To: "vnets"},
+			{From: "bootstrap_storages.*.storage_allow_vnet_subnets.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "vmseries_common.vnet_key", To: "vnets"},
+			{From: "vmseries.*.bootstrap_storage.key", To: "bootstrap_storages"},
+			{From: "vmseries.*.availability_set_key", To: "availability_sets"},
+			// all VM-Series are placed in the VNET of `vmseries_common`
+			{From: "vmseries.*.interfaces.*.subnet_key", To: "vnets.{vmseries_common.vnet_key}.subnets"},
+			{From: "vmseries.*.interfaces.*.gwlb_key", To: "gateway_load_balancers"},
+			{From: "vmseries.*.interfaces.*.gwlb_backend_key", To: "gateway_load_balancers.{gwlb_key}.backends"},
+			{From: "load_balancers.*.frontend_ips.*.gwlb_key", To: "gateway_load_balancers"},
+			{From: "appvms.*.vnet_key", To: "vnets"},
+			{From: "appvms.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "appvms.*.load_balancer_key", To: "load_balancers"},
+		},
+		Unused: []string{"availability_sets", "bootstrap_storages"},
+	}
+
+	panoramaRules = Rules{
+		References: []Reference{
+			{From: "panoramas.*.vnet_key", To: "vnets"},
+			{From: "panoramas.*.interfaces.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+		},
+	}
+
+	testInfrastructureRules = Rules{
+		References: []Reference{
+			{From: "test_vms.*.vnet_key", To: "vnets"},
+			{From: "test_vms.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+			{From: "bastions.*.vnet_key", To: "vnets"},
+			{From: "bastions.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+		},
+	}
+)
+
+// ExampleRules are the references between variables of each example, by the name of the example's directory.
+// They follow the wiring of the example's `main.tf`, update them together.
+var ExampleRules = map[string]Rules{
+	"common_vmseries":                  combine(networkRules, natgwRules, loadBalancerRules, appgwRules, vmseriesRules),
+	"common_vmseries_and_autoscale":    combine(networkRules, natgwRules, loadBalancerRules, appgwRules, vmssRules),
+	"dedicated_vmseries":               combine(networkRules, natgwRules, loadBalancerRules, appgwRules, vmseriesRules),
+	"dedicated_vmseries_and_autoscale": combine(networkRules, natgwRules, loadBalancerRules, appgwRules, vmssRules),
+	"gwlb_with_vmseries":               combine(unassignedRouteTablesRules, loadBalancerRules, gwlbRules),
+	"standalone_panorama":              combine(networkRules, panoramaRules),
+	"standalone_vmseries":              combine(networkRules, natgwRules, loadBalancerRules, appgwRules, vmseriesRules),
+	"test_infrastructure":              combine(networkRules, testInfrastructureRules),
+}
+
+func combine(rules ...Rules) Rules {
+	var combined Rules
+	for _, r := range rules {
+		combined.References = append(combined.References, r.References...)
+		combined.Unused = append(combined.Unused, r.Unused...)
+	}
+	return combined
+}
+
+// CheckReferences verifies that keys referenced in variable files are defined and that keys of maps listed
+// in rules.Unused are referenced. Like in Terraform, a variable set in more than one file takes the value
+// from the last one, so a file of the test matrix can be checked together with the example's variable files.
+func CheckReferences(files []string, rules Rules) ([]Problem, error) {
+	root := &node{items: map[string]*node{}, keyRanges: map[string]hcl.Range{}}
+	parser := hclparse.NewParser()
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := parser.ParseHCL(src, path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attrs := file.Body.(*hclsyntax.Body).Attributes
+		for _, name := range sortedAttributes(attrs) {
+			attr := attrs[name]
+			if _, ok := root.items[name]; !ok {
+				root.keys = append(root.keys, name)
+			}
+			root.items[name] = newNode(attr.Expr, name, root)
+			root.keyRanges[name] = attr.NameRange
+		}
+	}
+
+	c := &refChecker{root: root, referenced: map[*node]map[string]bool{}}
+	for _, ref := range rules.References {
+		from := strings.Split(ref.From, ".")
+		root.match(from, nil, func(n *node, captures []string) {
+			c.reference(n, captures, ref)
+		})
+	}
+	for _, pattern := range rules.Unused {
+		root.match(strings.Split(pattern, "."), nil, func(n *node, _ []string) {
+			for _, key := range n.keys {
+				if !c.referenced[n][key] {
+					c.report(n.keyRanges[key], n.path, "key %q is not referenced", key)
+				}
+			}
+		})
+	}
+
+	SortProblems(c.problems)
+	return c.problems, nil
+}
+
+// node is a value of variable files. Objects and tuples are kept with their locations, other values as expressions.
+type node struct {
+	path   string
+	expr   hclsyntax.Expression
+	parent *node
+	// keys are keys of an object in the order of the source
+	keys      []string
+	items     map[string]*node
+	keyRanges map[string]hcl.Range
+	elems     []*node
+}
+
+func newNode(expr hclsyntax.Expression, path string, parent *node) *node {
+	n := &node{path: path, expr: expr, parent: parent}
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		n.items = map[string]*node{}
+		n.keyRanges = map[string]hcl.Range{}
+		for _, item := range e.Items {
+			val, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || val.Type() != cty.String || val.IsNull() {
+				// reported by Check
+				continue
+			}
+			key := val.AsString()
+			if _, ok := n.items[key]; !ok {
+				n.keys = append(n.keys, key)
+			}
+			n.items[key] = newNode(item.ValueExpr, attributePath(path, key), n)
+			n.keyRanges[key] = item.KeyExpr.Range()
+		}
+	case *hclsyntax.TupleConsExpr:
+		for i, el := range e.Exprs {
+			n.elems = append(n.elems, newNode(el, fmt.Sprintf("%s[%d]", path, i), n))
+		}
+	}
+	return n
+}
+
+// str returns the value of a string literal.
+func (n *node) str() (string, bool) {
+	if n.items != nil || n.elems != nil {
+		return "", false
+	}
+	val, diags := n.expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || val.IsNull() || !val.IsKnown() {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// child returns a value of an object by its key or an element of a tuple by its index.
+func (n *node) child(key string) *node {
+	if n.items != nil {
+		return n.items[key]
+	}
+	if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(n.elems) {
+		return n.elems[i]
+	}
+	return nil
+}
+
+// match calls fn for every value matching the pattern, with keys matched by each `*`.
+func (n *node) match(segments []string, captures []string, fn func(*node, []string)) {
+	if len(segments) == 0 {
+		fn(n, captures)
+		return
+	}
+	seg, rest := segments[0], segments[1:]
+	if seg != "*" {
+		if c := n.child(seg); c != nil {
+			c.match(rest, captures, fn)
+		}
+		return
+	}
+	next := func(key string, c *node) {
+		c.match(rest, append(append([]string{}, captures...), key), fn)
+	}
+	for _, key := range n.keys {
+		next(key, n.items[key])
+	}
+	for i, el := range n.elems {
+		next(strconv.Itoa(i), el)
+	}
+}
+
+// lookup returns the string at a path of keys separated by dots.
+func (n *node) lookup(path string) (string, bool) {
+	for _, seg := range strings.Split(path, ".") {
+		if n = n.child(seg); n == nil {
+			return "", false
+		}
+	}
+	return n.str()
+}
+
+// enclosing returns the value of the closest attribute of objects enclosing the node.
+func (n *node) enclosing(name string) (string, bool) {
+	for p := n.parent; p != nil; p = p.parent {
+		if c, ok := p.items[name]; ok {
+			return c.str()
+		}
+	}
+	return "", false
+}
+
+type refChecker struct {
+	root     *node
+	problems []Problem
+	// referenced are keys of maps which are referenced
+	referenced map[*node]map[string]bool
+}
+
+func (c *refChecker) report(rng hcl.Range, path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Range: rng, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// reference verifies a single referencing attribute.
+func (c *refChecker) reference(n *node, captures []string, ref Reference) {
+	key, ok := n.str()
+	if !ok {
+		// null or not a literal, reported by Check if invalid
+		return
+	}
+
+	maps := []*node{c.root}
+	var target []string
+	for _, seg := range splitTarget(ref.To) {
+		switch {
+		case seg == "*":
+			var all []*node
+			for _, m := range maps {
+				for _, k := range m.keys {
+					all = append(all, m.items[k])
+				}
+			}
+			maps = all
+			target = append(target, seg)
+			continue
+		case strings.HasPrefix(seg, "$"):
+			i, err := strconv.Atoi(seg[1:])
+			if err != nil || i < 1 || i > len(captures) {
+				panic(fmt.Sprintf("invalid reference %s -> %s", ref.From, ref.To))
+			}
+			seg = captures[i-1]
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			name := seg[1 : len(seg)-1]
+			var resolved string
+			var ok bool
+			if strings.Contains(name, ".") {
+				resolved, ok = c.root.lookup(name)
+			} else {
+				resolved, ok = n.enclosing(name)
+			}
+			if !ok {
+				c.report(n.expr.Range(), n.path, "cannot verify %q, no %s is set", key, name)
+				return
+			}
+			found := false
+			for _, m := range maps {
+				if m.child(resolved) != nil {
+					found = true
+				}
+			}
+			if !found {
+				// a dangling key of the enclosing object, reported by its own reference
+				return
+			}
+			seg = resolved
+		}
+		var next []*node
+		for _, m := range maps {
+			if child := m.child(seg); child != nil {
+				next = append(next, child)
+			}
+		}
+		maps = next
+		target = append(target, seg)
+	}
+
+	var keys []string
+	for _, m := range maps {
+		if _, ok := m.items[key]; ok {
+			if c.referenced[m] == nil {
+				c.referenced[m] = map[string]bool{}
+			}
+			c.referenced[m][key] = true
+			return
+		}
+		keys = append(keys, m.keys...)
+	}
+	path := formatTarget(target)
+	if len(keys) == 0 {
+		c.report(n.expr.Range(), n.path, "%q is not a key of %s, which is empty or not set", key, path)
+		return
+	}
+	sort.Strings(keys)
+	c.report(n.expr.Range(), n.path, "%q is not a key of %s, expected one of: %s", key, path, strings.Join(unique(keys), ", "))
+}
+
+// splitTarget splits the path to a referenced map into segments, dots between braces are kept.
+func splitTarget(to string) []string {
+	var segments []string
+	start, depth := 0, 0
+	for i, r := range to {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, to[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, to[start:])
+}
+
+// formatTarget renders a resolved path the same way problems are located, i.e. `vnets.transit.subnets`.
+func formatTarget(segments []string) string {
+	path := segments[0]
+	for _, seg := range segments[1:] {
+		if seg == "*" {
+			path += ".*"
+			continue
+		}
+		path = attributePath(path, seg)
+	}
+	return path
+}
+
+func unique(sorted []string) []string {
+	var out []string
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+variable "location" {
+  type = string
+}
+
+variable "name_prefix" {
+  type    = string
+  default = ""
+}
+
+variable "enable_zones" {
+  type    = bool
+  default = true
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "zones" {
+  type     = list(string)
+  default  = ["1", "2", "3"]
+  nullable = false
+}
+
+variable "vnets" {
+  type = any
+}
+
+variable "test_vms" {
+  type = map(object({
+    name       = string
+    vnet_key   = string
+    subnet_key = string
+    size       = number
+  }))
+}
+
+variable "labels" {
+  type    = map(any)
+  default = {}
+}
+
+variable "resource_group_name" {
+  type = string
+}
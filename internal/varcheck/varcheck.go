@@ -0,0 +1,366 @@
+// Package varcheck verifies variable files against variables declared by a Terraform module, without Terraform.
+//
+// Terraform reports a malformed variable file only after downloading providers and initializing the module.
+// The checker parses the module's variable declarations and variable files with HCL, converts declared types
+// into cty types and reports values of a wrong type, unknown keys of objects and required variables that are not set.
+// Variables of type `any` accept every value, only their syntax is verified.
+//
+// Examples wire parts of their variables together with keys, i.e. a VM-Series interface points to a subnet
+// with `subnet_key`. A typo in such a key ends up in a `try()` fallback or in an error of an index during planning.
+// CheckReferences reports keys pointing to nothing and keys nothing points to.
+package varcheck
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Variable is a variable declared by a module.
+type Variable struct {
+	Name string
+	// Type is cty.DynamicPseudoType for variables of type `any` and variables without a type.
+	Type cty.Type
+	// Required is true for variables without a default value.
+	Required bool
+	// Nullable is false for variables declared with `nullable = false`.
+	Nullable bool
+	// Range is the location of the declaration.
+	Range hcl.Range
+}
+
+// LoadModule reads variables declared in `.tf` files of the module in dir.
+func LoadModule(dir string) (map[string]*Variable, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+
+	parser := hclparse.NewParser()
+	vars := map[string]*Variable{}
+	var diags hcl.Diagnostics
+	for _, path := range files {
+		file, fileDiags := parser.ParseHCLFile(path)
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+		for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 {
+				continue
+			}
+			v, varDiags := decodeVariable(block)
+			diags = append(diags, varDiags...)
+			if v != nil {
+				vars[v.Name] = v
+			}
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return vars, nil
+}
+
+func decodeVariable(block *hclsyntax.Block) (*Variable, hcl.Diagnostics) {
+	v := &Variable{
+		Name:     block.Labels[0],
+		Type:     cty.DynamicPseudoType,
+		Nullable: true,
+		Range:    block.DefRange(),
+	}
+	_, hasDefault := block.Body.Attributes["default"]
+	v.Required = !hasDefault
+
+	if attr, ok := block.Body.Attributes["type"]; ok {
+		ty, diags := typeexpr.TypeConstraint(attr.Expr)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		v.Type = ty
+	}
+	if attr, ok := block.Body.Attributes["nullable"]; ok {
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		if val.Type() == cty.Bool && val.IsKnown() && !val.IsNull() {
+			v.Nullable = val.True()
+		}
+	}
+	return v, nil
+}
+
+// Problem is a single issue found in variable files.
+type Problem struct {
+	// Range is the location of the offending value, or of the declaration of a required variable that is not set.
+	Range hcl.Range
+	// Path is the path to the offending value, i.e. `vnets["transit"].address_space[0]`.
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d,%d: %s: %s", p.Range.Filename, p.Range.Start.Line, p.Range.Start.Column, p.Path, p.Message)
+}
+
+// Options control which problems are reported.
+type Options struct {
+	// Provided are names of variables set outside of the variable files, i.e. with `-var` flags.
+	Provided []string
+	// Overlay skips the check for required variables, for files passed on top of a complete set of variable files.
+	Overlay bool
+}
+
+// Check verifies variable files against the declared variables. Like in Terraform, a variable set in more than
+// one file takes the value from the last one, but values in all files are verified.
+func Check(vars map[string]*Variable, files []string, opts Options) ([]Problem, error) {
+	c := &checker{}
+	set := map[string]bool{}
+	for _, name := range opts.Provided {
+		set[name] = true
+	}
+
+	parser := hclparse.NewParser()
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := parser.ParseHCL(src, path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		attrs := file.Body.(*hclsyntax.Body).Attributes
+		for _, name := range sortedAttributes(attrs) {
+			attr := attrs[name]
+			set[name] = true
+			v, ok := vars[name]
+			if !ok {
+				c.report(attr.NameRange, name, "no variable named %q is declared", name)
+				continue
+			}
+			c.variable(attr.Expr, v)
+		}
+	}
+
+	if !opts.Overlay {
+		for _, name := range sortedVariables(vars) {
+			if v := vars[name]; v.Required && !set[name] {
+				c.report(v.Range, name, "required variable is not set")
+			}
+		}
+	}
+
+	SortProblems(c.problems)
+	return c.problems, nil
+}
+
+// SortProblems orders problems by their location.
+func SortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i].Range, problems[j].Range
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Start.Byte < b.Start.Byte
+	})
+}
+
+type checker struct {
+	problems []Problem
+}
+
+func (c *checker) report(rng hcl.Range, path, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Range: rng, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// variable verifies the value of a single variable. Values are walked together with the type, so that problems
+// are reported with their exact location. When no problem is found, the whole value is converted to the type,
+// which catches elements of collections that cannot be unified, i.e. strings and objects in a `map(any)`.
+func (c *checker) variable(expr hclsyntax.Expression, v *Variable) {
+	before := len(c.problems)
+	c.value(expr, v.Name, v.Type, v.Nullable)
+	if len(c.problems) > before {
+		return
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || val.IsNull() {
+		return
+	}
+	if _, err := convert.Convert(val, v.Type); err != nil {
+		path := v.Name
+		if pathErr, ok := err.(cty.PathError); ok {
+			path += formatPath(pathErr.Path)
+		}
+		c.report(expr.Range(), path, "%s", err)
+	}
+}
+
+func (c *checker) value(expr hclsyntax.Expression, path string, ty cty.Type, nullable bool) {
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		if ty.IsObjectType() {
+			c.object(e, path, ty)
+			return
+		}
+		if ty.IsMapType() {
+			for _, item := range e.Items {
+				if key, ok := c.key(item.KeyExpr, path); ok {
+					c.value(item.ValueExpr, fmt.Sprintf("%s[%q]", path, key), ty.ElementType(), true)
+				}
+			}
+			return
+		}
+		if ty == cty.DynamicPseudoType {
+			// values of `any` are walked only to locate expressions that are not literals
+			for _, item := range e.Items {
+				if key, ok := c.key(item.KeyExpr, path); ok {
+					c.value(item.ValueExpr, attributePath(path, key), ty, true)
+				}
+			}
+			return
+		}
+	case *hclsyntax.TupleConsExpr:
+		if ty.IsListType() || ty.IsSetType() || ty == cty.DynamicPseudoType {
+			elem := ty
+			if ty != cty.DynamicPseudoType {
+				elem = ty.ElementType()
+			}
+			for i, el := range e.Exprs {
+				c.value(el, fmt.Sprintf("%s[%d]", path, i), elem, true)
+			}
+			return
+		}
+		if ty.IsTupleType() {
+			types := ty.TupleElementTypes()
+			if len(e.Exprs) != len(types) {
+				c.report(e.Range(), path, "expected a tuple of %d elements, got %d", len(types), len(e.Exprs))
+				return
+			}
+			for i, el := range e.Exprs {
+				c.value(el, fmt.Sprintf("%s[%d]", path, i), types[i], true)
+			}
+			return
+		}
+	}
+
+	// anything else is verified by converting its value
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		c.report(expr.Range(), path, "variable files can contain only literal values: %s", diags[0].Summary)
+		return
+	}
+	if val.IsNull() {
+		if !nullable {
+			c.report(expr.Range(), path, "the variable is not nullable, null is not allowed")
+		}
+		return
+	}
+	if ty == cty.DynamicPseudoType {
+		return
+	}
+	if _, err := convert.Convert(val, ty); err != nil {
+		c.report(expr.Range(), path, "%s, got %s", err, val.Type().FriendlyName())
+	}
+}
+
+func (c *checker) object(e *hclsyntax.ObjectConsExpr, path string, ty cty.Type) {
+	seen := map[string]bool{}
+	for _, item := range e.Items {
+		key, ok := c.key(item.KeyExpr, path)
+		if !ok {
+			continue
+		}
+		seen[key] = true
+		if !ty.HasAttribute(key) {
+			c.report(item.KeyExpr.Range(), path, "unknown key %q, expected one of: %s", key, strings.Join(attributeNames(ty), ", "))
+			continue
+		}
+		c.value(item.ValueExpr, attributePath(path, key), ty.AttributeType(key), true)
+	}
+	for _, name := range attributeNames(ty) {
+		if !seen[name] && !ty.AttributeOptional(name) {
+			c.report(e.Range(), path, "missing key %q", name)
+		}
+	}
+}
+
+// key returns the key of an object item. Bare identifiers are keys, like in Terraform.
+func (c *checker) key(expr hclsyntax.Expression, path string) (string, bool) {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		c.report(expr.Range(), path, "invalid key: %s", diags[0].Summary)
+		return "", false
+	}
+	if val.Type() != cty.String || val.IsNull() {
+		c.report(expr.Range(), path, "keys have to be strings")
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// attributePath returns the path to an attribute of an object, keys that are not identifiers are quoted.
+func attributePath(path, key string) string {
+	if hclsyntax.ValidIdentifier(key) {
+		return path + "." + key
+	}
+	return fmt.Sprintf("%s[%q]", path, key)
+}
+
+// formatPath renders a cty path the way Terraform does, i.e. `["transit"].subnets[0]`.
+func formatPath(path cty.Path) string {
+	var sb strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			sb.WriteString("." + s.Name)
+		case cty.IndexStep:
+			if s.Key.Type() == cty.String {
+				fmt.Fprintf(&sb, "[%q]", s.Key.AsString())
+			} else if s.Key.Type() == cty.Number {
+				fmt.Fprintf(&sb, "[%s]", s.Key.AsBigFloat().String())
+			}
+		}
+	}
+	return sb.String()
+}
+
+func attributeNames(ty cty.Type) []string {
+	var names []string
+	for name := range ty.AttributeTypes() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedAttributes(attrs hclsyntax.Attributes) []string {
+	var names []string
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedVariables(vars map[string]*Variable) []string {
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
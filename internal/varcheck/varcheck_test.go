@@ -0,0 +1,198 @@
+package varcheck
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func load(t *testing.T) map[string]*Variable {
+	t.Helper()
+	vars, err := LoadModule("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vars
+}
+
+func messages(problems []Problem) string {
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLoadModule(t *testing.T) {
+	vars := load(t)
+
+	if v := vars["test_vms"]; v == nil || !v.Type.IsMapType() || !v.Type.ElementType().IsObjectType() || !v.Required {
+		t.Errorf("test_vms not decoded as a required map of objects: %+v", v)
+	}
+	if v := vars["vnets"]; v == nil || v.Type != cty.DynamicPseudoType {
+		t.Errorf("vnets not decoded as any: %+v", v)
+	}
+	if v := vars["zones"]; v == nil || v.Nullable || v.Required {
+		t.Errorf("zones not decoded as an optional, not nullable variable: %+v", v)
+	}
+	if _, err := LoadModule(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without Terraform files")
+	}
+}
+
+func TestCheckValid(t *testing.T) {
+	problems, err := Check(load(t), []string{"testdata/valid.tfvars"}, Options{Provided: []string{"resource_group_name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("unexpected problems:\n%s", messages(problems))
+	}
+}
+
+func TestCheckInvalid(t *testing.T) {
+	problems, err := Check(load(t), []string{"testdata/invalid.tfvars"}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`testdata/invalid.tfvars:1,16: location: string required, got tuple`,
+		`testdata/invalid.tfvars:2,16: enable_zones: a bool is required, got string`,
+		`testdata/invalid.tfvars:3,16: zones: the variable is not nullable, null is not allowed`,
+		`testdata/invalid.tfvars:5,15: tags["CreatedBy"]: string required, got object`,
+		`testdata/invalid.tfvars:9,22: vnets.transit.address_space[0]: variable files can contain only literal values: Variables not allowed`,
+		`testdata/invalid.tfvars:13,12: test_vms["vm01"]: missing key "subnet_key"`,
+		`testdata/invalid.tfvars:16,5: test_vms["vm01"]: unknown key "subnt_key", expected one of: name, size, subnet_key, vnet_key`,
+		`testdata/invalid.tfvars:17,17: test_vms["vm01"].size: a number is required, got string`,
+		`testdata/invalid.tfvars:20,10: labels: all map elements must have the same type`,
+		`testdata/invalid.tfvars:24,1: vment: no variable named "vment" is declared`,
+		`testdata/variables.tf:44,1: resource_group_name: required variable is not set`,
+	}
+	if got := messages(problems); got != strings.Join(want, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckOverlay(t *testing.T) {
+	vars := load(t)
+
+	// a value set in a later file does not hide problems in an earlier one
+	problems, err := Check(vars, []string{"testdata/invalid.tfvars", "testdata/valid.tfvars"}, Options{Overlay: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(messages(problems), "location: string required") {
+		t.Errorf("problems of the first file are not reported:\n%s", messages(problems))
+	}
+	if strings.Contains(messages(problems), "required variable is not set") {
+		t.Errorf("required variables are verified for an overlay:\n%s", messages(problems))
+	}
+}
+
+func TestExamples(t *testing.T) {
+	examples, err := filepath.Glob("../../examples/*/example.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("no examples found")
+	}
+	for _, tfvars := range examples {
+		dir := filepath.Dir(tfvars)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			vars, err := LoadModule(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			problems, err := Check(vars, []string{tfvars}, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) > 0 {
+				t.Errorf("problems found:\n%s", messages(problems))
+			}
+		})
+	}
+}
+
+var testRules = Rules{
+	References: []Reference{
+		{From: "vnets.*.subnets.*.route_table", To: "vnets.$1.route_tables"},
+		{From: "vmseries.*.vnet_key", To: "vnets"},
+		{From: "vmseries.*.interfaces.*.subnet_key", To: "vnets.{vnet_key}.subnets"},
+		{From: "vmseries.*.interfaces.*.load_balancer_key", To: "load_balancers"},
+		{From: "appvms.*.subnet_key", To: "vnets.*.subnets"},
+		{From: "storages.*.subnet_key", To: "vnets.{common.vnet_key}.subnets"},
+	},
+	Unused: []string{"vnets.*.route_tables"},
+}
+
+func TestCheckReferences(t *testing.T) {
+	problems, err := CheckReferences([]string{"testdata/references.tfvars"}, testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`testdata/references.tfvars:8,23: vnets.transit.subnets.public.route_table: "pubic" is not a key of vnets.transit.route_tables, expected one of: management, private`,
+		`testdata/references.tfvars:13,7: vnets.transit.route_tables: key "private" is not referenced`,
+		`testdata/references.tfvars:19,23: vnets.app.subnets.web.route_table: "web" is not a key of vnets.app.route_tables, which is empty or not set`,
+		`testdata/references.tfvars:37,29: vmseries.fw.interfaces[1].subnet_key: "private" is not a key of vnets.transit.subnets, expected one of: management, public`,
+		`testdata/references.tfvars:45,22: vmseries.orphan.interfaces[0].subnet_key: cannot verify "management", no vnet_key is set`,
+		`testdata/references.tfvars:50,16: vmseries.lost.vnet_key: "trnsit" is not a key of vnets, expected one of: app, transit`,
+		`testdata/references.tfvars:64,18: appvms.db.subnet_key: "db" is not a key of vnets.*.subnets, expected one of: management, public, web`,
+		`testdata/references.tfvars:70,18: storages.bootstrap.subnet_key: "web" is not a key of vnets.transit.subnets, expected one of: management, public`,
+	}
+	if got := messages(problems); got != strings.Join(want, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckReferencesOverlay(t *testing.T) {
+	// vmseries of the overlay replace the ones of the first file
+	problems, err := CheckReferences([]string{"testdata/references.tfvars", "testdata/references_overlay.tfvars"}, testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := messages(problems)
+	if strings.Contains(got, "vmseries.") {
+		t.Errorf("problems of replaced values are reported:\n%s", got)
+	}
+	if !strings.Contains(got, `"pubic" is not a key`) {
+		t.Errorf("problems of values which are not replaced are not reported:\n%s", got)
+	}
+}
+
+func TestExampleReferences(t *testing.T) {
+	examples, err := filepath.Glob("../../examples/*/example.tfvars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tfvars := range examples {
+		dir := filepath.Dir(tfvars)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			rules, ok := ExampleRules[filepath.Base(dir)]
+			if !ok {
+				t.Fatal("no reference rules for the example")
+			}
+			overlays, err := filepath.Glob(filepath.Join(dir, "testdata", "matrix", "*.tfvars"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sets := [][]string{{tfvars}}
+			for _, overlay := range overlays {
+				sets = append(sets, []string{tfvars, overlay})
+			}
+			for _, files := range sets {
+				problems, err := CheckReferences(files, rules)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(problems) > 0 {
+					t.Errorf("problems found:\n%s", messages(problems))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+resource "azurerm_lb" "this" {
+  name     = var.name
+  location = var.location
+  sku      = var.sku
+  tags     = var.tags
+
+  dynamic "frontend_ip_configuration" {
+    for_each = var.frontend_ips
+    iterator = frontend
+    content {
+      name                = frontend.key
+      subnet_id           = try(frontend.value.subnet_id, null)
+      private_ip_address  = lookup(frontend.value, "private_ip_address", null)
+      zones               = try(frontend.value.zones, null)
+      session_persistence = try(frontend.value.session_persistence, null)
+    }
+  }
+}
+
+resource "azurerm_lb_probe" "this" {
+  name            = try(var.health_probe.name, var.name)
+  loadbalancer_id = azurerm_lb.this.id
+  port            = var.health_probe.port
+}
+
+locals {
+  in_rules = flatten([
+    for fip_key, fip in var.frontend_ips : [
+      for rule_key, rule in try(fip.in_rules, {}) : {
+        fip_key  = fip_key
+        rule_key = rule_key
+        rule     = rule
+      }
+    ]
+  ])
+}
+
+resource "azurerm_lb_rule" "this" {
+  for_each = { for v in local.in_rules : "${v.fip_key}-${v.rule_key}" => v }
+
+  name          = each.value.rule_key
+  protocol      = each.value.rule.protocol
+  frontend_port = each.value.rule.port
+}
+
+resource "azurerm_lb_backend_address_pool" "this" {
+  for_each = { for k, v in var.backends : k => merge(v, { index = k }) }
+
+  name            = "${each.value.name}-${each.value.index}"
+  loadbalancer_id = azurerm_lb.this.id
+}
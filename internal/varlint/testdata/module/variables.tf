@@ -0,0 +1,78 @@
+variable "name" {
+  description = "Name of the Load Balancer."
+  type        = string
+}
+
+variable "location" {
+  type = string
+}
+
+variable "zones" {
+  description = ""
+  default     = null
+}
+
+variable "sku" {
+  description = "SKU of the Load Balancer. Possible values are `Basic` or `Standard`."
+  default     = "Standard"
+  type        = string
+}
+
+variable "tier" {
+  description = "Tier of the Load Balancer. Possible values are `Regional` or `Global`."
+  default     = "Regional"
+  type        = string
+  validation {
+    condition     = contains(["Regional", "Global"], var.tier)
+    error_message = "The `tier` has to be either `Regional` or `Global`."
+  }
+}
+
+variable "create_public_ip" {
+  description = "If `true`, at least one of `public_ip_name` or `frontend_ips` has to be set (`frontend_ips` is preferred)."
+  default     = false
+  type        = bool
+}
+
+variable "health_probe" {
+  description = <<-EOF
+  Health probe of the Load Balancer. Following settings are available:
+  - `name`     - (Optional|string) Name of the health probe.
+  - `port`     - (Required|int) Port of the health probe, one of `80`
+    or `443` (continued here).
+  - `interval` - (Optional|int) Not read by the module.
+  EOF
+  type        = any
+}
+
+variable "frontend_ips" {
+  description = <<-EOF
+  Frontend IP configurations. Each one has a map of `in_rules`:
+  - `subnet_id` : Identifier of a subnet.
+  - `zones`     : Ignored for public frontends.
+  - `session_persistence` : one of:
+    - `Default` : 5 tuple.
+    - `SourceIP` : 2 tuple.
+
+  Attributes of a rule:
+  - `protocol` : Communication protocol.
+  - `port`     : Communication port.
+  EOF
+  default     = {}
+  type        = map(any)
+}
+
+variable "backends" {
+  description = <<-EOF
+  Backend pools:
+  - `name` - Name of the pool.
+  EOF
+  default     = {}
+  type        = map(object({ name = string }))
+}
+
+variable "tags" {
+  description = "Tags of the created resources."
+  default     = {}
+  type        = map(any)
+}
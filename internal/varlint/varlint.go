@@ -0,0 +1,552 @@
+// Package varlint verifies documentation and types of variables declared by modules, without Terraform.
+//
+// Several variables are of type `any`, their structure is described only in the description as a list of attributes:
+//
+//   - `name`      - (Optional|string) Name of the frontend IP configuration.
+//   - `subnet_id` : Identifier of an existing subnet.
+//
+// Such a list drifts from the code easily. The linter collects attributes the module reads from each variable,
+// directly (`var.health_probe.port`), through `try()` and `lookup()`, or through iterators of `for_each`,
+// `dynamic` blocks and `for` expressions, and compares them with the documented ones. It also reports variables
+// without a description and descriptions listing allowed values without a validation block. Variables of type `any`
+// are reported only on request, a free-form map like `tags` is fine as `map(any)`.
+package varlint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/varcheck"
+)
+
+var (
+	// attributeLine matches an attribute of a list in a description, i.e. "- `name` - (Optional|string) ...".
+	// Values listed the same way, i.e. "- `SourceIP` : ...", are not attributes.
+	attributeLine = regexp.MustCompile("^(\\s*)[-*]\\s+`([a-z_][a-z0-9_]*)`\\s*[-:]\\s")
+	// allowedValues matches a sentence introducing allowed values, i.e. "Possible values are `Basic` or `Standard`".
+	allowedValues = regexp.MustCompile("(?i)\\b(?:possible|allowed|valid|supported|accepted) values(?:\\s+(?:are|include)\\b|\\s*:)|\\bone of\\b|\\beither\\b")
+	sentenceEnd   = regexp.MustCompile("\\.(?:\\s|$)")
+	parenthesized = regexp.MustCompile("\\([^)]*\\)")
+	literal       = regexp.MustCompile("`([^`\\n]+)`")
+)
+
+// Lint verifies variables of the module in dir. With anyTypes, types containing `any` are reported as well.
+func Lint(dir string, anyTypes bool) ([]varcheck.Problem, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+	parser := hclparse.NewParser()
+	var bodies []*hclsyntax.Body
+	var diags hcl.Diagnostics
+	for _, path := range paths {
+		file, fileDiags := parser.ParseHCLFile(path)
+		diags = append(diags, fileDiags...)
+		if !fileDiags.HasErrors() {
+			bodies = append(bodies, file.Body.(*hclsyntax.Body))
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	l := &linter{
+		anyTypes:  anyTypes,
+		reads:     map[string]map[string]bool{},
+		locals:    map[string]hclsyntax.Expression{},
+		resolving: map[string]bool{},
+	}
+	var variables []*hclsyntax.Block
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type == "locals" {
+				for name, attr := range block.Body.Attributes {
+					l.locals[name] = attr.Expr
+				}
+			}
+		}
+	}
+	for _, body := range bodies {
+		for _, block := range body.Blocks {
+			if block.Type == "variable" && len(block.Labels) == 1 {
+				variables = append(variables, block)
+				continue
+			}
+			l.block(block, nil)
+		}
+	}
+	for _, block := range variables {
+		l.variable(block)
+	}
+	varcheck.SortProblems(l.problems)
+	return l.problems, nil
+}
+
+type linter struct {
+	anyTypes bool
+	// reads are names of attributes read from each variable, at any depth. The first attribute read from
+	// the variable, or from an element of a map or a list, is kept with a leading dot as well.
+	reads map[string]map[string]bool
+	// locals are expressions of local values, by name. Locals being resolved are kept in resolving.
+	locals    map[string]hclsyntax.Expression
+	resolving map[string]bool
+	problems  []varcheck.Problem
+}
+
+func (l *linter) report(rng hcl.Range, path, format string, args ...interface{}) {
+	l.problems = append(l.problems, varcheck.Problem{Range: rng, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// variable verifies the declaration of a single variable.
+func (l *linter) variable(block *hclsyntax.Block) {
+	name := block.Labels[0]
+	path := "var." + name
+
+	if attr, ok := block.Body.Attributes["type"]; !ok {
+		l.report(block.DefRange(), path, "variable has no type")
+	} else if ty, diags := typeexpr.TypeConstraint(attr.Expr); !diags.HasErrors() && l.anyTypes && hasAny(ty) {
+		l.report(attr.Expr.Range(), path, "type %s accepts any value, declare its structure with object()", typeexpr.TypeString(ty))
+	}
+
+	attr, ok := block.Body.Attributes["description"]
+	if !ok {
+		l.report(block.DefRange(), path, "variable has no description")
+		return
+	}
+	val, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || val.IsNull() || strings.TrimSpace(val.AsString()) == "" {
+		l.report(attr.Expr.Range(), path, "variable has no description")
+		return
+	}
+	documented, text := parseDescription(val.AsString())
+
+	if !hasValidation(block) {
+		if values := listedValues(text); len(values) > 1 {
+			l.report(attr.Expr.Range(), path, "description lists allowed values %s but there is no validation block", strings.Join(values, ", "))
+		}
+	}
+
+	if len(documented) == 0 {
+		return
+	}
+	reads := l.reads[name]
+	for _, attrName := range documented {
+		if !reads[attrName] {
+			l.report(attr.Expr.Range(), path+"."+attrName, "attribute is documented but the module never reads it")
+		}
+	}
+	// attributes of nested maps, like `in_rules` of a Load Balancer's frontend, are often described in the text
+	isDocumented := map[string]bool{}
+	for _, m := range literal.FindAllStringSubmatch(val.AsString(), -1) {
+		isDocumented[m[1]] = true
+	}
+	for _, read := range sortedReads(reads) {
+		if attrName := strings.TrimPrefix(read, "."); attrName != read && !isDocumented[attrName] {
+			l.report(attr.Expr.Range(), path+"."+attrName, "attribute is read by the module but not documented")
+		}
+	}
+}
+
+// parseDescription returns names of attributes listed in a description and the remaining text,
+// without the attribute lines and their continuations.
+func parseDescription(description string) ([]string, string) {
+	var names []string
+	var text []string
+	indent := -1
+	for _, line := range strings.Split(description, "\n") {
+		if m := attributeLine.FindStringSubmatch(line); m != nil {
+			names = append(names, m[2])
+			indent = len(m[1])
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		if indent >= 0 && trimmed != "" && len(line)-len(trimmed) > indent {
+			// a continuation of an attribute line
+			continue
+		}
+		indent = -1
+		text = append(text, line)
+	}
+	return names, strings.Join(text, "\n")
+}
+
+// listedValues returns literals following a phrase introducing allowed values, up to the end of the sentence.
+func listedValues(text string) []string {
+	var values []string
+	for _, loc := range allowedValues.FindAllStringIndex(text, -1) {
+		if strings.HasSuffix(strings.ToLower(text[:loc[0]]), "at least ") {
+			// "at least one of `a` or `b`" is a dependency between variables
+			continue
+		}
+		sentence := text[loc[1]:]
+		if end := sentenceEnd.FindStringIndex(sentence); end != nil {
+			sentence = sentence[:end[0]]
+		}
+		// remarks like "(only to be used with a `Predefined` policy)" are not values
+		sentence = parenthesized.ReplaceAllString(sentence, "")
+		for _, m := range literal.FindAllStringSubmatch(sentence, -1) {
+			if m[1] != "null" {
+				values = append(values, "`"+m[1]+"`")
+			}
+		}
+		if len(values) > 1 {
+			return values
+		}
+		values = nil
+	}
+	return nil
+}
+
+func hasValidation(block *hclsyntax.Block) bool {
+	for _, b := range block.Body.Blocks {
+		if b.Type == "validation" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAny reports whether the type, or a type nested in it, is `any`.
+func hasAny(ty cty.Type) bool {
+	switch {
+	case ty == cty.DynamicPseudoType:
+		return true
+	case ty.IsCollectionType():
+		return hasAny(ty.ElementType())
+	case ty.IsObjectType():
+		for _, attrTy := range ty.AttributeTypes() {
+			if hasAny(attrTy) {
+				return true
+			}
+		}
+	case ty.IsTupleType():
+		for _, elemTy := range ty.TupleElementTypes() {
+			if hasAny(elemTy) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ref is a value derived from a variable: the variable itself, or an attribute at the path within it.
+// Indexes are skipped, an element of a map is read the same way as an object.
+type ref struct {
+	variable string
+	path     []string
+}
+
+func (r ref) with(names ...string) ref {
+	return ref{variable: r.variable, path: append(append([]string{}, r.path...), names...)}
+}
+
+// value is what an expression evaluates to, as far as reads of variables are concerned: a value derived
+// from a variable, an object built of such values, or both when they are merged. Locals usually flatten nested
+// maps of variables into objects, i.e. `{ nsg_key = nsg_key, rule = rule }`, and their attributes are read later
+// through `each.value`. Like indexes, collections are not told apart from their elements.
+type value struct {
+	ref *ref
+	// attrs are attributes set by the module, nil for attributes not derived from a variable.
+	attrs map[string]*value
+}
+
+func (v *value) attr(name string) *value {
+	if attr, ok := v.attrs[name]; ok {
+		return attr
+	}
+	if v.ref != nil {
+		r := v.ref.with(name)
+		return &value{ref: &r}
+	}
+	return nil
+}
+
+func (v *value) traverse(steps hcl.Traversal) *value {
+	for _, step := range steps {
+		if v == nil {
+			return nil
+		}
+		if attr, ok := step.(hcl.TraverseAttr); ok {
+			v = v.attr(attr.Name)
+		}
+	}
+	return v
+}
+
+// binding is a symbol referring to a value: `each` of a resource, the iterator of a `dynamic` block
+// or a symbol of a `for` expression.
+type binding struct {
+	value *value
+	// iterator is set for `each` and iterators of `dynamic` blocks, which refer to the element with `.value`.
+	iterator bool
+}
+
+type scope struct {
+	symbols map[string]binding
+	parent  *scope
+}
+
+func (s *scope) lookup(name string) (binding, bool) {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.symbols[name]; ok {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
+
+func (s *scope) bind(name string, b binding) *scope {
+	return &scope{symbols: map[string]binding{name: b}, parent: s}
+}
+
+// block walks a top level block. Iterators of resources and modules created with `for_each` are bound to `each`.
+func (l *linter) block(block *hclsyntax.Block, s *scope) {
+	if attr, ok := block.Body.Attributes["for_each"]; ok && (block.Type == "resource" || block.Type == "data" || block.Type == "module") {
+		s = s.bind("each", binding{value: l.resolve(attr.Expr, s), iterator: true})
+	}
+	l.body(block.Body, s)
+}
+
+func (l *linter) body(body *hclsyntax.Body, s *scope) {
+	for _, attr := range body.Attributes {
+		l.expr(attr.Expr, s)
+	}
+	for _, block := range body.Blocks {
+		if block.Type != "dynamic" || len(block.Labels) != 1 {
+			l.body(block.Body, s)
+			continue
+		}
+		inner := s
+		if attr, ok := block.Body.Attributes["for_each"]; ok {
+			iterator := block.Labels[0]
+			if it, ok := block.Body.Attributes["iterator"]; ok {
+				iterator = hcl.ExprAsKeyword(it.Expr)
+			}
+			inner = s.bind(iterator, binding{value: l.resolve(attr.Expr, s), iterator: true})
+		}
+		for name, attr := range block.Body.Attributes {
+			if name != "iterator" {
+				l.expr(attr.Expr, s)
+			}
+		}
+		for _, content := range block.Body.Blocks {
+			l.body(content.Body, inner)
+		}
+	}
+}
+
+func (l *linter) expr(expr hclsyntax.Expression, s *scope) {
+	hclsyntax.Walk(expr, &walker{linter: l, scopes: []*scope{s}})
+}
+
+func (l *linter) read(r ref) {
+	reads := l.reads[r.variable]
+	if reads == nil {
+		reads = map[string]bool{}
+		l.reads[r.variable] = reads
+	}
+	for i, name := range r.path {
+		reads[name] = true
+		if i == 0 {
+			reads["."+name] = true
+		}
+	}
+}
+
+// walker records reads of variables in an expression. Symbols of `for` expressions are bound while their
+// expressions are walked.
+type walker struct {
+	linter *linter
+	scopes []*scope
+}
+
+func (w *walker) scope() *scope {
+	return w.scopes[len(w.scopes)-1]
+}
+
+func (w *walker) Enter(node hclsyntax.Node) hcl.Diagnostics {
+	switch e := node.(type) {
+	case *hclsyntax.ForExpr:
+		s := w.scope().bind(e.ValVar, binding{value: w.linter.resolve(e.CollExpr, w.scope())})
+		w.scopes = append(w.scopes, s)
+	case *hclsyntax.ScopeTraversalExpr, *hclsyntax.RelativeTraversalExpr, *hclsyntax.SplatExpr, *hclsyntax.FunctionCallExpr:
+		if v := w.linter.resolve(e.(hclsyntax.Expression), w.scope()); v != nil && v.ref != nil {
+			w.linter.read(*v.ref)
+		}
+	}
+	return nil
+}
+
+func (w *walker) Exit(node hclsyntax.Node) hcl.Diagnostics {
+	if _, ok := node.(*hclsyntax.ForExpr); ok {
+		w.scopes = w.scopes[:len(w.scopes)-1]
+	}
+	return nil
+}
+
+// resolve returns the value an expression evaluates to, or nil when it is not derived from a variable.
+func (l *linter) resolve(expr hclsyntax.Expression, s *scope) *value {
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		steps := e.Traversal[1:]
+		var v *value
+		switch root := e.Traversal.RootName(); root {
+		case "var", "local":
+			if len(steps) == 0 {
+				return nil
+			}
+			attr, ok := steps[0].(hcl.TraverseAttr)
+			if !ok {
+				return nil
+			}
+			if root == "var" {
+				v = &value{ref: &ref{variable: attr.Name}}
+			} else if v = l.local(attr.Name); v == nil {
+				return nil
+			}
+			steps = steps[1:]
+		default:
+			b, ok := s.lookup(root)
+			if !ok || b.value == nil {
+				return nil
+			}
+			if b.iterator {
+				if len(steps) == 0 {
+					return nil
+				}
+				if attr, ok := steps[0].(hcl.TraverseAttr); !ok || attr.Name != "value" {
+					return nil
+				}
+				steps = steps[1:]
+			}
+			v = b.value
+		}
+		return v.traverse(steps)
+	case *hclsyntax.RelativeTraversalExpr:
+		return l.resolve(e.Source, s).traverse(e.Traversal)
+	case *hclsyntax.IndexExpr:
+		return l.resolve(e.Collection, s)
+	case *hclsyntax.SplatExpr:
+		v := l.resolve(e.Source, s)
+		if each, ok := e.Each.(*hclsyntax.RelativeTraversalExpr); ok {
+			return v.traverse(each.Traversal)
+		}
+		return v
+	case *hclsyntax.ParenthesesExpr:
+		return l.resolve(e.Expression, s)
+	case *hclsyntax.TemplateWrapExpr:
+		return l.resolve(e.Wrapped, s)
+	case *hclsyntax.ConditionalExpr:
+		if v := l.resolve(e.TrueResult, s); v != nil {
+			return v
+		}
+		return l.resolve(e.FalseResult, s)
+	case *hclsyntax.ForExpr:
+		coll := l.resolve(e.CollExpr, s)
+		return l.resolve(e.ValExpr, s.bind(e.ValVar, binding{value: coll}))
+	case *hclsyntax.TupleConsExpr:
+		for _, elem := range e.Exprs {
+			if v := l.resolve(elem, s); v != nil {
+				return v
+			}
+		}
+	case *hclsyntax.ObjectConsExpr:
+		if v := l.object(e, s, false); len(v.attrs) > 0 {
+			return v
+		}
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name == "lookup" && len(e.Args) >= 2 {
+			v := l.resolve(e.Args[0], s)
+			key, diags := e.Args[1].Value(nil)
+			if v == nil || diags.HasErrors() || key.Type() != cty.String || key.IsNull() {
+				return nil
+			}
+			return v.attr(key.AsString())
+		}
+		if e.Name == "merge" {
+			return l.merge(e.Args, s)
+		}
+		switch e.Name {
+		case "try", "coalesce", "values", "flatten", "concat", "toset", "tolist", "tomap":
+			// the result is, or is built of, elements of the arguments
+			for _, arg := range e.Args {
+				if v := l.resolve(arg, s); v != nil {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// object resolves an object constructor. With all is set, attributes not derived from variables are kept as nil,
+// so that they shadow attributes of a merged variable.
+func (l *linter) object(e *hclsyntax.ObjectConsExpr, s *scope, all bool) *value {
+	v := &value{attrs: map[string]*value{}}
+	for _, item := range e.Items {
+		key := hcl.ExprAsKeyword(item.KeyExpr)
+		if key == "" {
+			continue
+		}
+		if attr := l.resolve(item.ValueExpr, s); attr != nil || all {
+			v.attrs[key] = attr
+		}
+	}
+	return v
+}
+
+// merge resolves `merge()` of values derived from variables and objects, i.e. `merge(v, { index = k })`.
+func (l *linter) merge(args []hclsyntax.Expression, s *scope) *value {
+	merged := &value{attrs: map[string]*value{}}
+	for _, arg := range args {
+		var v *value
+		if obj, ok := arg.(*hclsyntax.ObjectConsExpr); ok {
+			v = l.object(obj, s, true)
+		} else if v = l.resolve(arg, s); v == nil {
+			continue
+		}
+		if merged.ref == nil {
+			merged.ref = v.ref
+		}
+		for key, attr := range v.attrs {
+			merged.attrs[key] = attr
+		}
+	}
+	if merged.ref == nil && len(merged.attrs) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// local resolves a local value. Locals are resolved in the scope of the module, a local referring
+// to itself through other locals resolves to nil.
+func (l *linter) local(name string) *value {
+	expr, ok := l.locals[name]
+	if !ok || l.resolving[name] {
+		return nil
+	}
+	l.resolving[name] = true
+	defer delete(l.resolving, name)
+	return l.resolve(expr, nil)
+}
+
+func sortedReads(reads map[string]bool) []string {
+	var names []string
+	for name := range reads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,83 @@
+package varlint
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	problems, err := Lint("testdata/module", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`testdata/module/variables.tf:6,1: var.location: variable has no description`,
+		`testdata/module/variables.tf:10,1: var.zones: variable has no type`,
+		`testdata/module/variables.tf:11,17: var.zones: variable has no description`,
+		"testdata/module/variables.tf:16,17: var.sku: description lists allowed values `Basic`, `Standard` but there is no validation block",
+		`testdata/module/variables.tf:38,17: var.health_probe.interval: attribute is documented but the module never reads it`,
+		`testdata/module/variables.tf:45,17: var.health_probe: type any accepts any value, declare its structure with object()`,
+		`testdata/module/variables.tf:49,17: var.frontend_ips.private_ip_address: attribute is read by the module but not documented`,
+		`testdata/module/variables.tf:62,17: var.frontend_ips: type map(any) accepts any value, declare its structure with object()`,
+		`testdata/module/variables.tf:77,17: var.tags: type map(any) accepts any value, declare its structure with object()`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintWithoutAnyTypes(t *testing.T) {
+	problems, err := Lint("testdata/module", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		if strings.Contains(p.Message, "accepts any value") {
+			t.Errorf("got %s, want types containing any not reported", p)
+		}
+	}
+}
+
+func TestParseDescription(t *testing.T) {
+	names, text := parseDescription("Settings:\n- `name` - Name.\n  continued\n- `Default` : a value, not an attribute.\n\nPossible values are `a` or `b`.")
+	if strings.Join(names, ",") != "name" {
+		t.Errorf("got attributes %v", names)
+	}
+	if strings.Contains(text, "continued") {
+		t.Errorf("continuation of an attribute line kept in the text:\n%s", text)
+	}
+	if values := listedValues(text); strings.Join(values, ",") != "`a`,`b`" {
+		t.Errorf("got allowed values %v", values)
+	}
+}
+
+// TestModules verifies that documented attributes of the modules' variables match the attributes the modules read.
+// Allowed values without a validation are reported by cmd/varlint only.
+func TestModules(t *testing.T) {
+	modules, err := filepath.Glob("../../modules/*/variables.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(modules) == 0 {
+		t.Fatal("no modules found")
+	}
+	for _, variablesTf := range modules {
+		dir := filepath.Dir(variablesTf)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			problems, err := Lint(dir, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range problems {
+				if strings.Contains(p.Message, "documented") || strings.Contains(p.Message, "no description") {
+					t.Error(p)
+				}
+			}
+		})
+	}
+}
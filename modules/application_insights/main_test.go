@@ -4,8 +4,51 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
+var fixture = moduletest.Fixture{
+	Inputs: map[string]string{
+		"name":                `"${var.name_prefix}appi"`,
+		"resource_group_name": moduletest.ResourceGroupName,
+		"location":            moduletest.Location,
+	},
+	Scenarios: []moduletest.Scenario{
+		{
+			Name: "workspace",
+			Inputs: map[string]string{
+				"workspace_name":            `"${var.name_prefix}appi-wrkspc"`,
+				"metrics_retention_in_days": "30",
+			},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsCreated(moduletest.Address("azurerm_log_analytics_workspace.this[0]")),
+				planassert.AttributeEquals(moduletest.Address("azurerm_log_analytics_workspace.this[0]"), "sku", "PerGB2018"),
+				planassert.AttributeEquals(moduletest.Address("azurerm_application_insights.this"), "retention_in_days", float64(30)),
+				planassert.AttributeEquals(moduletest.Address("azurerm_application_insights.this"), "application_type", "other"),
+			},
+		},
+		{
+			Name:   "classic",
+			Inputs: map[string]string{"workspace_mode": "false"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(moduletest.Address("azurerm_log_analytics_workspace.this*")),
+				planassert.IsCreated(moduletest.Address("azurerm_application_insights.this")),
+				planassert.AttributeNull(moduletest.Address("azurerm_application_insights.this"), "workspace_id"),
+			},
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlan(t *testing.T) {
+	moduletest.Plan(t, fixture)
+}
+
+func TestApply(t *testing.T) {
+	moduletest.Apply(t, fixture)
+}
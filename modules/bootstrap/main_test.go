@@ -4,8 +4,65 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
 )
 
+var fixture = moduletest.Fixture{
+	Inputs: map[string]string{
+		"name":                `"bootstrap${substr(md5(var.resource_group_name), 0, 8)}"`,
+		"resource_group_name": moduletest.ResourceGroupName,
+		"location":            moduletest.Location,
+		"storage_acl":         "false",
+	},
+	Invalid: []moduletest.Scenario{
+		{
+			Name:          "acl_without_rules",
+			Inputs:        map[string]string{"storage_acl": "true"},
+			ExpectedError: "If 'storage_acl' is set to true, at least on of 'storage_allow_vnet_subnet_ids' or 'storage_allow_inbound_public_ips' must be a non-empty list.",
+		},
+		{
+			Name:          "rules_without_acl",
+			Inputs:        map[string]string{"storage_allow_inbound_public_ips": `["203.0.113.10"]`},
+			ExpectedError: "If either 'storage_allow_vnet_subnet_ids' or 'storage_allow_inbound_public_ips' is a non-empty list, 'storage_acl' must be set to true.",
+		},
+		{
+			Name:          "storage_account_name_uppercase",
+			Inputs:        map[string]string{"name": `"Bootstrap"`},
+			ExpectedError: "A Storage Account name must be between 3 and 24 characters, only lower case letters and numbers are allowed.",
+		},
+		{
+			Name:          "share_name_uppercase",
+			Inputs:        map[string]string{"storage_share_name": `"Bootstrap"`},
+			ExpectedError: "A File Share name must be between 3 and 63 characters, all lowercase numbers, letters or a dash, it must follow a valid URL schema.",
+		},
+		{
+			Name:          "share_name_double_dash",
+			Inputs:        map[string]string{"storage_share_name": `"boot--strap"`},
+			ExpectedError: "A File Share name must be between 3 and 63 characters",
+		},
+		{
+			Name:          "share_name_too_short",
+			Inputs:        map[string]string{"storage_share_name": `"ab"`},
+			ExpectedError: "A File Share name must be between 3 and 63 characters",
+		},
+		{
+			Name:          "retention_policy_days_zero",
+			Inputs:        map[string]string{"retention_policy_days": "0"},
+			ExpectedError: "Enter a value between 1 and 365.",
+		},
+		{
+			Name:          "blob_delete_retention_policy_days_too_long",
+			Inputs:        map[string]string{"blob_delete_retention_policy_days": "400"},
+			ExpectedError: "Enter a value between 1 and 365.",
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlanInvalid(t *testing.T) {
+	moduletest.PlanInvalid(t, fixture)
+}
@@ -20,6 +20,7 @@ variable "frontend_ips" {
   - `create_public_ip` : Optional. Set to `true` to create a public IP.
   - `public_ip_name` : Ignored if `create_public_ip` is `true`. The existing public IP resource name to use.
   - `public_ip_resource_group` : Ignored if `create_public_ip` is `true` or if `public_ip_name` is null. The name of the resource group which holds `public_ip_name`.
+  - `gateway_load_balancer_frontend_ip_configuration_id` : Optional. Identifier of a frontend IP configuration of a Gateway Load Balancer to chain this frontend with, see the `gwlb` module.
 
   Example
 
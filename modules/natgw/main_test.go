@@ -4,8 +4,108 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
+var fixture = moduletest.Fixture{
+	Setup: moduletest.NetworkSetup(map[string]string{"natgw": "10.0.0.0/24"}),
+	Inputs: map[string]string{
+		"name":                `"${var.name_prefix}natgw"`,
+		"resource_group_name": moduletest.ResourceGroupName,
+		"location":            moduletest.Location,
+		"subnet_ids":          `{ natgw = ` + moduletest.SubnetID("natgw") + ` }`,
+	},
+	Scenarios: []moduletest.Scenario{
+		{
+			Name:   "default",
+			Inputs: map[string]string{"zone": `"1"`},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsCreated(moduletest.Address("azurerm_nat_gateway.this[0]")),
+				planassert.AttributeEquals(moduletest.Address("azurerm_nat_gateway.this[0]"), "zones.0", "1"),
+				planassert.IsCreated(moduletest.Address("azurerm_public_ip.this[0]")),
+				planassert.IsCreated(moduletest.Address("azurerm_nat_gateway_public_ip_association.this[0]")),
+				planassert.IsNotPlanned(moduletest.Address("azurerm_public_ip_prefix.this*")),
+				planassert.CreatedCount(moduletest.Address("azurerm_subnet_nat_gateway_association.this[*]"), 1),
+			},
+		},
+		{
+			Name: "pip_prefix",
+			Inputs: map[string]string{
+				"create_pip":        "false",
+				"create_pip_prefix": "true",
+				"pip_prefix_length": "30",
+			},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(moduletest.Address("azurerm_public_ip.this*")),
+				planassert.AttributeEquals(moduletest.Address("azurerm_public_ip_prefix.this[0]"), "prefix_length", float64(30)),
+				planassert.IsCreated(moduletest.Address("azurerm_nat_gateway_public_ip_prefix_association.nat_ips[0]")),
+			},
+		},
+		{
+			Name: "existing_pip_and_prefix",
+			Setup: `
+resource "azurerm_public_ip" "existing" {
+  name                = "${var.name_prefix}existing-pip"
+  resource_group_name = azurerm_resource_group.this.name
+  location            = azurerm_resource_group.this.location
+  allocation_method   = "Static"
+  sku                 = "Standard"
+}
+
+resource "azurerm_public_ip_prefix" "existing" {
+  name                = "${var.name_prefix}existing-pip-prefix"
+  resource_group_name = azurerm_resource_group.this.name
+  location            = azurerm_resource_group.this.location
+  prefix_length       = 31
+}`,
+			Inputs: map[string]string{
+				"create_pip":               "false",
+				"existing_pip_name":        `"${var.name_prefix}existing-pip"`,
+				"existing_pip_prefix_name": `"${var.name_prefix}existing-pip-prefix"`,
+			},
+			DependsOn: []string{"azurerm_public_ip.existing", "azurerm_public_ip_prefix.existing"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(moduletest.Address("azurerm_public_ip.this*")),
+				planassert.IsNotPlanned(moduletest.Address("azurerm_public_ip_prefix.this*")),
+				planassert.IsCreated(moduletest.Address("azurerm_nat_gateway_public_ip_association.this[0]")),
+				planassert.IsCreated(moduletest.Address("azurerm_nat_gateway_public_ip_prefix_association.nat_ips[0]")),
+			},
+		},
+		{
+			// an existing NAT Gateway is only bound to subnets, Public IP settings are ignored
+			Name: "existing_natgw",
+			Setup: `
+resource "azurerm_nat_gateway" "existing" {
+  name                = "${var.name_prefix}natgw"
+  resource_group_name = azurerm_resource_group.this.name
+  location            = azurerm_resource_group.this.location
+  sku_name            = "Standard"
+}`,
+			Inputs: map[string]string{
+				"create_natgw":             "false",
+				"existing_pip_prefix_name": `"${var.name_prefix}existing-pip-prefix"`,
+			},
+			DependsOn: []string{"azurerm_nat_gateway.existing"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsNotPlanned(moduletest.Address("azurerm_nat_gateway.this*")),
+				planassert.IsNotPlanned(moduletest.Address("azurerm_public_ip*")),
+				planassert.IsNotPlanned(moduletest.Address("azurerm_nat_gateway_public_ip*")),
+				planassert.CreatedCount(moduletest.Address("azurerm_subnet_nat_gateway_association.this[*]"), 1),
+			},
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlan(t *testing.T) {
+	moduletest.Plan(t, fixture)
+}
+
+func TestApply(t *testing.T) {
+	moduletest.Apply(t, fixture)
+}
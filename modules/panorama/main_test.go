@@ -4,8 +4,32 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
 )
 
+var fixture = moduletest.Fixture{
+	Setup: moduletest.NetworkSetup(map[string]string{"management": "10.0.0.0/24"}),
+	Inputs: map[string]string{
+		"name":                `"${var.name_prefix}panorama"`,
+		"resource_group_name": moduletest.ResourceGroupName,
+		"location":            moduletest.Location,
+		"password":            `"Test-Password-123"`,
+		"interfaces":          `[{ name = "${var.name_prefix}panorama-mgmt", subnet_id = ` + moduletest.SubnetID("management") + ` }]`,
+	},
+	Invalid: []moduletest.Scenario{
+		{
+			Name:          "unsupported_disk_type",
+			Inputs:        map[string]string{"panorama_disk_type": `"Standard_ZRS"`},
+			ExpectedError: "Panorama disk type need to be one of list",
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlanInvalid(t *testing.T) {
+	moduletest.PlanInvalid(t, fixture)
+}
@@ -110,7 +110,7 @@ resource "azurerm_virtual_machine" "this" {
 
   lifecycle {
     precondition {
-      condition     = var.password != null || var.ssh_keys != []
+      condition     = var.password != null || length(var.ssh_keys) > 0
       error_message = "Either password or ssh_keys must be set in order to have access to the device"
     }
   }
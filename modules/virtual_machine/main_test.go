@@ -4,8 +4,32 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
 )
 
+var fixture = moduletest.Fixture{
+	Setup: moduletest.NetworkSetup(map[string]string{"vm": "10.0.0.0/24"}),
+	Inputs: map[string]string{
+		"name":                `"${var.name_prefix}vm"`,
+		"resource_group_name": moduletest.ResourceGroupName,
+		"location":            moduletest.Location,
+		"username":            `"panadmin"`,
+		"interfaces":          `[{ name = "${var.name_prefix}vm-nic", subnet_id = ` + moduletest.SubnetID("vm") + ` }]`,
+	},
+	Invalid: []moduletest.Scenario{
+		{
+			Name:          "no_password_nor_ssh_keys",
+			Inputs:        map[string]string{"password": "null", "ssh_keys": "[]"},
+			ExpectedError: "Either password or ssh_keys must be set in order to have access to the device",
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlanInvalid(t *testing.T) {
+	moduletest.PlanInvalid(t, fixture)
+}
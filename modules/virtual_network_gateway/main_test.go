@@ -4,8 +4,202 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
+var fixture = moduletest.Fixture{
+	Setup: moduletest.NetworkSetup(map[string]string{"GatewaySubnet": "10.0.0.0/27"}),
+	Inputs: map[string]string{
+		"name_prefix":                      "var.name_prefix",
+		"name":                             `"vpn"`,
+		"resource_group_name":              moduletest.ResourceGroupName,
+		"location":                         moduletest.Location,
+		"type":                             `"Vpn"`,
+		"enable_bgp":                       "true",
+		"default_local_network_gateway_id": "null",
+		"edge_zone":                        "null",
+		"private_ip_address_enabled":       "false",
+		"vpn_client_configuration":         "[]",
+		"custom_route":                     "[]",
+		"ipsec_shared_key":                 `"test123"`,
+		"connection_mode":                  "null",
+		"ipsec_policy":                     "[]",
+	},
+	Scenarios: []moduletest.Scenario{
+		{
+			Name: "active_standby",
+			Inputs: map[string]string{
+				"sku": `"VpnGw1"`,
+				"ip_configuration": `[{
+    name                   = "001"
+    create_public_ip       = true
+    public_ip_name         = null
+    public_ip_standard_sku = true
+    subnet_id              = ` + moduletest.SubnetID("GatewaySubnet") + `
+  }]`,
+				"azure_bgp_peers_addresses": `{ primary_1 = "169.254.21.2" }`,
+				"local_bgp_settings": `{
+    asn               = "65001"
+    peering_addresses = { "001" = { apipa_addresses = ["primary_1"] } }
+  }`,
+				"local_network_gateways": "{}",
+			},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsCreated(moduletest.Address("azurerm_virtual_network_gateway.this")),
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_gateway.this"), "active_active", false),
+				planassert.CreatedWithLength(moduletest.Address("azurerm_virtual_network_gateway.this"), "ip_configuration", 1),
+				planassert.CreatedCount(moduletest.Address("azurerm_public_ip.this[*]"), 1),
+				planassert.AttributeEquals(moduletest.Address("azurerm_public_ip.this[*]"), "sku", "Standard"),
+				planassert.IsNotPlanned(moduletest.Address("azurerm_local_network_gateway.this*")),
+				planassert.IsNotPlanned(moduletest.Address("azurerm_virtual_network_gateway_connection.this*")),
+			},
+		},
+		{
+			Name: "active_active_with_connections",
+			Inputs: map[string]string{
+				"sku":           `"VpnGw2AZ"`,
+				"generation":    `"Generation2"`,
+				"active_active": "true",
+				"avzones":       `["1", "2", "3"]`,
+				"ip_configuration": `[
+    {
+      name                   = "001"
+      create_public_ip       = true
+      public_ip_name         = null
+      public_ip_standard_sku = true
+      subnet_id              = ` + moduletest.SubnetID("GatewaySubnet") + `
+    },
+    {
+      name                   = "002"
+      create_public_ip       = true
+      public_ip_name         = null
+      public_ip_standard_sku = true
+      subnet_id              = ` + moduletest.SubnetID("GatewaySubnet") + `
+    },
+  ]`,
+				"azure_bgp_peers_addresses": `{
+    primary_1   = "169.254.21.2"
+    secondary_1 = "169.254.22.2"
+  }`,
+				"local_bgp_settings": `{
+    asn = "65001"
+    peering_addresses = {
+      "001" = { apipa_addresses = ["primary_1"] }
+      "002" = { apipa_addresses = ["secondary_1"] }
+    }
+  }`,
+				"local_network_gateways": `{
+    lg1 = {
+      name                 = "001"
+      connection           = "001"
+      gateway_address      = "203.0.113.10"
+      remote_bgp_settings  = [{ asn = "65002", bgp_peering_address = "169.254.21.1" }]
+      custom_bgp_addresses = [{ primary = "primary_1", secondary = "secondary_1" }]
+    }
+    lg2 = {
+      name                 = "002"
+      connection           = "002"
+      gateway_address      = "203.0.113.11"
+      remote_bgp_settings  = [{ asn = "65002", bgp_peering_address = "169.254.22.1" }]
+      custom_bgp_addresses = [{ primary = "primary_1", secondary = "secondary_1" }]
+    }
+  }`,
+				"ipsec_policy": `[{
+    dh_group         = "ECP384"
+    ike_encryption   = "AES256"
+    ike_integrity    = "SHA256"
+    ipsec_encryption = "AES256"
+    ipsec_integrity  = "SHA256"
+    pfs_group        = "ECP384"
+    sa_datasize      = "102400000"
+    sa_lifetime      = "27000"
+  }]`,
+			},
+			PlanAssertions: []planassert.Assertion{
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_gateway.this"), "active_active", true),
+				planassert.CreatedWithLength(moduletest.Address("azurerm_virtual_network_gateway.this"), "ip_configuration", 2),
+				planassert.CreatedCount(moduletest.Address("azurerm_public_ip.this[*]"), 2),
+				planassert.CreatedWithLength(moduletest.Address("azurerm_public_ip.this[*]"), "zones", 3),
+				planassert.CreatedCount(moduletest.Address("azurerm_local_network_gateway.this[*]"), 2),
+				planassert.CreatedCount(moduletest.Address("azurerm_virtual_network_gateway_connection.this[*]"), 2),
+				planassert.CreatedWithLength(moduletest.Address("azurerm_virtual_network_gateway_connection.this[*]"), "ipsec_policy", 1),
+			},
+		},
+	},
+	Invalid: []moduletest.Scenario{
+		{
+			Name:          "unsupported_type",
+			Base:          "active_standby",
+			Inputs:        map[string]string{"type": `"LocalGateway"`},
+			ExpectedError: "Valid options are Vpn or ExpressRoute",
+		},
+		{
+			Name:          "unsupported_vpn_type",
+			Base:          "active_standby",
+			Inputs:        map[string]string{"vpn_type": `"Both"`},
+			ExpectedError: "Valid options are RouteBased or PolicyBased",
+		},
+		{
+			Name:          "unsupported_sku",
+			Base:          "active_standby",
+			Inputs:        map[string]string{"sku": `"VpnGw6"`},
+			ExpectedError: "Valid options are Basic, Standard, HighPerformance, UltraPerformance",
+		},
+		{
+			Name:          "unsupported_generation",
+			Base:          "active_standby",
+			Inputs:        map[string]string{"generation": `"Generation3"`},
+			ExpectedError: "Valid options are Generation1, Generation2 or None",
+		},
+		{
+			Name:          "generation2_on_small_sku",
+			Base:          "active_standby",
+			Inputs:        map[string]string{"generation": `"Generation2"`},
+			ExpectedError: "Generation2 is only value for a sku larger than VpnGw2 or VpnGw2AZ",
+		},
+		{
+			Name: "active_standby_with_two_peering_addresses",
+			Base: "active_standby",
+			Inputs: map[string]string{
+				"local_bgp_settings": `{
+    asn = "65001"
+    peering_addresses = {
+      "001" = { apipa_addresses = ["primary_1"] }
+      "002" = { apipa_addresses = ["primary_1"] }
+    }
+  }`,
+			},
+			ExpectedError: "Map of peering addresses has to contain 1 (for active-standby) or 2 objects (for active-active).",
+		},
+		{
+			Name: "active_standby_without_apipa_addresses",
+			Base: "active_standby",
+			Inputs: map[string]string{
+				"azure_bgp_peers_addresses": "{}",
+				"local_bgp_settings": `{
+    asn               = "65001"
+    peering_addresses = { "001" = { apipa_addresses = [] } }
+  }`,
+			},
+			ExpectedError: "For active-standby you need to configure at least 1 custom Azure APIPA BGP IP address, for active-active at least 2.",
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlan(t *testing.T) {
+	moduletest.Plan(t, fixture)
+}
+
+func TestPlanInvalid(t *testing.T) {
+	moduletest.PlanInvalid(t, fixture)
+}
+
+func TestApply(t *testing.T) {
+	moduletest.Apply(t, fixture)
+}
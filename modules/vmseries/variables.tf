@@ -57,7 +57,6 @@ variable "interfaces" {
   - `private_ip_address`       - (optional|string) Static private IP to asssign to the interface. If null, dynamic one is allocated.
   - `public_ip_name`           - (optional|string) Name of an existing public IP to associate to the interface, used only when `create_public_ip` is `false`.
   - `public_ip_resource_group` - (optional|string) Name of a Resource Group that contains public IP resource to associate to the interface. When not specified defaults to `var.resource_group_name`. Used only when `create_public_ip` is `false`.
-  - `availability_zone`        - (optional|string) Availability zone to create public IP in. If not specified, set based on `avzone` and `enable_zones`.
   - `enable_ip_forwarding`     - (optional|bool) If true, the network interface will not discard packets sent to an IP address other than the one assigned. If false, the network interface only accepts traffic destined to its IP address.
   - `enable_backend_pool`      - (optional|bool) If true, associate interface with backend pool specified with `lb_backend_pool_id`. Default is false.
   - `lb_backend_pool_id`       - (optional|string) Identifier of an existing backend pool to associate interface with. Required if `enable_backend_pool` is true.
@@ -27,7 +27,7 @@ resource "azurerm_linux_virtual_machine_scale_set" "this" {
 
   lifecycle {
     precondition {
-      condition     = var.password != null || var.ssh_keys != []
+      condition     = var.password != null || length(var.ssh_keys) > 0
       error_message = "Either password or ssh_key must be set in order to have access to the device"
     }
   }
@@ -4,8 +4,33 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
 )
 
+var fixture = moduletest.Fixture{
+	Setup: moduletest.NetworkSetup(map[string]string{"management": "10.0.0.0/24"}),
+	Inputs: map[string]string{
+		"name":                `"${var.name_prefix}vmss"`,
+		"resource_group_name": moduletest.ResourceGroupName,
+		"location":            moduletest.Location,
+		"img_version":         `"10.2.3"`,
+		"interfaces":          `[{ name = "management", subnet_id = ` + moduletest.SubnetID("management") + ` }]`,
+		"password":            "null",
+	},
+	Invalid: []moduletest.Scenario{
+		{
+			Name:          "no_password_nor_ssh_keys",
+			Inputs:        map[string]string{"ssh_keys": "[]"},
+			ExpectedError: "Either password or ssh_key must be set in order to have access to the device",
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlanInvalid(t *testing.T) {
+	moduletest.PlanInvalid(t, fixture)
+}
@@ -167,7 +167,7 @@ variable "subnets" {
   - `name` - Name of a subnet.
   - `address_prefixes` : The address prefix to use for the subnet. Only required when a subnet will be created.
   - `network_security_group` : The Network Security Group identifier to associate with the subnet.
-  - `route_table_id` : The Route Table identifier to associate with the subnet.
+  - `route_table` : A key of a Route Table defined in `route_tables` to associate with the subnet.
   - `enable_storage_service_endpoint` : Flag that enables `Microsoft.Storage` service endpoint on a subnet. This is a suggested setting for the management interface when full bootstrapping using an Azure Storage Account is used. Defaults to `false`.
   Example:
   ```
@@ -4,8 +4,77 @@ import (
 	"testing"
 
 	"github.com/PaloAltoNetworks/terraform-modules-vmseries-tests-skeleton/pkg/testskeleton"
+
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/moduletest"
+	"github.com/PaloAltoNetworks/terraform-azure-vmseries-modules/internal/planassert"
 )
 
+var fixture = moduletest.Fixture{
+	Setup: `
+resource "azurerm_virtual_network" "local" {
+  name                = "${var.name_prefix}local-vnet"
+  resource_group_name = azurerm_resource_group.this.name
+  location            = azurerm_resource_group.this.location
+  address_space       = ["10.0.0.0/16"]
+}
+
+resource "azurerm_virtual_network" "remote" {
+  name                = "${var.name_prefix}remote-vnet"
+  resource_group_name = azurerm_resource_group.this.name
+  location            = azurerm_resource_group.this.location
+  address_space       = ["10.1.0.0/16"]
+}`,
+	Scenarios: []moduletest.Scenario{
+		{
+			Name: "default",
+			Inputs: map[string]string{
+				"local_peer_config":  `{ vnet_name = azurerm_virtual_network.local.name, resource_group_name = azurerm_resource_group.this.name }`,
+				"remote_peer_config": `{ vnet_name = azurerm_virtual_network.remote.name, resource_group_name = azurerm_resource_group.this.name }`,
+			},
+			// VNETs are read by the module with data sources
+			DependsOn: []string{"azurerm_virtual_network.local", "azurerm_virtual_network.remote"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.IsCreated(moduletest.Address("azurerm_virtual_network_peering.local")),
+				planassert.IsCreated(moduletest.Address("azurerm_virtual_network_peering.remote")),
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_peering.*"), "allow_forwarded_traffic", true),
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_peering.*"), "allow_gateway_transit", false),
+			},
+		},
+		{
+			Name: "custom_names_and_flags",
+			Inputs: map[string]string{
+				"local_peer_config": `{
+    vnet_name               = azurerm_virtual_network.local.name
+    resource_group_name     = azurerm_resource_group.this.name
+    name                    = "${var.name_prefix}local-to-remote"
+    allow_forwarded_traffic = false
+  }`,
+				"remote_peer_config": `{
+    vnet_name                    = azurerm_virtual_network.remote.name
+    resource_group_name          = azurerm_resource_group.this.name
+    name                         = "${var.name_prefix}remote-to-local"
+    allow_virtual_network_access = false
+  }`,
+			},
+			DependsOn: []string{"azurerm_virtual_network.local", "azurerm_virtual_network.remote"},
+			PlanAssertions: []planassert.Assertion{
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_peering.local"), "allow_forwarded_traffic", false),
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_peering.local"), "allow_virtual_network_access", true),
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_peering.remote"), "allow_forwarded_traffic", true),
+				planassert.AttributeEquals(moduletest.Address("azurerm_virtual_network_peering.remote"), "allow_virtual_network_access", false),
+			},
+		},
+	},
+}
+
 func TestValidate(t *testing.T) {
 	testskeleton.ValidateCode(t, nil)
 }
+
+func TestPlan(t *testing.T) {
+	moduletest.Plan(t, fixture)
+}
+
+func TestApply(t *testing.T) {
+	moduletest.Apply(t, fixture)
+}